@@ -0,0 +1,90 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package batchconverter adapts the batching of the collector configurations
+// to the Lambda sandbox, which is frozen between invocations: data held back
+// by the collector waits for the next invocation, or is lost with the sandbox.
+package batchconverter // import "github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/batchconverter"
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const (
+	procKey = "processors"
+	expKey  = "exporters"
+	// MaxBatchTimeout is the longest timeout kept on batch processors, the
+	// default timeout of the processor
+	MaxBatchTimeout = 200 * time.Millisecond
+)
+
+type converter struct {
+}
+
+// New returns a confmap.Converter, that lowers the timeout of the batch
+// processors to MaxBatchTimeout, and removes the persistent storage of the
+// sending queues of the exporters, as the files written would not outlive the
+// sandbox.
+func New() confmap.Converter {
+	return &converter{}
+}
+
+func (c converter) Convert(_ context.Context, conf *confmap.Conf) error {
+	out := make(map[string]interface{})
+
+	if procs, ok := conf.Get(procKey).(map[string]interface{}); ok {
+		for name, settings := range procs {
+			if strings.Split(name, "/")[0] != "batch" {
+				continue
+			}
+
+			settings, _ := settings.(map[string]interface{})
+			if timeout, ok := duration(settings["timeout"]); ok && timeout > MaxBatchTimeout {
+				out[fmt.Sprintf("%s::%s::timeout", procKey, name)] = MaxBatchTimeout.String()
+			}
+		}
+	}
+
+	if exps, ok := conf.Get(expKey).(map[string]interface{}); ok {
+		for name := range exps {
+			key := fmt.Sprintf("%s::%s::sending_queue::storage", expKey, name)
+			if conf.Get(key) != nil {
+				out[key] = nil
+			}
+		}
+	}
+
+	return conf.Merge(confmap.NewFromStringMap(out))
+}
+
+// duration returns the duration of a timeout setting, as a string or as
+// nanoseconds.
+func duration(value interface{}) (time.Duration, bool) {
+	switch value := value.(type) {
+	case string:
+		d, err := time.ParseDuration(value)
+		return d, err == nil
+	case int:
+		return time.Duration(value), true
+	case int64:
+		return time.Duration(value), true
+	}
+
+	return 0, false
+}
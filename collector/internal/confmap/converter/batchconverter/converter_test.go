@@ -0,0 +1,64 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batchconverter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+func TestConvert(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		conf     *confmap.Conf
+		expected *confmap.Conf
+	}{
+		{
+			name:     "empty",
+			conf:     confmap.New(),
+			expected: confmap.New(),
+		},
+		{
+			name:     "default timeout",
+			conf:     confmap.NewFromStringMap(map[string]any{"processors": map[string]any{"batch": nil}}),
+			expected: confmap.NewFromStringMap(map[string]any{"processors": map[string]any{"batch": nil}}),
+		},
+		{
+			name:     "short timeout",
+			conf:     confmap.NewFromStringMap(map[string]any{"processors": map[string]any{"batch": map[string]any{"timeout": "50ms"}}}),
+			expected: confmap.NewFromStringMap(map[string]any{"processors": map[string]any{"batch": map[string]any{"timeout": "50ms"}}}),
+		},
+		{
+			name:     "long timeouts",
+			conf:     confmap.NewFromStringMap(map[string]any{"processors": map[string]any{"batch/logs": map[string]any{"timeout": "10s"}, "batch/traces": map[string]any{"timeout": 1000000000}, "span": map[string]any{"timeout": "10s"}}}),
+			expected: confmap.NewFromStringMap(map[string]any{"processors": map[string]any{"batch/logs": map[string]any{"timeout": "200ms"}, "batch/traces": map[string]any{"timeout": "200ms"}, "span": map[string]any{"timeout": "10s"}}}),
+		},
+		{
+			name:     "persistent queue",
+			conf:     confmap.NewFromStringMap(map[string]any{"exporters": map[string]any{"otlp": map[string]any{"sending_queue": map[string]any{"storage": "file_storage"}}, "logging": nil}}),
+			expected: confmap.NewFromStringMap(map[string]any{"exporters": map[string]any{"otlp": map[string]any{"sending_queue": map[string]any{"storage": nil}}, "logging": nil}}),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New()
+			err := c.Convert(context.Background(), tc.conf)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected.ToStringMap(), tc.conf.ToStringMap())
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package decoupleconverter adds the decouple processor to the pipelines, so
+// the exports don't hold up the invocations without editing the
+// configurations.
+package decoupleconverter // import "github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/decoupleconverter"
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const (
+	procKey      = "processors"
+	pipelinesKey = "service::pipelines"
+	// processorType is the type of the decouple processor
+	processorType = "decouple"
+)
+
+type converter struct {
+}
+
+// New returns a confmap.Converter, that adds the decouple processor as the
+// last processor of the pipelines not using one yet, with its default
+// settings unless the configuration sets them.
+func New() confmap.Converter {
+	return &converter{}
+}
+
+func (c converter) Convert(_ context.Context, conf *confmap.Conf) error {
+	out := make(map[string]interface{})
+
+	pipelines, ok := conf.Get(pipelinesKey).(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	for name, pipeline := range pipelines {
+		pipeline, ok := pipeline.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		names, _ := pipeline["processors"].([]interface{})
+		if decoupled(names) {
+			continue
+		}
+
+		out[fmt.Sprintf("%s::%s::processors", pipelinesKey, name)] = append(append([]interface{}{}, names...), processorType)
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+
+	if !conf.IsSet(procKey + "::" + processorType) {
+		out[procKey+"::"+processorType] = nil
+	}
+
+	return conf.Merge(confmap.NewFromStringMap(out))
+}
+
+// decoupled returns whether the processors include a decouple processor.
+func decoupled(names []interface{}) bool {
+	for _, name := range names {
+		if strings.Split(fmt.Sprint(name), "/")[0] == processorType {
+			return true
+		}
+	}
+
+	return false
+}
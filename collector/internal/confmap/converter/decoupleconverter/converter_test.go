@@ -0,0 +1,78 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decoupleconverter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+func TestConvert(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		conf     *confmap.Conf
+		expected *confmap.Conf
+	}{
+		{
+			name:     "no pipelines",
+			conf:     confmap.New(),
+			expected: confmap.New(),
+		},
+		{
+			name: "pipelines",
+			conf: confmap.NewFromStringMap(map[string]any{
+				"processors": map[string]any{"batch": nil},
+				"service": map[string]any{"pipelines": map[string]any{
+					"traces": map[string]any{"processors": []any{"batch"}},
+					"logs":   map[string]any{"receivers": []any{"otlp"}},
+				}},
+			}),
+			expected: confmap.NewFromStringMap(map[string]any{
+				"processors": map[string]any{"batch": nil, "decouple": nil},
+				"service": map[string]any{"pipelines": map[string]any{
+					"traces": map[string]any{"processors": []any{"batch", "decouple"}},
+					"logs":   map[string]any{"receivers": []any{"otlp"}, "processors": []any{"decouple"}},
+				}},
+			}),
+		},
+		{
+			name: "configured",
+			conf: confmap.NewFromStringMap(map[string]any{
+				"processors": map[string]any{"decouple": map[string]any{"max_queue_size": 10}, "decouple/logs": nil},
+				"service": map[string]any{"pipelines": map[string]any{
+					"traces": map[string]any{},
+					"logs":   map[string]any{"processors": []any{"decouple/logs", "batch"}},
+				}},
+			}),
+			expected: confmap.NewFromStringMap(map[string]any{
+				"processors": map[string]any{"decouple": map[string]any{"max_queue_size": 10}, "decouple/logs": nil},
+				"service": map[string]any{"pipelines": map[string]any{
+					"traces": map[string]any{"processors": []any{"decouple"}},
+					"logs":   map[string]any{"processors": []any{"decouple/logs", "batch"}},
+				}},
+			}),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New()
+			err := c.Convert(context.Background(), tc.conf)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected.ToStringMap(), tc.conf.ToStringMap())
+		})
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kmsdecryptconverter decrypts the configuration values encrypted
+// with AWS KMS, so the secrets of a configuration, e.g. one hosted on S3,
+// stay encrypted at rest.
+package kmsdecryptconverter // import "github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/kmsdecryptconverter"
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const (
+	// prefix marks the values holding a base64 encoded KMS ciphertext
+	prefix = "kms:"
+)
+
+type kmsClient interface {
+	Decrypt(context.Context, *kms.DecryptInput, ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+type converter struct {
+	mu     sync.Mutex
+	client kmsClient
+}
+
+// New returns a confmap.Converter, that replaces the values of the form
+// kms:<base64 ciphertext> with their plaintext, decrypted with AWS KMS.
+func New() confmap.Converter {
+	return &converter{}
+}
+
+func (c *converter) Convert(ctx context.Context, conf *confmap.Conf) error {
+	d := &decryption{converter: c, plaintexts: map[string]string{}}
+
+	out, err := d.decrypt(ctx, "", conf.ToStringMap())
+	if err != nil {
+		return err
+	}
+
+	if len(d.plaintexts) == 0 {
+		return nil
+	}
+
+	return conf.Merge(confmap.NewFromStringMap(out.(map[string]interface{})))
+}
+
+// decryption decrypts the values of a configuration
+type decryption struct {
+	*converter
+	// plaintexts caches the plaintexts by ciphertext, so a secret used twice
+	// is decrypted once
+	plaintexts map[string]string
+}
+
+// decrypt returns the value at path with its encrypted values, at any depth,
+// decrypted.
+func (d *decryption) decrypt(ctx context.Context, path string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if !strings.HasPrefix(v, prefix) {
+			return v, nil
+		}
+
+		plaintext, err := d.plaintext(ctx, strings.TrimPrefix(v, prefix))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt the value of %q: %w", path, err)
+		}
+
+		return plaintext, nil
+
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+		for i, item := range v {
+			item, err := d.decrypt(ctx, fmt.Sprintf("%s[%d]", path, i), item)
+			if err != nil {
+				return nil, err
+			}
+
+			out = append(out, item)
+		}
+
+		return out, nil
+
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			itemPath := key
+			if path != "" {
+				itemPath = path + confmap.KeyDelimiter + key
+			}
+
+			item, err := d.decrypt(ctx, itemPath, item)
+			if err != nil {
+				return nil, err
+			}
+
+			out[key] = item
+		}
+
+		return out, nil
+	}
+
+	return value, nil
+}
+
+// plaintext decrypts a base64 encoded ciphertext.
+func (d *decryption) plaintext(ctx context.Context, ciphertext string) (string, error) {
+	if plaintext, ok := d.plaintexts[ciphertext]; ok {
+		return plaintext, nil
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64 ciphertext: %w", err)
+	}
+
+	client, err := d.kmsClient()
+	if err != nil {
+		return "", err
+	}
+
+	output, err := client.Decrypt(ctx, &kms.DecryptInput{CiphertextBlob: blob})
+	if err != nil {
+		return "", err
+	}
+
+	d.plaintexts[ciphertext] = string(output.Plaintext)
+
+	return string(output.Plaintext), nil
+}
+
+// kmsClient returns the KMS client, initialized on first use so
+// configurations without encrypted values don't load the AWS configuration.
+func (c *converter) kmsClient() (kmsClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.client == nil {
+		cfg, err := config.LoadDefaultConfig(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load configurations to initialize an AWS SDK client, error: %w", err)
+		}
+		c.client = kms.NewFromConfig(cfg)
+	}
+
+	return c.client, nil
+}
@@ -0,0 +1,100 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kmsdecryptconverter
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+type fakeClient struct {
+	calls int
+}
+
+// Decrypt "decrypts" the ciphertexts starting with "encrypted:".
+func (c *fakeClient) Decrypt(_ context.Context, input *kms.DecryptInput, _ ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	c.calls++
+
+	if !bytes.HasPrefix(input.CiphertextBlob, []byte("encrypted:")) {
+		return nil, errors.New("InvalidCiphertextException")
+	}
+
+	return &kms.DecryptOutput{Plaintext: bytes.TrimPrefix(input.CiphertextBlob, []byte("encrypted:"))}, nil
+}
+
+func encrypted(plaintext string) string {
+	return "kms:" + base64.StdEncoding.EncodeToString([]byte("encrypted:"+plaintext))
+}
+
+func TestConvert(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		conf     *confmap.Conf
+		expected *confmap.Conf
+		calls    int
+		err      string
+	}{
+		{
+			name:     "no encrypted values",
+			conf:     confmap.NewFromStringMap(map[string]any{"exporters": map[string]any{"otlp": map[string]any{"endpoint": "localhost:4317"}}}),
+			expected: confmap.NewFromStringMap(map[string]any{"exporters": map[string]any{"otlp": map[string]any{"endpoint": "localhost:4317"}}}),
+		},
+		{
+			name:     "encrypted values",
+			conf:     confmap.NewFromStringMap(map[string]any{"exporters": map[string]any{"otlp": map[string]any{"endpoint": "localhost:4317", "headers": map[string]any{"api-key": encrypted("s3cr3t")}}}}),
+			expected: confmap.NewFromStringMap(map[string]any{"exporters": map[string]any{"otlp": map[string]any{"endpoint": "localhost:4317", "headers": map[string]any{"api-key": "s3cr3t"}}}}),
+			calls:    1,
+		},
+		{
+			name:     "encrypted list items decrypted once",
+			conf:     confmap.NewFromStringMap(map[string]any{"receivers": map[string]any{"otlp": map[string]any{"tokens": []any{encrypted("token"), "plain", encrypted("token")}}}}),
+			expected: confmap.NewFromStringMap(map[string]any{"receivers": map[string]any{"otlp": map[string]any{"tokens": []any{"token", "plain", "token"}}}}),
+			calls:    1,
+		},
+		{
+			name: "invalid base64",
+			conf: confmap.NewFromStringMap(map[string]any{"exporters": map[string]any{"otlp": map[string]any{"headers": map[string]any{"api-key": "kms:not base64"}}}}),
+			err:  `failed to decrypt the value of "exporters::otlp::headers::api-key": invalid base64 ciphertext: illegal base64 data at input byte 3`,
+		},
+		{
+			name:  "decrypt failure",
+			conf:  confmap.NewFromStringMap(map[string]any{"exporters": []any{"kms:" + base64.StdEncoding.EncodeToString([]byte("other"))}}),
+			calls: 1,
+			err:   `failed to decrypt the value of "exporters[0]": InvalidCiphertextException`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &fakeClient{}
+			c := &converter{client: client}
+
+			err := c.Convert(context.Background(), tc.conf)
+			assert.Equal(t, tc.calls, client.calls)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected.ToStringMap(), tc.conf.ToStringMap())
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package logconfigconverter logs the effective collector configuration, once
+// resolved and converted, with its secrets masked, to debug configurations
+// assembled from several sources.
+package logconfigconverter // import "github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/logconfigconverter"
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted replaces the values of secrets.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are the parts of the names of settings holding secrets
+var sensitiveKeys = []string{"secret", "token", "password", "passwd", "apikey", "api_key", "api-key", "authorization", "credential", "private_key"}
+
+// sensitiveHeaders are the parts of the names of headers holding secrets, as
+// headers are commonly named after their backend, e.g. x-honeycomb-team
+var sensitiveHeaders = []string{"auth", "key", "token", "secret", "cookie", "signature", "team", "license"}
+
+type converter struct {
+	log func(string)
+}
+
+// New returns a confmap.Converter, that hands the configuration as YAML to
+// log, with its secrets masked, see Redact. The configuration is left as is.
+func New(log func(string)) confmap.Converter {
+	return &converter{log: log}
+}
+
+func (c converter) Convert(_ context.Context, conf *confmap.Conf) error {
+	out, err := yaml.Marshal(Redact(conf.ToStringMap()))
+	if err != nil {
+		return err
+	}
+
+	c.log(string(out))
+
+	return nil
+}
+
+// Redact returns a copy of the configuration raw, with the values of the
+// settings whose name suggests a secret and the values of the headers whose
+// name suggests a credential replaced by Redacted, and the passwords of URLs
+// masked.
+func Redact(raw map[string]interface{}) map[string]interface{} {
+	return redactMap(raw, sensitiveKeys)
+}
+
+func redactMap(raw map[string]interface{}, sensitive []string) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(raw))
+
+	for key, value := range raw {
+		switch {
+		case value != nil && contains(key, sensitive):
+			redacted[key] = Redacted
+		case strings.EqualFold(key, "headers"):
+			if headers, ok := value.(map[string]interface{}); ok {
+				redacted[key] = redactMap(headers, sensitiveHeaders)
+				continue
+			}
+			redacted[key] = redact(value)
+		default:
+			redacted[key] = redact(value)
+		}
+	}
+
+	return redacted
+}
+
+func redact(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return redactMap(value, sensitiveKeys)
+	case []interface{}:
+		redacted := make([]interface{}, len(value))
+		for i, v := range value {
+			redacted[i] = redact(v)
+		}
+		return redacted
+	case string:
+		if u, err := url.Parse(value); err == nil && u.User != nil {
+			return u.Redacted()
+		}
+	}
+
+	return value
+}
+
+func contains(key string, parts []string) bool {
+	key = strings.ToLower(key)
+	for _, part := range parts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+
+	return false
+}
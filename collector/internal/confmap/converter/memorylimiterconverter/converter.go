@@ -0,0 +1,136 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package memorylimiterconverter sizes the memory_limiter processors from the
+// memory of the function, so a single configuration works across functions
+// of different memory sizes.
+package memorylimiterconverter // import "github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/memorylimiterconverter"
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const (
+	procKey      = "processors"
+	pipelinesKey = "service::pipelines"
+	// processorName is the memory_limiter processor added to the pipelines
+	processorName = "memory_limiter"
+	// defaultLimitPercentage is the share of the function memory the
+	// collector may use when the processor sets no limit, leaving the rest to
+	// the function the sandbox memory is shared with
+	defaultLimitPercentage = 25
+	// defaultCheckInterval is set on processors without one, as the processor requires it
+	defaultCheckInterval = "1s"
+)
+
+type converter struct {
+	memorySizeMiB int
+	inject        bool
+}
+
+// New returns a confmap.Converter, that sets the limit_mib and
+// spike_limit_mib of the memory_limiter processors not setting limit_mib, from
+// their limit_percentage and spike_limit_percentage of memorySizeMiB, the
+// memory of the function, instead of the memory of the host. If inject is set
+// and the configuration has no memory_limiter processor, one limited to
+// defaultLimitPercentage of memorySizeMiB is added first to all pipelines.
+func New(memorySizeMiB int, inject bool) confmap.Converter {
+	return &converter{memorySizeMiB: memorySizeMiB, inject: inject}
+}
+
+func (c converter) Convert(_ context.Context, conf *confmap.Conf) error {
+	out := make(map[string]interface{})
+
+	procs, _ := conf.Get(procKey).(map[string]interface{})
+
+	found := false
+	for name, settings := range procs {
+		if strings.Split(name, "/")[0] != processorName {
+			continue
+		}
+
+		found = true
+
+		settings, _ := settings.(map[string]interface{})
+		if limit, _ := number(settings["limit_mib"]); limit != 0 {
+			// Fixed limits are kept
+			continue
+		}
+
+		key := fmt.Sprintf("%s::%s::", procKey, name)
+
+		limitPercentage, ok := number(settings["limit_percentage"])
+		if !ok || limitPercentage == 0 {
+			limitPercentage = defaultLimitPercentage
+		}
+		out[key+"limit_mib"] = c.memorySizeMiB * limitPercentage / 100
+		out[key+"limit_percentage"] = 0
+
+		// Without a spike limit, the processor uses 20% of the limit
+		if spikePercentage, ok := number(settings["spike_limit_percentage"]); ok && spikePercentage != 0 {
+			out[key+"spike_limit_mib"] = c.memorySizeMiB * spikePercentage / 100
+			out[key+"spike_limit_percentage"] = 0
+		}
+
+		if _, ok := settings["check_interval"]; !ok {
+			out[key+"check_interval"] = defaultCheckInterval
+		}
+	}
+
+	if !found && c.inject {
+		c.injectProcessor(conf, out)
+	}
+
+	return conf.Merge(confmap.NewFromStringMap(out))
+}
+
+// injectProcessor adds a memory_limiter processor to out, placed first in
+// all pipelines of conf, so it refuses data before it is processed.
+func (c converter) injectProcessor(conf *confmap.Conf, out map[string]interface{}) {
+	pipelines, ok := conf.Get(pipelinesKey).(map[string]interface{})
+	if !ok || len(pipelines) == 0 {
+		return
+	}
+
+	for name, pipeline := range pipelines {
+		pipeline, _ := pipeline.(map[string]interface{})
+		names, _ := pipeline["processors"].([]interface{})
+		out[fmt.Sprintf("%s::%s::processors", pipelinesKey, name)] = append([]interface{}{processorName}, names...)
+	}
+
+	out[procKey+"::"+processorName] = map[string]interface{}{
+		"check_interval": defaultCheckInterval,
+		"limit_mib":      c.memorySizeMiB * defaultLimitPercentage / 100,
+	}
+}
+
+// number returns the integer value of a setting, as decoded from YAML.
+func number(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case uint64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,110 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memorylimiterconverter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+func TestConvert(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		inject   bool
+		conf     *confmap.Conf
+		expected *confmap.Conf
+	}{
+		{
+			name:     "no processors",
+			conf:     confmap.New(),
+			expected: confmap.New(),
+		},
+		{
+			name:     "no memory_limiter",
+			conf:     confmap.NewFromStringMap(map[string]any{"processors": map[string]any{"batch": nil}}),
+			expected: confmap.NewFromStringMap(map[string]any{"processors": map[string]any{"batch": nil}}),
+		},
+		{
+			name:     "fixed limits",
+			conf:     confmap.NewFromStringMap(map[string]any{"processors": map[string]any{"memory_limiter": map[string]any{"check_interval": "1s", "limit_mib": 100}}}),
+			expected: confmap.NewFromStringMap(map[string]any{"processors": map[string]any{"memory_limiter": map[string]any{"check_interval": "1s", "limit_mib": 100}}}),
+		},
+		{
+			name:     "percentages",
+			conf:     confmap.NewFromStringMap(map[string]any{"processors": map[string]any{"memory_limiter": map[string]any{"check_interval": "5s", "limit_percentage": 50, "spike_limit_percentage": 10}}}),
+			expected: confmap.NewFromStringMap(map[string]any{"processors": map[string]any{"memory_limiter": map[string]any{"check_interval": "5s", "limit_mib": 512, "limit_percentage": 0, "spike_limit_mib": 102, "spike_limit_percentage": 0}}}),
+		},
+		{
+			name:     "no limits",
+			conf:     confmap.NewFromStringMap(map[string]any{"processors": map[string]any{"memory_limiter/traces": nil}}),
+			expected: confmap.NewFromStringMap(map[string]any{"processors": map[string]any{"memory_limiter/traces": map[string]any{"check_interval": "1s", "limit_mib": 256, "limit_percentage": 0}}}),
+		},
+		{
+			name:   "injected",
+			inject: true,
+			conf: confmap.NewFromStringMap(map[string]any{
+				"processors": map[string]any{"batch": nil},
+				"service": map[string]any{"pipelines": map[string]any{
+					"traces": map[string]any{"processors": []any{"batch"}},
+					"logs":   map[string]any{"receivers": []any{"otlp"}},
+				}},
+			}),
+			expected: confmap.NewFromStringMap(map[string]any{
+				"processors": map[string]any{"batch": nil, "memory_limiter": map[string]any{"check_interval": "1s", "limit_mib": 256}},
+				"service": map[string]any{"pipelines": map[string]any{
+					"traces": map[string]any{"processors": []any{"memory_limiter", "batch"}},
+					"logs":   map[string]any{"receivers": []any{"otlp"}, "processors": []any{"memory_limiter"}},
+				}},
+			}),
+		},
+		{
+			name:   "not injected next to a memory_limiter",
+			inject: true,
+			conf: confmap.NewFromStringMap(map[string]any{
+				"processors": map[string]any{"memory_limiter/traces": map[string]any{"limit_mib": 100}},
+				"service": map[string]any{"pipelines": map[string]any{
+					"traces": map[string]any{"processors": []any{"memory_limiter/traces"}},
+					"logs":   map[string]any{"receivers": []any{"otlp"}},
+				}},
+			}),
+			expected: confmap.NewFromStringMap(map[string]any{
+				"processors": map[string]any{"memory_limiter/traces": map[string]any{"limit_mib": 100}},
+				"service": map[string]any{"pipelines": map[string]any{
+					"traces": map[string]any{"processors": []any{"memory_limiter/traces"}},
+					"logs":   map[string]any{"receivers": []any{"otlp"}},
+				}},
+			}),
+		},
+		{
+			name: "not injected",
+			conf: confmap.NewFromStringMap(map[string]any{
+				"service": map[string]any{"pipelines": map[string]any{"traces": map[string]any{"processors": []any{"batch"}}}},
+			}),
+			expected: confmap.NewFromStringMap(map[string]any{
+				"service": map[string]any{"pipelines": map[string]any{"traces": map[string]any{"processors": []any{"batch"}}}},
+			}),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New(1024, tc.inject)
+			err := c.Convert(context.Background(), tc.conf)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected.ToStringMap(), tc.conf.ToStringMap())
+		})
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package migrateconverter rewrites the settings of the collector
+// configurations which older collector versions accepted, so configurations
+// written for them keep working when the layer is upgraded.
+package migrateconverter // import "github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/migrateconverter"
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+// migration rewrites the deprecated settings of the components of a kind,
+// e.g. exporters, and type, e.g. logging, returning a warning per setting
+// rewritten.
+type migration struct {
+	kind    string
+	typ     string
+	migrate func(settings map[string]interface{}) []string
+}
+
+var migrations = []migration{
+	{kind: "exporters", typ: "logging", migrate: migrateLogLevel},
+	{kind: "exporters", typ: "otlp", migrate: migrateInsecure},
+	{kind: "exporters", typ: "otlphttp", migrate: migrateInsecure},
+	{kind: "receivers", typ: "otlp", migrate: migrateCORS},
+	{kind: "processors", typ: "memory_limiter", migrate: migrateBallastSize},
+}
+
+// logLevelVerbosity maps the loglevel of the logging exporter to its
+// verbosity, as the exporter does
+var logLevelVerbosity = map[string]string{
+	"debug":  "detailed",
+	"info":   "normal",
+	"warn":   "basic",
+	"error":  "basic",
+	"dpanic": "basic",
+	"panic":  "basic",
+	"fatal":  "basic",
+}
+
+type converter struct {
+	warn func(string)
+}
+
+// New returns a confmap.Converter, that rewrites the deprecated settings of
+// the configuration and calls warn with a description of each:
+//   - the loglevel of the logging exporters is replaced by its verbosity
+//   - the insecure setting of the otlp and otlphttp exporters is moved to
+//     tls::insecure
+//   - the cors_allowed_origins and cors_allowed_headers settings of the HTTP
+//     protocol of the otlp receivers are moved to cors
+//   - the ballast_size_mib setting of the memory_limiter processors, and the
+//     memory_ballast extensions, are removed
+func New(warn func(string)) confmap.Converter {
+	return &converter{warn: warn}
+}
+
+func (c converter) Convert(_ context.Context, conf *confmap.Conf) error {
+	raw := conf.ToStringMap()
+
+	var warnings []string
+	for _, m := range migrations {
+		components, _ := raw[m.kind].(map[string]interface{})
+		for _, name := range sortedKeys(components) {
+			settings, ok := components[name].(map[string]interface{})
+			if !ok || strings.Split(name, "/")[0] != m.typ {
+				continue
+			}
+
+			for _, warning := range m.migrate(settings) {
+				warnings = append(warnings, fmt.Sprintf("%s::%s: %s", m.kind, name, warning))
+			}
+		}
+	}
+
+	warnings = append(warnings, removeBallast(raw)...)
+
+	if len(warnings) == 0 {
+		return nil
+	}
+
+	for _, warning := range warnings {
+		c.warn(warning)
+	}
+
+	// Merging can't remove the deprecated settings
+	*conf = *confmap.NewFromStringMap(raw)
+	return nil
+}
+
+func migrateLogLevel(settings map[string]interface{}) []string {
+	level, ok := settings["loglevel"]
+	if !ok {
+		return nil
+	}
+
+	if _, ok := settings["verbosity"]; ok {
+		delete(settings, "loglevel")
+		return []string{"loglevel is removed, verbosity is set"}
+	}
+
+	verbosity, ok := logLevelVerbosity[strings.ToLower(fmt.Sprint(level))]
+	if !ok {
+		// Left to the exporter to report
+		return nil
+	}
+
+	delete(settings, "loglevel")
+	settings["verbosity"] = verbosity
+	return []string{fmt.Sprintf("loglevel %v is replaced by verbosity %s", level, verbosity)}
+}
+
+func migrateInsecure(settings map[string]interface{}) []string {
+	insecure, ok := settings["insecure"]
+	if !ok {
+		return nil
+	}
+
+	delete(settings, "insecure")
+
+	tls, _ := settings["tls"].(map[string]interface{})
+	if tls == nil {
+		tls = make(map[string]interface{})
+		settings["tls"] = tls
+	}
+
+	if _, ok := tls["insecure"]; ok {
+		return []string{"insecure is removed, tls::insecure is set"}
+	}
+
+	tls["insecure"] = insecure
+	return []string{"insecure is moved to tls::insecure"}
+}
+
+func migrateCORS(settings map[string]interface{}) []string {
+	protocols, _ := settings["protocols"].(map[string]interface{})
+	http, _ := protocols["http"].(map[string]interface{})
+	if http == nil {
+		return nil
+	}
+
+	var warnings []string
+	for _, key := range []string{"allowed_origins", "allowed_headers"} {
+		value, ok := http["cors_"+key]
+		if !ok {
+			continue
+		}
+
+		delete(http, "cors_"+key)
+
+		cors, _ := http["cors"].(map[string]interface{})
+		if cors == nil {
+			cors = make(map[string]interface{})
+			http["cors"] = cors
+		}
+
+		if _, ok := cors[key]; ok {
+			warnings = append(warnings, fmt.Sprintf("protocols::http::cors_%s is removed, protocols::http::cors::%s is set", key, key))
+			continue
+		}
+
+		cors[key] = value
+		warnings = append(warnings, fmt.Sprintf("protocols::http::cors_%s is moved to protocols::http::cors::%s", key, key))
+	}
+
+	return warnings
+}
+
+func migrateBallastSize(settings map[string]interface{}) []string {
+	if _, ok := settings["ballast_size_mib"]; !ok {
+		return nil
+	}
+
+	delete(settings, "ballast_size_mib")
+	return []string{"ballast_size_mib is removed, the memory ballast is no longer supported"}
+}
+
+// removeBallast removes the memory_ballast extensions, which the collector of
+// the extension doesn't have.
+func removeBallast(raw map[string]interface{}) []string {
+	var warnings []string
+
+	extensions, _ := raw["extensions"].(map[string]interface{})
+	for _, name := range sortedKeys(extensions) {
+		if strings.Split(name, "/")[0] == "memory_ballast" {
+			delete(extensions, name)
+			warnings = append(warnings, fmt.Sprintf("extensions::%s is removed, the memory ballast is no longer supported", name))
+		}
+	}
+
+	service, _ := raw["service"].(map[string]interface{})
+	names, ok := service["extensions"].([]interface{})
+	if !ok {
+		return warnings
+	}
+
+	kept := []interface{}{}
+	for _, name := range names {
+		if strings.Split(fmt.Sprint(name), "/")[0] != "memory_ballast" {
+			kept = append(kept, name)
+		}
+	}
+
+	if len(kept) != len(names) {
+		service["extensions"] = kept
+		warnings = append(warnings, "service::extensions: memory_ballast is removed, the memory ballast is no longer supported")
+	}
+
+	return warnings
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
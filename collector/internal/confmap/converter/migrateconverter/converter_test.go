@@ -0,0 +1,130 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrateconverter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+func TestConvert(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		conf     *confmap.Conf
+		expected *confmap.Conf
+		warnings []string
+	}{
+		{
+			name:     "empty",
+			conf:     confmap.New(),
+			expected: confmap.New(),
+		},
+		{
+			name: "current settings",
+			conf: confmap.NewFromStringMap(map[string]any{
+				"exporters": map[string]any{"logging": map[string]any{"verbosity": "detailed"}, "otlp": map[string]any{"tls": map[string]any{"insecure": true}}},
+			}),
+			expected: confmap.NewFromStringMap(map[string]any{
+				"exporters": map[string]any{"logging": map[string]any{"verbosity": "detailed"}, "otlp": map[string]any{"tls": map[string]any{"insecure": true}}},
+			}),
+		},
+		{
+			name: "logging loglevel",
+			conf: confmap.NewFromStringMap(map[string]any{
+				"exporters": map[string]any{
+					"logging":         map[string]any{"loglevel": "debug"},
+					"logging/both":    map[string]any{"loglevel": "info", "verbosity": "basic"},
+					"logging/unknown": map[string]any{"loglevel": "trace"},
+				},
+			}),
+			expected: confmap.NewFromStringMap(map[string]any{
+				"exporters": map[string]any{
+					"logging":         map[string]any{"verbosity": "detailed"},
+					"logging/both":    map[string]any{"verbosity": "basic"},
+					"logging/unknown": map[string]any{"loglevel": "trace"},
+				},
+			}),
+			warnings: []string{
+				"exporters::logging: loglevel debug is replaced by verbosity detailed",
+				"exporters::logging/both: loglevel is removed, verbosity is set",
+			},
+		},
+		{
+			name: "otlp insecure",
+			conf: confmap.NewFromStringMap(map[string]any{
+				"exporters": map[string]any{
+					"otlp":          map[string]any{"endpoint": "collector:4317", "insecure": true},
+					"otlphttp/both": map[string]any{"insecure": true, "tls": map[string]any{"insecure": false}},
+				},
+			}),
+			expected: confmap.NewFromStringMap(map[string]any{
+				"exporters": map[string]any{
+					"otlp":          map[string]any{"endpoint": "collector:4317", "tls": map[string]any{"insecure": true}},
+					"otlphttp/both": map[string]any{"tls": map[string]any{"insecure": false}},
+				},
+			}),
+			warnings: []string{
+				"exporters::otlp: insecure is moved to tls::insecure",
+				"exporters::otlphttp/both: insecure is removed, tls::insecure is set",
+			},
+		},
+		{
+			name: "otlp receiver cors",
+			conf: confmap.NewFromStringMap(map[string]any{
+				"receivers": map[string]any{"otlp": map[string]any{"protocols": map[string]any{"http": map[string]any{
+					"endpoint":             "localhost:4318",
+					"cors_allowed_origins": []any{"https://*.example.com"},
+				}}}},
+			}),
+			expected: confmap.NewFromStringMap(map[string]any{
+				"receivers": map[string]any{"otlp": map[string]any{"protocols": map[string]any{"http": map[string]any{
+					"endpoint": "localhost:4318",
+					"cors":     map[string]any{"allowed_origins": []any{"https://*.example.com"}},
+				}}}},
+			}),
+			warnings: []string{"receivers::otlp: protocols::http::cors_allowed_origins is moved to protocols::http::cors::allowed_origins"},
+		},
+		{
+			name: "memory ballast",
+			conf: confmap.NewFromStringMap(map[string]any{
+				"extensions": map[string]any{"memory_ballast": map[string]any{"size_mib": 64}, "sigv4auth": nil},
+				"processors": map[string]any{"memory_limiter": map[string]any{"limit_mib": 100, "ballast_size_mib": 64}},
+				"service":    map[string]any{"extensions": []any{"memory_ballast", "sigv4auth"}},
+			}),
+			expected: confmap.NewFromStringMap(map[string]any{
+				"extensions": map[string]any{"sigv4auth": nil},
+				"processors": map[string]any{"memory_limiter": map[string]any{"limit_mib": 100}},
+				"service":    map[string]any{"extensions": []any{"sigv4auth"}},
+			}),
+			warnings: []string{
+				"processors::memory_limiter: ballast_size_mib is removed, the memory ballast is no longer supported",
+				"extensions::memory_ballast is removed, the memory ballast is no longer supported",
+				"service::extensions: memory_ballast is removed, the memory ballast is no longer supported",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var warnings []string
+			c := New(func(warning string) { warnings = append(warnings, warning) })
+			err := c.Convert(context.Background(), tc.conf)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected.ToStringMap(), tc.conf.ToStringMap())
+			assert.Equal(t, tc.warnings, warnings)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otlpenvconverter applies the OTEL_EXPORTER_OTLP_* environment
+// variables of the OpenTelemetry SDKs to the OTLP exporters of the collector
+// configurations, so functions configured for the SDKs export the same way
+// without a dedicated configuration.
+package otlpenvconverter // import "github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/otlpenvconverter"
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const expKey = "exporters"
+
+// Settings are the OTLP exporter settings of the SDK environment variables.
+type Settings struct {
+	// Endpoint is the OTEL_EXPORTER_OTLP_ENDPOINT base URL
+	Endpoint string
+	// Headers are the OTEL_EXPORTER_OTLP_HEADERS, see ParseHeaders
+	Headers map[string]string
+	// Protocol is the OTEL_EXPORTER_OTLP_PROTOCOL: grpc, http/protobuf or
+	// http/json
+	Protocol string
+}
+
+type converter struct {
+	settings Settings
+}
+
+// New returns a confmap.Converter, that sets the endpoint of the OTLP
+// exporters matching the protocol of settings, otlp for grpc and otlphttp for
+// http/protobuf and http/json, or of both if no protocol is set, and merges
+// the headers over theirs.
+func New(settings Settings) confmap.Converter {
+	return &converter{settings: settings}
+}
+
+func (c converter) Convert(_ context.Context, conf *confmap.Conf) error {
+	out := make(map[string]interface{})
+
+	types, err := exporterTypes(c.settings.Protocol)
+	if err != nil {
+		return err
+	}
+
+	exps, ok := conf.Get(expKey).(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	for name := range exps {
+		if !types[strings.Split(name, "/")[0]] {
+			continue
+		}
+
+		if c.settings.Endpoint != "" {
+			out[fmt.Sprintf("%s::%s::endpoint", expKey, name)] = c.settings.Endpoint
+		}
+
+		for header, value := range c.settings.Headers {
+			out[fmt.Sprintf("%s::%s::headers::%s", expKey, name, header)] = value
+		}
+	}
+
+	return conf.Merge(confmap.NewFromStringMap(out))
+}
+
+// exporterTypes returns the types of the exporters speaking protocol.
+func exporterTypes(protocol string) (map[string]bool, error) {
+	switch protocol {
+	case "":
+		return map[string]bool{"otlp": true, "otlphttp": true}, nil
+	case "grpc":
+		return map[string]bool{"otlp": true}, nil
+	case "http/protobuf", "http/json":
+		// otlphttp only sends protobuf, which all OTLP/HTTP servers accept
+		return map[string]bool{"otlphttp": true}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported OTEL_EXPORTER_OTLP_PROTOCOL %q, expected grpc, http/protobuf or http/json", protocol)
+}
+
+// ParseHeaders returns the headers of an OTEL_EXPORTER_OTLP_HEADERS value, a
+// comma separated list of key=value pairs with URL encoded values.
+func ParseHeaders(value string) (map[string]string, error) {
+	headers := make(map[string]string)
+
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		key, val, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid header %q, expected key=value", pair)
+		}
+
+		val, err := url.QueryUnescape(strings.TrimSpace(val))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of header %q: %w", key, err)
+		}
+
+		headers[key] = val
+	}
+
+	return headers, nil
+}
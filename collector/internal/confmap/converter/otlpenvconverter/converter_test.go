@@ -0,0 +1,114 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlpenvconverter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+func TestConvert(t *testing.T) {
+	exporters := func() *confmap.Conf {
+		return confmap.NewFromStringMap(map[string]any{
+			"exporters": map[string]any{
+				"otlp":          map[string]any{"endpoint": "localhost:4317"},
+				"otlphttp/team": map[string]any{"endpoint": "http://localhost:4318", "headers": map[string]any{"x-scope": "team"}},
+				"logging":       nil,
+			},
+		})
+	}
+
+	for _, tc := range []struct {
+		name     string
+		settings Settings
+		conf     *confmap.Conf
+		expected *confmap.Conf
+	}{
+		{
+			name:     "no exporters",
+			settings: Settings{Endpoint: "https://otlp.example.com"},
+			conf:     confmap.New(),
+			expected: confmap.New(),
+		},
+		{
+			name:     "all protocols",
+			settings: Settings{Endpoint: "https://otlp.example.com", Headers: map[string]string{"api-key": "secret"}},
+			conf:     exporters(),
+			expected: confmap.NewFromStringMap(map[string]any{
+				"exporters": map[string]any{
+					"otlp":          map[string]any{"endpoint": "https://otlp.example.com", "headers": map[string]any{"api-key": "secret"}},
+					"otlphttp/team": map[string]any{"endpoint": "https://otlp.example.com", "headers": map[string]any{"x-scope": "team", "api-key": "secret"}},
+					"logging":       nil,
+				},
+			}),
+		},
+		{
+			name:     "grpc",
+			settings: Settings{Endpoint: "https://otlp.example.com:4317", Protocol: "grpc"},
+			conf:     exporters(),
+			expected: confmap.NewFromStringMap(map[string]any{
+				"exporters": map[string]any{
+					"otlp":          map[string]any{"endpoint": "https://otlp.example.com:4317"},
+					"otlphttp/team": map[string]any{"endpoint": "http://localhost:4318", "headers": map[string]any{"x-scope": "team"}},
+					"logging":       nil,
+				},
+			}),
+		},
+		{
+			name:     "http headers only",
+			settings: Settings{Headers: map[string]string{"x-scope": "platform"}, Protocol: "http/protobuf"},
+			conf:     exporters(),
+			expected: confmap.NewFromStringMap(map[string]any{
+				"exporters": map[string]any{
+					"otlp":          map[string]any{"endpoint": "localhost:4317"},
+					"otlphttp/team": map[string]any{"endpoint": "http://localhost:4318", "headers": map[string]any{"x-scope": "platform"}},
+					"logging":       nil,
+				},
+			}),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New(tc.settings)
+			err := c.Convert(context.Background(), tc.conf)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected.ToStringMap(), tc.conf.ToStringMap())
+		})
+	}
+}
+
+func TestConvertUnsupportedProtocol(t *testing.T) {
+	c := New(Settings{Endpoint: "https://otlp.example.com", Protocol: "udp"})
+	assert.ErrorContains(t, c.Convert(context.Background(), confmap.New()), `unsupported OTEL_EXPORTER_OTLP_PROTOCOL "udp"`)
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := ParseHeaders("api-key=secret, x-scope = team%2Fa,,authorization=Basic%20dXNlcjpwYXNz")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"api-key":       "secret",
+		"x-scope":       "team/a",
+		"authorization": "Basic dXNlcjpwYXNz",
+	}, headers)
+
+	_, err = ParseHeaders("api-key")
+	assert.Error(t, err)
+
+	_, err = ParseHeaders("api-key=%zz")
+	assert.Error(t, err)
+}
@@ -0,0 +1,110 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package resourceattributesconverter adds the resource attributes of the
+// function to the telemetry of every pipeline, so it can be told apart in the
+// backends without configuring a resource processor in each configuration.
+package resourceattributesconverter // import "github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/resourceattributesconverter"
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const (
+	procKey      = "processors"
+	pipelinesKey = "service::pipelines"
+	// processorName is the resource processor added to the pipelines
+	processorName = "resource/lambda"
+)
+
+type converter struct {
+	attributes map[string]interface{}
+}
+
+// New returns a confmap.Converter, that adds a resource processor inserting
+// the given attributes to all pipelines. Attributes already set on the
+// telemetry are kept.
+func New(attributes map[string]interface{}) confmap.Converter {
+	return &converter{attributes: attributes}
+}
+
+func (c converter) Convert(_ context.Context, conf *confmap.Conf) error {
+	out := make(map[string]interface{})
+
+	pipelines, ok := conf.Get(pipelinesKey).(map[string]interface{})
+	if !ok || len(c.attributes) == 0 {
+		return nil
+	}
+
+	for name, pipeline := range pipelines {
+		pipeline, ok := pipeline.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		names, _ := pipeline["processors"].([]interface{})
+		if contains(names, processorName) {
+			continue
+		}
+
+		// The memory_limiter processors are kept first, as they must refuse data before it is processed
+		i := 0
+		for i < len(names) && strings.Split(fmt.Sprint(names[i]), "/")[0] == "memory_limiter" {
+			i++
+		}
+
+		processors := append(append(append([]interface{}{}, names[:i]...), processorName), names[i:]...)
+		out[fmt.Sprintf("%s::%s::processors", pipelinesKey, name)] = processors
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+
+	if !conf.IsSet(procKey + "::" + processorName) {
+		keys := make([]string, 0, len(c.attributes))
+		for key := range c.attributes {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		actions := make([]interface{}, 0, len(keys))
+		for _, key := range keys {
+			actions = append(actions, map[string]interface{}{
+				"key":    key,
+				"value":  c.attributes[key],
+				"action": "insert",
+			})
+		}
+
+		out[procKey+"::"+processorName] = map[string]interface{}{"attributes": actions}
+	}
+
+	return conf.Merge(confmap.NewFromStringMap(out))
+}
+
+func contains(names []interface{}, name string) bool {
+	for _, n := range names {
+		if fmt.Sprint(n) == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,83 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourceattributesconverter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+func TestConvert(t *testing.T) {
+	processor := map[string]any{
+		"attributes": []any{
+			map[string]any{"key": "cloud.provider", "value": "aws", "action": "insert"},
+			map[string]any{"key": "faas.max_memory", "value": 128, "action": "insert"},
+		},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		conf     *confmap.Conf
+		expected *confmap.Conf
+	}{
+		{
+			name:     "no pipelines",
+			conf:     confmap.New(),
+			expected: confmap.New(),
+		},
+		{
+			name: "pipeline without processors",
+			conf: confmap.NewFromStringMap(map[string]any{
+				"service": map[string]any{"pipelines": map[string]any{"traces": map[string]any{"receivers": []any{"otlp"}}}},
+			}),
+			expected: confmap.NewFromStringMap(map[string]any{
+				"processors": map[string]any{"resource/lambda": processor},
+				"service":    map[string]any{"pipelines": map[string]any{"traces": map[string]any{"receivers": []any{"otlp"}, "processors": []any{"resource/lambda"}}}},
+			}),
+		},
+		{
+			name: "after memory_limiter",
+			conf: confmap.NewFromStringMap(map[string]any{
+				"processors": map[string]any{"memory_limiter": nil, "batch": nil},
+				"service":    map[string]any{"pipelines": map[string]any{"logs": map[string]any{"processors": []any{"memory_limiter", "batch"}}}},
+			}),
+			expected: confmap.NewFromStringMap(map[string]any{
+				"processors": map[string]any{"memory_limiter": nil, "batch": nil, "resource/lambda": processor},
+				"service":    map[string]any{"pipelines": map[string]any{"logs": map[string]any{"processors": []any{"memory_limiter", "resource/lambda", "batch"}}}},
+			}),
+		},
+		{
+			name: "already configured",
+			conf: confmap.NewFromStringMap(map[string]any{
+				"processors": map[string]any{"resource/lambda": nil},
+				"service":    map[string]any{"pipelines": map[string]any{"metrics": map[string]any{"processors": []any{"resource/lambda"}}}},
+			}),
+			expected: confmap.NewFromStringMap(map[string]any{
+				"processors": map[string]any{"resource/lambda": nil},
+				"service":    map[string]any{"pipelines": map[string]any{"metrics": map[string]any{"processors": []any{"resource/lambda"}}}},
+			}),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New(map[string]any{"faas.max_memory": 128, "cloud.provider": "aws"})
+			err := c.Convert(context.Background(), tc.conf)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected.ToStringMap(), tc.conf.ToStringMap())
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package syncexportconverter removes the processors and queues holding data
+// back from the export, so the exporters have acknowledged the data of an
+// invocation once it went through the pipelines.
+package syncexportconverter // import "github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/syncexportconverter"
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/disablequeuedretryconverter"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const (
+	expKey       = "exporters"
+	pipelinesKey = "service::pipelines"
+)
+
+// processors are the processors exporting the data they receive later, in the background
+var processors = map[string]struct{}{
+	"batch":    {},
+	"decouple": {},
+}
+
+// bufferingProcessors are the processors holding data back until they decide
+// what to do with it, which can't be left out without changing the data
+// exported
+var bufferingProcessors = map[string]struct{}{
+	"groupbytrace":  {},
+	"tail_sampling": {},
+}
+
+// queueKeys are the settings enabling the queues of the exporters not using
+// sending_queue, by exporter type
+var queueKeys = map[string]string{
+	"prometheusremotewrite": "remote_write_queue::enabled",
+}
+
+type converter struct {
+}
+
+// New returns a confmap.Converter, that removes the batch and decouple
+// processors from all pipelines, and disables the queues of the exporters. It
+// fails if a pipeline uses a processor holding data back, such as
+// groupbytrace or tail_sampling.
+func New() confmap.Converter {
+	return &converter{}
+}
+
+func (c converter) Convert(ctx context.Context, conf *confmap.Conf) error {
+	out := make(map[string]interface{})
+
+	pipelines, _ := conf.Get(pipelinesKey).(map[string]interface{})
+
+	names := make([]string, 0, len(pipelines))
+	for name := range pipelines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		pipeline, ok := pipelines[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		procs, ok := pipeline["processors"].([]interface{})
+		if !ok {
+			continue
+		}
+
+		kept := []interface{}{}
+		for _, processor := range procs {
+			typ := strings.Split(fmt.Sprint(processor), "/")[0]
+			if _, ok := bufferingProcessors[typ]; ok {
+				return fmt.Errorf("the %v processor of the %s pipeline holds data back, the export of each invocation can't be awaited", processor, name)
+			}
+
+			if _, ok := processors[typ]; !ok {
+				kept = append(kept, processor)
+			}
+		}
+
+		if len(kept) != len(procs) {
+			out[fmt.Sprintf("%s::%s::processors", pipelinesKey, name)] = kept
+		}
+	}
+
+	if exps, ok := conf.Get(expKey).(map[string]interface{}); ok {
+		for name := range exps {
+			if key, ok := queueKeys[strings.Split(name, "/")[0]]; ok {
+				out[fmt.Sprintf("%s::%s::%s", expKey, name, key)] = false
+			}
+		}
+	}
+
+	if err := conf.Merge(confmap.NewFromStringMap(out)); err != nil {
+		return err
+	}
+
+	// Whatever OTEL_LAMBDA_DISABLE_QUEUED_RETRY is set to
+	return disablequeuedretryconverter.New().Convert(ctx, conf)
+}
@@ -0,0 +1,87 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncexportconverter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+func TestConvert(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		conf     *confmap.Conf
+		expected *confmap.Conf
+		err      error
+	}{
+		{
+			name:     "no pipelines",
+			conf:     confmap.New(),
+			expected: confmap.New(),
+			err:      nil,
+		},
+		{
+			name:     "no processors",
+			conf:     confmap.NewFromStringMap(map[string]any{"service": map[string]any{"pipelines": map[string]any{"traces": map[string]any{"receivers": []any{"otlp"}}}}}),
+			expected: confmap.NewFromStringMap(map[string]any{"service": map[string]any{"pipelines": map[string]any{"traces": map[string]any{"receivers": []any{"otlp"}}}}}),
+			err:      nil,
+		},
+		{
+			name:     "no deferring processors",
+			conf:     confmap.NewFromStringMap(map[string]any{"service": map[string]any{"pipelines": map[string]any{"traces": map[string]any{"processors": []any{"memory_limiter"}}}}}),
+			expected: confmap.NewFromStringMap(map[string]any{"service": map[string]any{"pipelines": map[string]any{"traces": map[string]any{"processors": []any{"memory_limiter"}}}}}),
+			err:      nil,
+		},
+		{
+			name:     "deferring processors",
+			conf:     confmap.NewFromStringMap(map[string]any{"service": map[string]any{"pipelines": map[string]any{"traces": map[string]any{"processors": []any{"memory_limiter", "batch", "decouple"}}, "logs/team": map[string]any{"processors": []any{"batch/logs"}}}}}),
+			expected: confmap.NewFromStringMap(map[string]any{"service": map[string]any{"pipelines": map[string]any{"traces": map[string]any{"processors": []any{"memory_limiter"}}, "logs/team": map[string]any{"processors": []any{}}}}}),
+			err:      nil,
+		},
+		{
+			name: "exporter queues",
+			conf: confmap.NewFromStringMap(map[string]any{"exporters": map[string]any{
+				"otlp":                  map[string]any{"sending_queue": map[string]any{"enabled": true}},
+				"otlphttp/team":         nil,
+				"prometheusremotewrite": map[string]any{"endpoint": "https://prometheus.example.com"},
+				"logging":               nil,
+			}}),
+			expected: confmap.NewFromStringMap(map[string]any{"exporters": map[string]any{
+				"otlp":                  map[string]any{"sending_queue": map[string]any{"enabled": false}},
+				"otlphttp/team":         map[string]any{"sending_queue": map[string]any{"enabled": false}},
+				"prometheusremotewrite": map[string]any{"endpoint": "https://prometheus.example.com", "remote_write_queue": map[string]any{"enabled": false}},
+				"logging":               nil,
+			}}),
+			err: nil,
+		},
+		{
+			name:     "buffering processors",
+			conf:     confmap.NewFromStringMap(map[string]any{"service": map[string]any{"pipelines": map[string]any{"traces": map[string]any{"processors": []any{"groupbytrace", "tail_sampling/errors", "batch"}}}}}),
+			expected: confmap.NewFromStringMap(map[string]any{"service": map[string]any{"pipelines": map[string]any{"traces": map[string]any{"processors": []any{"groupbytrace", "tail_sampling/errors", "batch"}}}}}),
+			err:      errors.New("the groupbytrace processor of the traces pipeline holds data back, the export of each invocation can't be awaited"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New()
+			err := c.Convert(context.Background(), tc.conf)
+			assert.Equal(t, err, tc.err)
+			assert.Equal(t, tc.expected.ToStringMap(), tc.conf.ToStringMap())
+		})
+	}
+}
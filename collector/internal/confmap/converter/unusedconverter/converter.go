@@ -0,0 +1,89 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package unusedconverter warns about the components of the collector
+// configurations which nothing uses: the collector accepts them, but doesn't
+// create them, so their settings are ignored without notice.
+package unusedconverter // import "github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/unusedconverter"
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const (
+	pipelinesKey  = "service::pipelines"
+	extensionsKey = "service::extensions"
+)
+
+type converter struct {
+	warn func(string)
+}
+
+// New returns a confmap.Converter, that calls warn for each receiver,
+// processor and exporter used by no pipeline, and each extension missing from
+// service::extensions. The configuration is left as is.
+func New(warn func(string)) confmap.Converter {
+	return &converter{warn: warn}
+}
+
+func (c converter) Convert(_ context.Context, conf *confmap.Conf) error {
+	used := map[string]map[string]bool{
+		"receivers":  {},
+		"processors": {},
+		"exporters":  {},
+		"extensions": {},
+	}
+
+	pipelines, _ := conf.Get(pipelinesKey).(map[string]interface{})
+	for _, pipeline := range pipelines {
+		pipeline, _ := pipeline.(map[string]interface{})
+		for _, kind := range []string{"receivers", "processors", "exporters"} {
+			names, _ := pipeline[kind].([]interface{})
+			for _, name := range names {
+				used[kind][fmt.Sprint(name)] = true
+			}
+		}
+	}
+
+	names, _ := conf.Get(extensionsKey).([]interface{})
+	for _, name := range names {
+		used["extensions"][fmt.Sprint(name)] = true
+	}
+
+	for _, kind := range []string{"receivers", "processors", "exporters", "extensions"} {
+		components, _ := conf.Get(kind).(map[string]interface{})
+
+		unused := make([]string, 0, len(components))
+		for name := range components {
+			if !used[kind][name] {
+				unused = append(unused, name)
+			}
+		}
+		sort.Strings(unused)
+
+		for _, name := range unused {
+			if kind == "extensions" {
+				c.warn(fmt.Sprintf("%s::%s is not in %s, it is ignored", kind, name, extensionsKey))
+			} else {
+				c.warn(fmt.Sprintf("%s::%s is used by no pipeline, it is ignored", kind, name))
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unusedconverter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+func TestConvert(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		conf     map[string]any
+		warnings []string
+	}{
+		{
+			name: "empty",
+			conf: map[string]any{},
+		},
+		{
+			name: "all used",
+			conf: map[string]any{
+				"receivers":  map[string]any{"otlp": nil},
+				"processors": map[string]any{"batch": nil},
+				"exporters":  map[string]any{"logging": nil},
+				"extensions": map[string]any{"sigv4auth": nil},
+				"service": map[string]any{
+					"extensions": []any{"sigv4auth"},
+					"pipelines":  map[string]any{"traces": map[string]any{"receivers": []any{"otlp"}, "processors": []any{"batch"}, "exporters": []any{"logging"}}},
+				},
+			},
+		},
+		{
+			name: "unused",
+			conf: map[string]any{
+				"receivers":  map[string]any{"otlp": nil, "telemetryapi": nil},
+				"processors": map[string]any{"batch": nil, "filter/b": nil, "attributes/a": nil},
+				"exporters":  map[string]any{"logging": nil, "otlp": nil},
+				"extensions": map[string]any{"sigv4auth": nil},
+				"service": map[string]any{
+					"pipelines": map[string]any{
+						"traces":  map[string]any{"receivers": []any{"otlp"}, "processors": []any{"batch"}, "exporters": []any{"logging"}},
+						"metrics": map[string]any{"receivers": []any{"otlp"}, "exporters": []any{"otlp"}},
+					},
+				},
+			},
+			warnings: []string{
+				"receivers::telemetryapi is used by no pipeline, it is ignored",
+				"processors::attributes/a is used by no pipeline, it is ignored",
+				"processors::filter/b is used by no pipeline, it is ignored",
+				"extensions::sigv4auth is not in service::extensions, it is ignored",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var warnings []string
+			c := New(func(warning string) { warnings = append(warnings, warning) })
+			conf := confmap.NewFromStringMap(tc.conf)
+			err := c.Convert(context.Background(), conf)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.conf, conf.ToStringMap())
+			assert.Equal(t, tc.warnings, warnings)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cacheprovider caches the configurations retrieved from remote
+// sources on the local disk, so the restarts of the collector within a
+// sandbox don't fetch them again.
+package cacheprovider // import "github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/provider/cacheprovider"
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/provider/jsonprovider"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/utility"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+type provider struct {
+	confmap.Provider
+	dir string
+	ttl time.Duration
+}
+
+// Wrap returns a confmap.Provider retrieving configurations with p, and
+// keeping them in dir for ttl. A configuration cached for less than ttl is
+// read from dir instead of being retrieved again. Failures to write the cache
+// are logged, the configuration retrieved being used anyway.
+func Wrap(p confmap.Provider, dir string, ttl time.Duration) confmap.Provider {
+	return &provider{Provider: p, dir: dir, ttl: ttl}
+}
+
+func (p *provider) Retrieve(ctx context.Context, uri string, watcher confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	path := filepath.Join(p.dir, key(uri))
+
+	if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) < p.ttl {
+		if content, err := os.ReadFile(filepath.Clean(path)); err == nil {
+			return jsonprovider.NewRetrieved(content)
+		}
+	}
+
+	retrieved, err := p.Provider.Retrieve(ctx, uri, watcher)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := retrieved.AsRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := write(path, raw); err != nil {
+		utility.LogError(err, "cacheprovider", "Failed to cache the configuration", utility.KeyValue{K: "uri", V: uri})
+	}
+
+	return retrieved, nil
+}
+
+// Invalidate removes the configurations cached in dir, e.g. once they are
+// known to have changed.
+func Invalidate(dir string) error {
+	return os.RemoveAll(dir)
+}
+
+// key returns the name of the cache file of uri, which may hold characters
+// which aren't valid in file names.
+func key(uri string) string {
+	sum := sha256.Sum256([]byte(uri))
+	return hex.EncodeToString(sum[:]) + ".json"
+}
+
+// write stores raw at path, replacing the file at once so concurrent reads
+// never see a partial configuration.
+func write(path string, raw interface{}) error {
+	content, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	return nil
+}
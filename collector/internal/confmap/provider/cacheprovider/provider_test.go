@@ -0,0 +1,110 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cacheprovider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+type countingProvider struct {
+	raw   interface{}
+	err   error
+	calls int
+}
+
+func (p *countingProvider) Retrieve(context.Context, string, confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	return confmap.NewRetrieved(p.raw)
+}
+
+func (p *countingProvider) Scheme() string {
+	return "s3"
+}
+
+func (p *countingProvider) Shutdown(context.Context) error {
+	return nil
+}
+
+func TestRetrieve(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	inner := &countingProvider{raw: map[string]interface{}{"exporters": map[string]interface{}{"otlp": map[string]interface{}{"endpoint": "localhost:4317", "timeout": 5}}}}
+	p := Wrap(inner, dir, time.Minute)
+	assert.Equal(t, "s3", p.Scheme())
+
+	const uri = "s3://bucket.s3.eu-west-1.amazonaws.com/config.yaml"
+	for i := 0; i < 3; i++ {
+		retrieved, err := p.Retrieve(context.Background(), uri, nil)
+		require.NoError(t, err)
+
+		raw, err := retrieved.AsRaw()
+		require.NoError(t, err)
+		assert.Equal(t, inner.raw, raw)
+	}
+	assert.Equal(t, 1, inner.calls)
+
+	// Other configurations are cached apart
+	_, err := p.Retrieve(context.Background(), uri+".other", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, inner.calls)
+
+	// Expired configurations are retrieved again
+	past := time.Now().Add(-2 * time.Minute)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, key(uri)), past, past))
+	_, err = p.Retrieve(context.Background(), uri, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, inner.calls)
+
+	require.NoError(t, Invalidate(dir))
+	_, err = p.Retrieve(context.Background(), uri, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 4, inner.calls)
+
+	// Failures aren't cached
+	require.NoError(t, Invalidate(dir))
+	inner.err = errors.New("AccessDenied")
+	_, err = p.Retrieve(context.Background(), uri, nil)
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(dir, key(uri)))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRetrieveUnwritableCache(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0600))
+
+	// The cache directory can't be created under a file
+	inner := &countingProvider{raw: "value"}
+	p := Wrap(inner, filepath.Join(file, "cache"), time.Minute)
+
+	retrieved, err := p.Retrieve(context.Background(), "s3://bucket.s3.eu-west-1.amazonaws.com/config.yaml", nil)
+	require.NoError(t, err)
+
+	raw, err := retrieved.AsRaw()
+	require.NoError(t, err)
+	assert.Equal(t, "value", raw)
+}
@@ -0,0 +1,81 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fileprovider reads collector configurations from files, in YAML or
+// in JSON.
+package fileprovider // import "github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/provider/fileprovider"
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/provider/jsonprovider"
+	"go.opentelemetry.io/collector/confmap"
+	"gopkg.in/yaml.v3"
+)
+
+const schemeName = "file"
+
+type provider struct{}
+
+// New returns a confmap.Provider that reads the configuration from a file.
+//
+// This Provider supports "file" scheme, and can be called with a "uri" that
+// follows:
+//
+//	file-uri : file:<path>
+//
+// Files with a .json extension, or whose content is a JSON document, are
+// read as JSON, others as YAML.
+//
+// Examples:
+// `file:/opt/collector-config/config.yaml`
+// `file:/var/task/collector.json`
+func New() confmap.Provider {
+	return &provider{}
+}
+
+func (p *provider) Retrieve(_ context.Context, uri string, _ confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	if !strings.HasPrefix(uri, schemeName+":") {
+		return nil, fmt.Errorf("%q uri is not supported by %q provider", uri, schemeName)
+	}
+
+	path := filepath.Clean(uri[len(schemeName)+1:])
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the file %v: %w", uri, err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") || jsonprovider.IsJSON(content) {
+		return jsonprovider.NewRetrieved(content)
+	}
+
+	var raw interface{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+
+	return confmap.NewRetrieved(raw)
+}
+
+func (*provider) Scheme() string {
+	return schemeName
+}
+
+func (*provider) Shutdown(context.Context) error {
+	return nil
+}
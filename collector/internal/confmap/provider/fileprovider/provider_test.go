@@ -0,0 +1,78 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fileprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetrieve(t *testing.T) {
+	dir := t.TempDir()
+	expected := map[string]interface{}{"exporters": map[string]interface{}{"otlphttp": map[string]interface{}{"endpoint": "https://example.com"}}}
+
+	for _, tc := range []struct {
+		name    string
+		file    string
+		content string
+		err     bool
+	}{
+		{
+			name:    "yaml",
+			file:    "config.yaml",
+			content: "exporters:\n  otlphttp:\n    endpoint: https://example.com\n",
+		},
+		{
+			name:    "json extension",
+			file:    "config.json",
+			content: `{"exporters": {"otlphttp": {"endpoint": "https:\/\/example.com"}}}`,
+		},
+		{
+			name:    "json content",
+			file:    "config.yaml",
+			content: "\n{\n\t\"exporters\": {\"otlphttp\": {\"endpoint\": \"https:\\/\\/example.com\"}}\n}\n",
+		},
+		{
+			name:    "invalid json",
+			file:    "config.json",
+			content: "exporters:\n  otlphttp:\n",
+			err:     true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, tc.file)
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0600))
+
+			ret, err := New().Retrieve(context.Background(), "file:"+path, nil)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			conf, err := ret.AsConf()
+			require.NoError(t, err)
+			assert.Equal(t, expected, conf.ToStringMap())
+		})
+	}
+
+	_, err := New().Retrieve(context.Background(), "file:"+filepath.Join(dir, "missing.yaml"), nil)
+	assert.Error(t, err)
+}
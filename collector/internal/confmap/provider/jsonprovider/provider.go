@@ -0,0 +1,106 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jsonprovider reads collector configurations written in JSON, as
+// generated by tools such as the AWS CDK or Terraform. Most JSON documents
+// are valid YAML, but not all of them, e.g. those escaping slashes or
+// repeating keys.
+package jsonprovider // import "github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/provider/jsonprovider"
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const schemeName = "json"
+
+type provider struct{}
+
+// New returns a confmap.Provider that reads the configuration from the
+// inline JSON document of the uri.
+//
+// This Provider supports "json" scheme, and can be called with a "uri" that
+// follows:
+//
+//	json-uri : json:<json-document>
+//
+// Examples:
+// `json:{"processors":{"batch":{"timeout":"100ms"}}}`
+func New() confmap.Provider {
+	return &provider{}
+}
+
+func (p *provider) Retrieve(_ context.Context, uri string, _ confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	if !strings.HasPrefix(uri, schemeName+":") {
+		return nil, fmt.Errorf("%q uri is not supported by %q provider", uri, schemeName)
+	}
+
+	return NewRetrieved([]byte(uri[len(schemeName)+1:]))
+}
+
+func (*provider) Scheme() string {
+	return schemeName
+}
+
+func (*provider) Shutdown(context.Context) error {
+	return nil
+}
+
+// IsJSON returns whether content is a JSON document, rather than YAML.
+func IsJSON(content []byte) bool {
+	trimmed := bytes.TrimSpace(content)
+	return len(trimmed) > 0 && trimmed[0] == '{' && json.Valid(trimmed)
+}
+
+// NewRetrieved returns the configuration of the JSON document content.
+func NewRetrieved(content []byte) (*confmap.Retrieved, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var raw interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON configuration: %w", err)
+	}
+
+	return confmap.NewRetrieved(numbers(raw))
+}
+
+// numbers replaces the json.Number values of raw by integers where possible,
+// as the YAML decoder returns them, or floats otherwise.
+func numbers(raw interface{}) interface{} {
+	switch value := raw.(type) {
+	case map[string]interface{}:
+		for k, v := range value {
+			value[k] = numbers(v)
+		}
+	case []interface{}:
+		for i, v := range value {
+			value[i] = numbers(v)
+		}
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return int(i)
+		}
+
+		f, _ := value.Float64()
+		return f
+	}
+
+	return raw
+}
@@ -0,0 +1,65 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonprovider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsJSON(t *testing.T) {
+	for content, expected := range map[string]bool{
+		`{"receivers": {"otlp": null}}`: true,
+		"\n\t{\"a\": [1, 2]}\n":         true,
+		`{receivers: {otlp: }}`:         false,
+		"receivers:\n  otlp:\n":         false,
+		`["a"]`:                         false,
+		"":                              false,
+	} {
+		assert.Equal(t, expected, IsJSON([]byte(content)), content)
+	}
+}
+
+func TestRetrieve(t *testing.T) {
+	p := New()
+	assert.Equal(t, "json", p.Scheme())
+
+	ret, err := p.Retrieve(context.Background(), `json:{"exporters":{"otlphttp":{"endpoint":"https:\/\/example.com","timeout":1.5,"sending_queue":{"num_consumers":2}}},"a":"x","a":"y"}`, nil)
+	require.NoError(t, err)
+
+	conf, err := ret.AsConf()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"exporters": map[string]interface{}{
+			"otlphttp": map[string]interface{}{
+				"endpoint":      "https://example.com",
+				"timeout":       1.5,
+				"sending_queue": map[string]interface{}{"num_consumers": 2},
+			},
+		},
+		"a": "y",
+	}, conf.ToStringMap())
+
+	_, err = p.Retrieve(context.Background(), `json:{"a":`, nil)
+	assert.ErrorContains(t, err, "invalid JSON configuration")
+
+	_, err = p.Retrieve(context.Background(), `yaml:a: b`, nil)
+	assert.Error(t, err)
+
+	assert.NoError(t, p.Shutdown(context.Background()))
+}
@@ -0,0 +1,116 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package profileprovider selects a profile of the configurations holding
+// several, e.g. one per environment, so the same configuration can be
+// promoted from development to production.
+package profileprovider // import "github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/provider/profileprovider"
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const profilesKey = "profiles"
+
+type provider struct {
+	confmap.Provider
+	profile string
+}
+
+// Wrap returns a confmap.Provider retrieving configurations with p, which
+// replaces the profiles of those holding a "profiles" map of named profiles by
+// the given profile, merged over the rest of the configuration. Maps are
+// merged key by key, other values of the profile replace those of the
+// configuration. The profiles are selected before the configuration is
+// expanded, so the ${scheme:...} URIs of the other profiles, e.g. their
+// secrets, are never retrieved.
+func Wrap(p confmap.Provider, profile string) confmap.Provider {
+	return &provider{Provider: p, profile: profile}
+}
+
+func (p *provider) Retrieve(ctx context.Context, uri string, watcher confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	retrieved, err := p.Provider.Retrieve(ctx, uri, watcher)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := retrieved.AsRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	conf, ok := raw.(map[string]interface{})
+	if !ok {
+		return retrieved, nil
+	}
+
+	profiles, ok := conf[profilesKey]
+	if !ok {
+		return retrieved, nil
+	}
+
+	selected, err := p.selectProfile(uri, profiles)
+	if err != nil {
+		return nil, err
+	}
+
+	base := make(map[string]interface{}, len(conf))
+	for key, value := range conf {
+		if key != profilesKey {
+			base[key] = value
+		}
+	}
+
+	merged := confmap.NewFromStringMap(base)
+	if err := merged.Merge(confmap.NewFromStringMap(selected)); err != nil {
+		return nil, err
+	}
+
+	return confmap.NewRetrieved(merged.ToStringMap(), confmap.WithRetrievedClose(retrieved.Close))
+}
+
+// selectProfile returns the settings of the profile in profiles.
+func (p *provider) selectProfile(uri string, profiles interface{}) (map[string]interface{}, error) {
+	named, ok := profiles.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%q of uri %q must be a map of named profiles", profilesKey, uri)
+	}
+
+	settings, ok := named[p.profile]
+	if !ok {
+		names := make([]string, 0, len(named))
+		for name := range named {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return nil, fmt.Errorf("uri %q has no profile %q, its profiles are: %s", uri, p.profile, strings.Join(names, ", "))
+	}
+
+	if settings == nil {
+		return map[string]interface{}{}, nil
+	}
+
+	selected, ok := settings.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("profile %q of uri %q must be a map", p.profile, uri)
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,117 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profileprovider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/provider/yamlprovider"
+)
+
+const profiles = `
+exporters:
+  otlp:
+    endpoint: localhost:4317
+    compression: gzip
+service:
+  pipelines:
+    traces:
+      exporters: [otlp]
+profiles:
+  dev:
+  prod:
+    exporters:
+      otlp:
+        endpoint: collector.example.com:4317
+        headers:
+          api-key: ${secretsmanager://prod-api-key}
+`
+
+func TestRetrieve(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		uri      string
+		profile  string
+		expected interface{}
+		err      string
+	}{
+		{
+			name:     "no profiles",
+			uri:      "yaml:exporters::otlp::endpoint: localhost:4317",
+			profile:  "prod",
+			expected: map[string]interface{}{"exporters::otlp::endpoint": "localhost:4317"},
+		},
+		{
+			name:     "value",
+			uri:      "yaml:localhost:4317",
+			profile:  "prod",
+			expected: "localhost:4317",
+		},
+		{
+			name:    "empty profile",
+			uri:     "yaml:" + profiles,
+			profile: "dev",
+			expected: map[string]interface{}{
+				"exporters": map[string]interface{}{"otlp": map[string]interface{}{"endpoint": "localhost:4317", "compression": "gzip"}},
+				"service":   map[string]interface{}{"pipelines": map[string]interface{}{"traces": map[string]interface{}{"exporters": []interface{}{"otlp"}}}},
+			},
+		},
+		{
+			name:    "profile",
+			uri:     "yaml:" + profiles,
+			profile: "prod",
+			expected: map[string]interface{}{
+				"exporters": map[string]interface{}{"otlp": map[string]interface{}{
+					"endpoint":    "collector.example.com:4317",
+					"compression": "gzip",
+					"headers":     map[string]interface{}{"api-key": "${secretsmanager://prod-api-key}"},
+				}},
+				"service": map[string]interface{}{"pipelines": map[string]interface{}{"traces": map[string]interface{}{"exporters": []interface{}{"otlp"}}}},
+			},
+		},
+		{
+			name:    "unknown profile",
+			uri:     "yaml:" + profiles,
+			profile: "staging",
+			err:     "has no profile \"staging\", its profiles are: dev, prod",
+		},
+		{
+			name:    "invalid profiles",
+			uri:     "yaml:profiles: [dev, prod]",
+			profile: "dev",
+			err:     "must be a map of named profiles",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := Wrap(yamlprovider.New(), tc.profile)
+			assert.Equal(t, "yaml", p.Scheme())
+
+			retrieved, err := p.Retrieve(context.Background(), tc.uri, nil)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			raw, err := retrieved.AsRaw()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, raw)
+			assert.NoError(t, retrieved.Close(context.Background()))
+		})
+	}
+}
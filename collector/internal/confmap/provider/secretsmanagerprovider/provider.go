@@ -0,0 +1,192 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package secretsmanagerprovider resolves configuration values from AWS
+// Secrets Manager, so secrets such as exporter API keys don't have to be
+// written into the configuration or the environment of the function.
+package secretsmanagerprovider // import "github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/provider/secretsmanagerprovider"
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const (
+	schemeName = "secretsmanager"
+	uriPrefix  = schemeName + "://"
+)
+
+type secretsManagerClient interface {
+	GetSecretValue(context.Context, *secretsmanager.GetSecretValueInput, ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+type provider struct {
+	mu     sync.Mutex
+	client secretsManagerClient
+	// secrets caches the values of the secrets retrieved, by secret ID, so
+	// the keys of a JSON secret are resolved with a single call
+	secrets map[string]string
+}
+
+// New returns a confmap.Provider that reads configuration values from AWS
+// Secrets Manager.
+//
+// This Provider supports "secretsmanager" scheme, and can be called with a
+// "uri" that follows:
+//
+//	secretsmanager-uri : secretsmanager://[SECRET-ID][#KEY]
+//
+// where [SECRET-ID] is the name or the ARN of the secret, and [KEY] is the
+// key to read from a secret holding a JSON object, or the dot separated path
+// of a key of nested objects. Without a key, the whole secret string is the
+// value. A secret in another region than the function
+// must be referred to by its ARN.
+//
+// Examples:
+// `${secretsmanager://otlp-api-key}`
+// `${secretsmanager://arn:aws:secretsmanager:eu-west-1:123456789012:secret:otlp-AbCdEf#client_secret}`
+// `${secretsmanager://exporters#datadog.api_key}`
+func New() confmap.Provider {
+	return &provider{secrets: map[string]string{}}
+}
+
+func (p *provider) Retrieve(ctx context.Context, uri string, _ confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	if !strings.HasPrefix(uri, uriPrefix) {
+		return nil, fmt.Errorf("%q uri is not supported by %q provider", uri, schemeName)
+	}
+
+	secretID, key, hasKey := strings.Cut(strings.TrimPrefix(uri, uriPrefix), "#")
+	if secretID == "" {
+		return nil, fmt.Errorf("%q uri has no secret ID", uri)
+	}
+
+	secret, err := p.secret(ctx, secretID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch secret of uri %q: %w", uri, err)
+	}
+
+	if !hasKey {
+		return confmap.NewRetrieved(secret)
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &values); err != nil {
+		return nil, fmt.Errorf("secret of uri %q is not a JSON object: %w", uri, err)
+	}
+
+	value, ok := lookup(values, key)
+	if !ok {
+		return nil, fmt.Errorf("secret of uri %q has no key %q", uri, key)
+	}
+
+	if _, ok := value.(string); !ok {
+		// Numbers, booleans and nested values are kept in their JSON form
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+
+		value = string(raw)
+	}
+
+	return confmap.NewRetrieved(value)
+}
+
+// lookup returns the value of key in values, or else of the key at the dot
+// separated path key in the nested objects of values, so keys containing dots
+// can still be read.
+func lookup(values map[string]interface{}, key string) (interface{}, bool) {
+	if value, ok := values[key]; ok {
+		return value, true
+	}
+
+	parent, child, nested := strings.Cut(key, ".")
+	if !nested {
+		return nil, false
+	}
+
+	for nested {
+		if object, ok := values[parent].(map[string]interface{}); ok {
+			if value, ok := lookup(object, child); ok {
+				return value, true
+			}
+		}
+
+		// The dot may be part of the key itself, e.g. "a.b" in {"a.b": {"c": 1}}
+		var next string
+		next, child, nested = strings.Cut(child, ".")
+		parent += "." + next
+	}
+
+	return nil, false
+}
+
+// secret returns the value of the secret, fetching it on first use.
+func (p *provider) secret(ctx context.Context, secretID string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if secret, ok := p.secrets[secretID]; ok {
+		return secret, nil
+	}
+
+	// initialize the Secrets Manager client in the first call of Retrieve
+	if p.client == nil {
+		cfg, err := config.LoadDefaultConfig(context.Background())
+		if err != nil {
+			return "", fmt.Errorf("failed to load configurations to initialize an AWS SDK client, error: %w", err)
+		}
+		p.client = secretsmanager.NewFromConfig(cfg)
+	}
+
+	var optFns []func(*secretsmanager.Options)
+	if secretARN, err := arn.Parse(secretID); err == nil && secretARN.Region != "" {
+		optFns = append(optFns, func(o *secretsmanager.Options) {
+			o.Region = secretARN.Region
+		})
+	}
+
+	output, err := p.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretID),
+	}, optFns...)
+	if err != nil {
+		return "", err
+	}
+
+	secret := aws.ToString(output.SecretString)
+	if output.SecretString == nil {
+		secret = string(output.SecretBinary)
+	}
+
+	p.secrets[secretID] = secret
+
+	return secret, nil
+}
+
+func (*provider) Scheme() string {
+	return schemeName
+}
+
+func (*provider) Shutdown(context.Context) error {
+	return nil
+}
@@ -0,0 +1,181 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretsmanagerprovider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClient struct {
+	secrets map[string]*secretsmanager.GetSecretValueOutput
+	calls   []string
+	regions []string
+}
+
+func (c *fakeClient) GetSecretValue(_ context.Context, input *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	secretID := aws.ToString(input.SecretId)
+	c.calls = append(c.calls, secretID)
+
+	options := secretsmanager.Options{Region: "us-east-1"}
+	for _, optFn := range optFns {
+		optFn(&options)
+	}
+	c.regions = append(c.regions, options.Region)
+
+	output, ok := c.secrets[secretID]
+	if !ok {
+		return nil, errors.New("ResourceNotFoundException")
+	}
+
+	return output, nil
+}
+
+const remoteARN = "arn:aws:secretsmanager:eu-west-1:123456789012:secret:remote-AbCdEf"
+
+func newTestProvider() (*provider, *fakeClient) {
+	client := &fakeClient{secrets: map[string]*secretsmanager.GetSecretValueOutput{
+		"api-key":   {SecretString: aws.String("s3cr3t")},
+		"binary":    {SecretBinary: []byte("b1n4ry")},
+		"oauth2":    {SecretString: aws.String(`{"client_id":"id","client_secret":"secret","port":4317,"enabled":true}`)},
+		remoteARN:   {SecretString: aws.String("remote")},
+		"not-json":  {SecretString: aws.String("plain")},
+		"exporters": {SecretString: aws.String(`{"datadog":{"api_key":"dd"},"honeycomb.io":{"team":"hc"},"otlp":{"headers":{"x.api.key":"otlp"}}}`)},
+	}}
+
+	p := New().(*provider)
+	p.client = client
+
+	return p, client
+}
+
+func TestRetrieve(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		uri      string
+		expected interface{}
+		err      string
+	}{
+		{
+			name:     "secret string",
+			uri:      "secretsmanager://api-key",
+			expected: "s3cr3t",
+		},
+		{
+			name:     "secret binary",
+			uri:      "secretsmanager://binary",
+			expected: "b1n4ry",
+		},
+		{
+			name:     "json key",
+			uri:      "secretsmanager://oauth2#client_secret",
+			expected: "secret",
+		},
+		{
+			name:     "json number",
+			uri:      "secretsmanager://oauth2#port",
+			expected: "4317",
+		},
+		{
+			name:     "nested key",
+			uri:      "secretsmanager://exporters#datadog.api_key",
+			expected: "dd",
+		},
+		{
+			name:     "nested key with dots",
+			uri:      "secretsmanager://exporters#honeycomb.io.team",
+			expected: "hc",
+		},
+		{
+			name:     "nested object",
+			uri:      "secretsmanager://exporters#otlp.headers",
+			expected: `{"x.api.key":"otlp"}`,
+		},
+		{
+			name:     "nested key ending with dots",
+			uri:      "secretsmanager://exporters#otlp.headers.x.api.key",
+			expected: "otlp",
+		},
+		{
+			name: "unknown nested key",
+			uri:  "secretsmanager://exporters#datadog.app_key",
+			err:  `secret of uri "secretsmanager://exporters#datadog.app_key" has no key "datadog.app_key"`,
+		},
+		{
+			name:     "arn",
+			uri:      "secretsmanager://" + remoteARN,
+			expected: "remote",
+		},
+		{
+			name: "unsupported scheme",
+			uri:  "s3://api-key",
+			err:  `"s3://api-key" uri is not supported by "secretsmanager" provider`,
+		},
+		{
+			name: "no secret id",
+			uri:  "secretsmanager://#key",
+			err:  `"secretsmanager://#key" uri has no secret ID`,
+		},
+		{
+			name: "unknown secret",
+			uri:  "secretsmanager://unknown",
+			err:  `failed to fetch secret of uri "secretsmanager://unknown": ResourceNotFoundException`,
+		},
+		{
+			name: "unknown key",
+			uri:  "secretsmanager://oauth2#token",
+			err:  `secret of uri "secretsmanager://oauth2#token" has no key "token"`,
+		},
+		{
+			name: "key of a plain secret",
+			uri:  "secretsmanager://not-json#key",
+			err:  `secret of uri "secretsmanager://not-json#key" is not a JSON object: invalid character 'p' looking for beginning of value`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, _ := newTestProvider()
+
+			retrieved, err := p.Retrieve(context.Background(), tc.uri, nil)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			raw, err := retrieved.AsRaw()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, raw)
+		})
+	}
+}
+
+func TestRetrieveCache(t *testing.T) {
+	p, client := newTestProvider()
+
+	for _, uri := range []string{"secretsmanager://oauth2#client_id", "secretsmanager://oauth2#client_secret", "secretsmanager://" + remoteARN} {
+		_, err := p.Retrieve(context.Background(), uri, nil)
+		require.NoError(t, err)
+	}
+
+	// The keys of a secret are read from a single call, the region is the one of an ARN
+	assert.Equal(t, []string{"oauth2", remoteARN}, client.calls)
+	assert.Equal(t, []string{"us-east-1", "eu-west-1"}, client.regions)
+}
@@ -0,0 +1,218 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sigv4httpprovider reads collector configurations over HTTPS from
+// endpoints requiring AWS Signature Version 4, e.g. API Gateway APIs with IAM
+// authorization or Lambda function URLs, signing the requests with the
+// credentials of the function, so configurations don't have to be served
+// from public unauthenticated URLs.
+package sigv4httpprovider // import "github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/provider/sigv4httpprovider"
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/provider/jsonprovider"
+	"go.opentelemetry.io/collector/confmap"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	schemeName = "sigv4+https"
+	// defaultService is the service requests are signed for when it can't be
+	// told from the host, e.g. private APIs behind custom domain names
+	defaultService = "execute-api"
+	// emptyPayloadHash is the SHA-256 of the empty body of the requests
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	// requestTimeout bounds the retrieval of a configuration
+	requestTimeout = 10 * time.Second
+)
+
+// hostPatterns match the hosts of the AWS endpoints whose service and region
+// are part of the host name.
+var hostPatterns = map[string]*regexp.Regexp{
+	"execute-api": regexp.MustCompile(`^[^.]+\.execute-api\.([a-z0-9-]+)\.amazonaws\.com$`),
+	"lambda":      regexp.MustCompile(`^[^.]+\.lambda-url\.([a-z0-9-]+)\.on\.aws$`),
+}
+
+type provider struct {
+	mu     sync.Mutex
+	client *http.Client
+}
+
+// New returns a confmap.Provider that reads the configuration from an HTTPS
+// URL, with requests signed with AWS Signature Version 4.
+//
+// This Provider supports "sigv4+https" scheme, and can be called with a "uri"
+// that follows:
+//
+//	sigv4+https-uri : sigv4+https://[HOST][PATH]
+//
+// The service and region the requests are signed for are read from the
+// host of API Gateway and Lambda function URL endpoints, else the requests
+// are signed for API Gateway in the region of the function. The response is
+// read as JSON if it is a JSON document, else as YAML.
+//
+// Examples:
+// `sigv4+https://abcdef1234.execute-api.eu-west-1.amazonaws.com/prod/collector`
+// `sigv4+https://abcdef1234.lambda-url.eu-west-1.on.aws/config.yaml`
+func New() confmap.Provider {
+	return &provider{}
+}
+
+func (p *provider) Retrieve(ctx context.Context, uri string, _ confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	if !strings.HasPrefix(uri, schemeName+":") {
+		return nil, fmt.Errorf("%q uri is not supported by %q provider", uri, schemeName)
+	}
+
+	client, err := p.httpClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	url := URL(uri)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("unable to download the file via HTTPS GET for uri %q: %w", uri, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to load resource from uri %q: %s", uri, response.Status)
+	}
+
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the response body of uri %q: %w", uri, err)
+	}
+
+	if jsonprovider.IsJSON(content) {
+		return jsonprovider.NewRetrieved(content)
+	}
+
+	var raw interface{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+
+	return confmap.NewRetrieved(raw)
+}
+
+// httpClient returns the signing client, creating it on first use.
+func (p *provider) httpClient(ctx context.Context) (*http.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.client == nil {
+		client, err := NewClient(ctx, requestTimeout)
+		if err != nil {
+			return nil, err
+		}
+		p.client = client
+	}
+
+	return p.client, nil
+}
+
+func (*provider) Scheme() string {
+	return schemeName
+}
+
+func (*provider) Shutdown(context.Context) error {
+	return nil
+}
+
+// URL returns the HTTPS URL of a sigv4+https uri.
+func URL(uri string) string {
+	return strings.TrimPrefix(uri, "sigv4+")
+}
+
+// NewClient returns an HTTP client signing its requests with the credentials
+// of the default AWS configuration, e.g. the execution role of the function.
+func NewClient(ctx context.Context, timeout time.Duration) (*http.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configurations to initialize an AWS SDK client, error: %w", err)
+	}
+
+	return &http.Client{
+		Transport: NewTransport(http.DefaultTransport, cfg.Credentials, cfg.Region),
+		Timeout:   timeout,
+	}, nil
+}
+
+// NewTransport returns an http.RoundTripper signing the requests without a
+// body it sends through base with credentials. region is the region of the
+// requests to hosts which don't tell theirs.
+func NewTransport(base http.RoundTripper, credentials aws.CredentialsProvider, region string) http.RoundTripper {
+	return &signingTransport{
+		base:        base,
+		credentials: credentials,
+		region:      region,
+		signer:      v4.NewSigner(),
+	}
+}
+
+type signingTransport struct {
+	base        http.RoundTripper
+	credentials aws.CredentialsProvider
+	region      string
+	signer      *v4.Signer
+}
+
+func (t *signingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body != nil && request.Body != http.NoBody {
+		return nil, fmt.Errorf("cannot sign the body of requests to %s", request.URL)
+	}
+
+	credentials, err := t.credentials.Retrieve(request.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+
+	service, region := t.signingName(request.URL.Hostname())
+
+	// The request must not be modified by a RoundTripper
+	signed := request.Clone(request.Context())
+	if err := t.signer.SignHTTP(request.Context(), credentials, signed, emptyPayloadHash, service, region, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to sign the request to %s: %w", request.URL, err)
+	}
+
+	return t.base.RoundTrip(signed)
+}
+
+// signingName returns the service and region to sign requests to host for.
+func (t *signingTransport) signingName(host string) (string, string) {
+	for service, pattern := range hostPatterns {
+		if matches := pattern.FindStringSubmatch(host); matches != nil {
+			return service, matches[1]
+		}
+	}
+
+	return defaultService, t.region
+}
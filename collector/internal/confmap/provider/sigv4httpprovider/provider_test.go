@@ -0,0 +1,84 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sigv4httpprovider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCredentials = aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+	return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+})
+
+func TestRetrieve(t *testing.T) {
+	body := "exporters:\n  otlphttp:\n    endpoint: https://example.com\n"
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !assert.Contains(t, r.Header.Get("Authorization"), "Credential=AKID/") || r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/execute-api/aws4_request")
+		assert.NotEmpty(t, r.Header.Get("X-Amz-Date"))
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	p := &provider{client: &http.Client{Transport: NewTransport(server.Client().Transport, testCredentials, "eu-west-1")}}
+	assert.Equal(t, "sigv4+https", p.Scheme())
+
+	expected := map[string]interface{}{"exporters": map[string]interface{}{"otlphttp": map[string]interface{}{"endpoint": "https://example.com"}}}
+	for _, content := range []string{body, `{"exporters": {"otlphttp": {"endpoint": "https:\/\/example.com"}}}`} {
+		body = content
+
+		ret, err := p.Retrieve(context.Background(), "sigv4+"+server.URL+"/config", nil)
+		require.NoError(t, err)
+
+		conf, err := ret.AsConf()
+		require.NoError(t, err)
+		assert.Equal(t, expected, conf.ToStringMap())
+	}
+
+	_, err := p.Retrieve(context.Background(), "sigv4+"+server.URL+"/missing", nil)
+	assert.ErrorContains(t, err, "403")
+
+	_, err = p.Retrieve(context.Background(), server.URL, nil)
+	assert.Error(t, err)
+
+	assert.NoError(t, p.Shutdown(context.Background()))
+}
+
+func TestSigningName(t *testing.T) {
+	transport := NewTransport(http.DefaultTransport, testCredentials, "us-east-1").(*signingTransport)
+
+	for host, expected := range map[string][2]string{
+		"abcdef1234.execute-api.eu-west-1.amazonaws.com": {"execute-api", "eu-west-1"},
+		"abcdef1234.lambda-url.eu-north-1.on.aws":        {"lambda", "eu-north-1"},
+		"config.internal.example.com":                    {"execute-api", "us-east-1"},
+	} {
+		service, region := transport.signingName(host)
+		assert.Equal(t, expected, [2]string{service, region}, host)
+	}
+}
+
+func TestURL(t *testing.T) {
+	assert.Equal(t, "https://example.com/config.yaml", URL("sigv4+https://example.com/config.yaml"))
+}
@@ -0,0 +1,325 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lambdaemulator emulates the parts of the Lambda Runtime API used by
+// the extension: the Extensions API and the Telemetry API. Invocations are
+// scripted up front, so the whole extension lifecycle can run in integration
+// tests or on a developer machine by pointing AWS_LAMBDA_RUNTIME_API at the
+// address of the emulator.
+package lambdaemulator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/telemetryapi"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/utility"
+)
+
+const (
+	extensionID = "emulated-extension-id"
+	accountID   = "123456789012"
+	// defaultTimeout is the function timeout of invocations without one
+	defaultTimeout = 3 * time.Second
+	// shutdownTimeout is the time extensions are given to exit after SHUTDOWN
+	shutdownTimeout = 2 * time.Second
+)
+
+// Invocation is a scripted function invocation.
+type Invocation struct {
+	RequestID string
+	// Timeout sets the deadline of the INVOKE event. Zero means 3 seconds.
+	Timeout time.Duration
+	// Events are sent to the Telemetry API subscribers once the INVOKE event
+	// has been handed out, keeping the event types they subscribed to.
+	Events []telemetryapi.Event
+}
+
+// Subscription is a Telemetry API subscription received by the emulator.
+type Subscription struct {
+	ExtensionID string
+	Request     telemetryapi.SubscribeRequest
+}
+
+// Emulator serves the Extensions API and the Telemetry API. Each
+// /extension/event/next request hands out the next scripted invocation, then
+// a SHUTDOWN event once they are all used.
+type Emulator struct {
+	// ShutdownReason is the reason of the SHUTDOWN event, spindown if empty.
+	// It must be set before Start.
+	ShutdownReason extensionapi.ShutdownReason
+
+	server   *http.Server
+	listener net.Listener
+
+	mu          sync.Mutex
+	invocations []Invocation
+	// invoke is whether the extension registered for INVOKE events
+	invoke        bool
+	shutdown      bool
+	subscriptions []Subscription
+	requests      []string
+	errors        []string
+
+	// deliveries holds the event batches to send, in order, to the subscribers
+	deliveries chan []telemetryapi.Event
+	delivered  chan struct{}
+}
+
+// New returns an emulator handing out the given invocations.
+func New(invocations ...Invocation) *Emulator {
+	return &Emulator{
+		invocations: invocations,
+		deliveries:  make(chan []telemetryapi.Event, len(invocations)),
+		delivered:   make(chan struct{}),
+	}
+}
+
+// Start listens on address, e.g. "127.0.0.1:0", and serves the APIs in a
+// goroutine. It returns the host:port to set AWS_LAMBDA_RUNTIME_API to.
+func (e *Emulator) Start(address string) (string, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return "", fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/"+extensionapi.SchemaVersionLatest+"/extension/", e.extensionHandler)
+	mux.HandleFunc("/"+telemetryapi.APIVersion20220701+"/telemetry", e.telemetryHandler)
+
+	e.listener = listener
+	e.server = &http.Server{Handler: mux}
+
+	go func() {
+		err := e.server.Serve(listener)
+		if err != http.ErrServerClosed {
+			utility.LogError(err, "Emulator", "Unexpected stop on HTTP Server")
+		}
+	}()
+
+	go e.deliver()
+
+	return listener.Addr().String(), nil
+}
+
+// Close stops serving the APIs once the pending telemetry has been sent.
+func (e *Emulator) Close() error {
+	close(e.deliveries)
+	<-e.delivered
+
+	return e.server.Close()
+}
+
+// Requests returns the method and path of the requests received so far.
+func (e *Emulator) Requests() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]string(nil), e.requests...)
+}
+
+// Subscriptions returns the Telemetry API subscriptions received so far.
+func (e *Emulator) Subscriptions() []Subscription {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]Subscription(nil), e.subscriptions...)
+}
+
+// Errors returns the error types reported to /init/error and /exit/error.
+func (e *Emulator) Errors() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]string(nil), e.errors...)
+}
+
+func (e *Emulator) extensionHandler(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.requests = append(e.requests, r.Method+" "+r.URL.Path)
+
+	switch action := r.URL.Path[strings.LastIndex(r.URL.Path, "/extension/")+len("/extension"):]; action {
+	case "/register":
+		var request struct {
+			Events []extensionapi.EventType `json:"events"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+
+		for _, event := range request.Events {
+			e.invoke = e.invoke || event == extensionapi.Invoke
+		}
+
+		response := extensionapi.RegisterResponse{
+			FunctionName:    "emulated-function",
+			FunctionVersion: "$LATEST",
+			Handler:         "index.handler",
+		}
+
+		if strings.Contains(r.Header.Get(extensionapi.ExtensionFeatureHeader), extensionapi.FeatureAccountID) {
+			response.AccountID = accountID
+		}
+
+		w.Header().Set(extensionapi.ExtensionIdentiferHeader, extensionID)
+		writeJSON(w, response)
+
+	case "/event/next":
+		e.nextEvent(w)
+
+	case "/init/error", "/exit/error":
+		e.errors = append(e.errors, r.Header.Get(extensionapi.ExtensionErrorType))
+		writeJSON(w, extensionapi.StatusResponse{Status: "OK"})
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// nextEvent hands out the next scripted invocation and queues its telemetry.
+// Extensions not registered for INVOKE events only see the SHUTDOWN event,
+// once the telemetry of every invocation is queued.
+func (e *Emulator) nextEvent(w http.ResponseWriter) {
+	if !e.invoke {
+		for _, invocation := range e.invocations {
+			if len(invocation.Events) > 0 {
+				e.deliveries <- invocation.Events
+			}
+		}
+
+		e.invocations = nil
+	}
+
+	if len(e.invocations) == 0 {
+		if e.shutdown {
+			// The extension is expected to exit after SHUTDOWN
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		reason := e.ShutdownReason
+		if reason == "" {
+			reason = extensionapi.Spindown
+		}
+
+		e.shutdown = true
+		writeJSON(w, extensionapi.NextEventResponse{
+			EventType:      extensionapi.Shutdown,
+			DeadlineMs:     time.Now().Add(shutdownTimeout).UnixMilli(),
+			ShutdownReason: reason,
+		})
+		return
+	}
+
+	invocation := e.invocations[0]
+	e.invocations = e.invocations[1:]
+
+	timeout := invocation.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	writeJSON(w, extensionapi.NextEventResponse{
+		EventType:          extensionapi.Invoke,
+		RequestID:          invocation.RequestID,
+		DeadlineMs:         time.Now().Add(timeout).UnixMilli(),
+		InvokedFunctionArn: "arn:aws:lambda:us-east-1:" + accountID + ":function:emulated-function",
+	})
+
+	if len(invocation.Events) > 0 {
+		e.deliveries <- invocation.Events
+	}
+}
+
+func (e *Emulator) telemetryHandler(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.requests = append(e.requests, r.Method+" "+r.URL.Path)
+
+	var request telemetryapi.SubscribeRequest
+	if r.Method != http.MethodPut || json.NewDecoder(r.Body).Decode(&request) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	e.subscriptions = append(e.subscriptions, Subscription{
+		ExtensionID: r.Header.Get(extensionapi.ExtensionIdentiferHeader),
+		Request:     request,
+	})
+
+	_, _ = w.Write([]byte("OK"))
+}
+
+// deliver sends the queued event batches to the subscribers until Close.
+func (e *Emulator) deliver() {
+	defer close(e.delivered)
+
+	for events := range e.deliveries {
+		for _, subscription := range e.Subscriptions() {
+			post(subscription.Request, events)
+		}
+	}
+}
+
+// post sends the events of the subscribed types to the destination of a
+// subscription, as the Telemetry API does.
+func post(request telemetryapi.SubscribeRequest, events []telemetryapi.Event) {
+	var batch []telemetryapi.Event
+	for _, event := range events {
+		if subscribed(request.EventTypes, event.Type) {
+			batch = append(batch, event)
+		}
+	}
+
+	if len(batch) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(batch)
+	if err != nil {
+		utility.LogError(err, "Emulator", "Failed to marshal telemetry events")
+		return
+	}
+
+	response, err := http.Post(string(request.Destination.URI), "application/json", bytes.NewReader(body))
+	if err != nil {
+		utility.LogError(err, "Emulator", "Failed to send telemetry events", utility.KeyValue{K: "uri", V: request.Destination.URI})
+		return
+	}
+
+	response.Body.Close()
+}
+
+func subscribed(eventTypes []telemetryapi.EventType, eventType string) bool {
+	for _, t := range eventTypes {
+		if string(t) == eventType || (t == telemetryapi.Platform && strings.HasPrefix(eventType, "platform.")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
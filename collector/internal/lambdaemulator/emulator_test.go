@@ -0,0 +1,103 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lambdaemulator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/telemetryapi"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmulator(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	destination := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var events []telemetryapi.Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&events))
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, event := range events {
+			received = append(received, event.Type)
+		}
+	}))
+	defer destination.Close()
+
+	emulator := New(
+		NewInvocation("1", telemetryapi.StatusSuccess, "hello"),
+		NewInvocation("2", telemetryapi.StatusFailure),
+	)
+	address, err := emulator.Start("127.0.0.1:0")
+	require.NoError(t, err)
+
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", address)
+	ctx := context.Background()
+
+	extensionClient := extensionapi.NewClient(address, extensionapi.SchemaVersionLatest, extensionapi.DefaultTimeouts())
+	registration, err := extensionClient.Register(ctx, "test")
+	require.NoError(t, err)
+	assert.Equal(t, extensionID, registration.ExtensionID)
+	assert.Equal(t, accountID, registration.AccountID)
+
+	_, err = telemetryapi.NewClient(telemetryapi.SchemaVersionLatest).Subscribe(ctx, registration.ExtensionID, destination.URL, []telemetryapi.EventType{telemetryapi.Platform})
+	require.NoError(t, err)
+
+	for _, expected := range []extensionapi.NextEventResponse{
+		{EventType: extensionapi.Invoke, RequestID: "1"},
+		{EventType: extensionapi.Invoke, RequestID: "2"},
+		{EventType: extensionapi.Shutdown, ShutdownReason: extensionapi.Spindown},
+	} {
+		event, err := extensionClient.NextEvent(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, expected.EventType, event.EventType)
+		assert.Equal(t, expected.RequestID, event.RequestID)
+		assert.Equal(t, expected.ShutdownReason, event.ShutdownReason)
+	}
+
+	_, err = extensionClient.NextEvent(ctx)
+	assert.Error(t, err)
+
+	_, err = extensionClient.ExitError(ctx, "test.Error")
+	require.NoError(t, err)
+
+	require.NoError(t, emulator.Close())
+
+	// Function log lines are left out as only platform events were subscribed to
+	platformEvents := []string{telemetryapi.PLATFORM_START, telemetryapi.PLATFORM_RUNTIME_DONE, telemetryapi.PLATFORM_REPORT}
+	assert.Equal(t, append(platformEvents, platformEvents...), received)
+
+	require.Len(t, emulator.Subscriptions(), 1)
+	assert.Equal(t, extensionID, emulator.Subscriptions()[0].ExtensionID)
+	assert.Equal(t, []string{"test.Error"}, emulator.Errors())
+	assert.Equal(t, []string{
+		"POST /2020-01-01/extension/register",
+		"PUT /2022-07-01/telemetry",
+		"GET /2020-01-01/extension/event/next",
+		"GET /2020-01-01/extension/event/next",
+		"GET /2020-01-01/extension/event/next",
+		"GET /2020-01-01/extension/event/next",
+		"POST /2020-01-01/extension/exit/error",
+	}, emulator.Requests())
+}
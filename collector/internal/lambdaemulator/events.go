@@ -0,0 +1,70 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lambdaemulator
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/telemetryapi"
+)
+
+// NewInvocation returns an invocation of the given status whose function
+// writes the given log lines, with the platform.start, platform.runtimeDone
+// and platform.report events the Telemetry API sends for it.
+func NewInvocation(requestID string, status telemetryapi.Status, lines ...string) Invocation {
+	start := time.Now()
+
+	events := []telemetryapi.Event{
+		event(start, telemetryapi.PLATFORM_START, telemetryapi.PlatformStart{RequestID: requestID}),
+	}
+
+	for _, line := range lines {
+		events = append(events, event(start, string(telemetryapi.Function), line))
+	}
+
+	events = append(events,
+		event(start, telemetryapi.PLATFORM_RUNTIME_DONE, telemetryapi.PlatformRuntimeDone{
+			RequestID: requestID,
+			Status:    status,
+			Metrics:   &telemetryapi.RuntimeDoneMetrics{DurationMs: 1},
+		}),
+		event(start, telemetryapi.PLATFORM_REPORT, telemetryapi.PlatformReport{
+			RequestID: requestID,
+			Status:    status,
+			Metrics: telemetryapi.ReportMetrics{
+				DurationMs:       1,
+				BilledDurationMs: 1,
+				MemorySizeMB:     128,
+				MaxMemoryUsedMB:  64,
+			},
+		}),
+	)
+
+	return Invocation{
+		RequestID: requestID,
+		Events:    events,
+	}
+}
+
+func event(t time.Time, eventType string, record interface{}) telemetryapi.Event {
+	raw, _ := json.Marshal(record)
+
+	return telemetryapi.Event{
+		Time:   t.UTC().Format(time.RFC3339Nano),
+		Type:   eventType,
+		Record: raw,
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lazycomponent wraps exporter and processor factories so their
+// components are only created and started once the first telemetry reaches
+// them. Receivers keep listening from the collector start, while functions
+// that rarely emit telemetry don't pay for the exporters, e.g. their
+// connections and credentials, at cold start.
+package lazycomponent // import "github.com/open-telemetry/opentelemetry-lambda/collector/internal/lazycomponent"
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// Factories returns a copy of factories whose exporters and processors are
+// created lazily. The configurations are still validated when the collector
+// starts, but errors creating or starting a component are returned to its
+// first caller instead, e.g. a receiver.
+func Factories(factories component.Factories) component.Factories {
+	exporters := make(map[component.Type]component.ExporterFactory, len(factories.Exporters))
+	for t, f := range factories.Exporters {
+		exporters[t] = exporterFactory(f)
+	}
+
+	processors := make(map[component.Type]component.ProcessorFactory, len(factories.Processors))
+	for t, f := range factories.Processors {
+		processors[t] = processorFactory(f)
+	}
+
+	factories.Exporters = exporters
+	factories.Processors = processors
+
+	return factories
+}
+
+func exporterFactory(f component.ExporterFactory) component.ExporterFactory {
+	var options []component.ExporterFactoryOption
+
+	if sl := f.TracesExporterStability(); sl != component.StabilityLevelUndefined {
+		options = append(options, component.WithTracesExporter(func(_ context.Context, set component.ExporterCreateSettings, cfg component.ExporterConfig) (component.TracesExporter, error) {
+			return &traces{newLazy(cfg.ID(), func(ctx context.Context) (component.Component, error) {
+				return f.CreateTracesExporter(ctx, set, cfg)
+			})}, nil
+		}, sl))
+	}
+
+	if sl := f.MetricsExporterStability(); sl != component.StabilityLevelUndefined {
+		options = append(options, component.WithMetricsExporter(func(_ context.Context, set component.ExporterCreateSettings, cfg component.ExporterConfig) (component.MetricsExporter, error) {
+			return &metrics{newLazy(cfg.ID(), func(ctx context.Context) (component.Component, error) {
+				return f.CreateMetricsExporter(ctx, set, cfg)
+			})}, nil
+		}, sl))
+	}
+
+	if sl := f.LogsExporterStability(); sl != component.StabilityLevelUndefined {
+		options = append(options, component.WithLogsExporter(func(_ context.Context, set component.ExporterCreateSettings, cfg component.ExporterConfig) (component.LogsExporter, error) {
+			return &logs{newLazy(cfg.ID(), func(ctx context.Context) (component.Component, error) {
+				return f.CreateLogsExporter(ctx, set, cfg)
+			})}, nil
+		}, sl))
+	}
+
+	return component.NewExporterFactory(f.Type(), f.CreateDefaultConfig, options...)
+}
+
+func processorFactory(f component.ProcessorFactory) component.ProcessorFactory {
+	var options []component.ProcessorFactoryOption
+
+	if sl := f.TracesProcessorStability(); sl != component.StabilityLevelUndefined {
+		options = append(options, component.WithTracesProcessor(func(_ context.Context, set component.ProcessorCreateSettings, cfg component.ProcessorConfig, next consumer.Traces) (component.TracesProcessor, error) {
+			return &traces{newLazy(cfg.ID(), func(ctx context.Context) (component.Component, error) {
+				return f.CreateTracesProcessor(ctx, set, cfg, next)
+			})}, nil
+		}, sl))
+	}
+
+	if sl := f.MetricsProcessorStability(); sl != component.StabilityLevelUndefined {
+		options = append(options, component.WithMetricsProcessor(func(_ context.Context, set component.ProcessorCreateSettings, cfg component.ProcessorConfig, next consumer.Metrics) (component.MetricsProcessor, error) {
+			return &metrics{newLazy(cfg.ID(), func(ctx context.Context) (component.Component, error) {
+				return f.CreateMetricsProcessor(ctx, set, cfg, next)
+			})}, nil
+		}, sl))
+	}
+
+	if sl := f.LogsProcessorStability(); sl != component.StabilityLevelUndefined {
+		options = append(options, component.WithLogsProcessor(func(_ context.Context, set component.ProcessorCreateSettings, cfg component.ProcessorConfig, next consumer.Logs) (component.LogsProcessor, error) {
+			return &logs{newLazy(cfg.ID(), func(ctx context.Context) (component.Component, error) {
+				return f.CreateLogsProcessor(ctx, set, cfg, next)
+			})}, nil
+		}, sl))
+	}
+
+	return component.NewProcessorFactory(f.Type(), f.CreateDefaultConfig, options...)
+}
+
+// lazy creates and starts the wrapped component on first use.
+type lazy struct {
+	id     component.ID
+	create func(ctx context.Context) (component.Component, error)
+
+	mu   sync.Mutex
+	host component.Host
+	// component is nil until the first successful use
+	component component.Component
+	stopped   bool
+}
+
+func newLazy(id component.ID, create func(ctx context.Context) (component.Component, error)) *lazy {
+	return &lazy{id: id, create: create}
+}
+
+// Start only keeps the host to start the wrapped component with later.
+func (l *lazy) Start(_ context.Context, host component.Host) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.host = host
+	return nil
+}
+
+// Shutdown stops the wrapped component, if it was ever started.
+func (l *lazy) Shutdown(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stopped = true
+	if l.component == nil {
+		return nil
+	}
+
+	return l.component.Shutdown(ctx)
+}
+
+// Capabilities can't be known before the wrapped component is created, so
+// the data is assumed to be mutated, which at worst costs a copy.
+func (l *lazy) Capabilities() consumer.Capabilities {
+	return consumer.Capabilities{MutatesData: true}
+}
+
+// get returns the wrapped component, creating and starting it on the first
+// call. A failed attempt is retried on the next call.
+func (l *lazy) get() (component.Component, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.host == nil || l.stopped {
+		return nil, fmt.Errorf("%s is not running", l.id)
+	}
+
+	if l.component != nil {
+		return l.component, nil
+	}
+
+	// The components outlive the call that happens to create them
+	c, err := l.create(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", l.id, err)
+	}
+
+	if err = c.Start(context.Background(), l.host); err != nil {
+		_ = c.Shutdown(context.Background())
+		return nil, fmt.Errorf("failed to start %s: %w", l.id, err)
+	}
+
+	l.component = c
+	return c, nil
+}
+
+type traces struct{ *lazy }
+
+func (t *traces) ConsumeTraces(ctx context.Context, td ptrace.Traces) error {
+	c, err := t.get()
+	if err != nil {
+		return err
+	}
+
+	return c.(consumer.Traces).ConsumeTraces(ctx, td)
+}
+
+type metrics struct{ *lazy }
+
+func (m *metrics) ConsumeMetrics(ctx context.Context, md pmetric.Metrics) error {
+	c, err := m.get()
+	if err != nil {
+		return err
+	}
+
+	return c.(consumer.Metrics).ConsumeMetrics(ctx, md)
+}
+
+type logs struct{ *lazy }
+
+func (l *logs) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
+	c, err := l.get()
+	if err != nil {
+		return err
+	}
+
+	return c.(consumer.Logs).ConsumeLogs(ctx, ld)
+}
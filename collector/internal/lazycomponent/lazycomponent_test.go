@@ -0,0 +1,158 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lazycomponent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// countingExporter counts the calls made to it, failing to start with startErr.
+type countingExporter struct {
+	consumertest.TracesSink
+	created, started, stopped int
+	startErr                  error
+}
+
+func (e *countingExporter) Start(context.Context, component.Host) error {
+	e.started++
+	return e.startErr
+}
+
+func (e *countingExporter) Shutdown(context.Context) error {
+	e.stopped++
+	return nil
+}
+
+func (e *countingExporter) factory() component.ExporterFactory {
+	return component.NewExporterFactory(
+		"counting",
+		func() component.ExporterConfig {
+			cfg := config.NewExporterSettings(component.NewID("counting"))
+			return &cfg
+		},
+		component.WithTracesExporter(func(context.Context, component.ExporterCreateSettings, component.ExporterConfig) (component.TracesExporter, error) {
+			e.created++
+			return e, nil
+		}, component.StabilityLevelAlpha))
+}
+
+func TestFactories(t *testing.T) {
+	exporter := &countingExporter{}
+	factories := Factories(component.Factories{
+		Exporters: map[component.Type]component.ExporterFactory{"counting": exporter.factory()},
+	})
+
+	factory := factories.Exporters["counting"]
+	assert.Equal(t, component.StabilityLevelAlpha, factory.TracesExporterStability())
+	assert.Equal(t, component.StabilityLevelUndefined, factory.MetricsExporterStability())
+
+	ctx := context.Background()
+	traces, err := factory.CreateTracesExporter(ctx, componenttest.NewNopExporterCreateSettings(), factory.CreateDefaultConfig())
+	require.NoError(t, err)
+	require.NoError(t, traces.Start(ctx, componenttest.NewNopHost()))
+
+	// Nothing is created before the first traces
+	assert.Equal(t, 0, exporter.created)
+
+	require.NoError(t, traces.ConsumeTraces(ctx, ptrace.NewTraces()))
+	require.NoError(t, traces.ConsumeTraces(ctx, ptrace.NewTraces()))
+	assert.Equal(t, 1, exporter.created)
+	assert.Equal(t, 1, exporter.started)
+	assert.Len(t, exporter.AllTraces(), 2)
+
+	require.NoError(t, traces.Shutdown(ctx))
+	assert.Equal(t, 1, exporter.stopped)
+	assert.Error(t, traces.ConsumeTraces(ctx, ptrace.NewTraces()))
+}
+
+func TestFactoriesProcessor(t *testing.T) {
+	created := 0
+	factories := Factories(component.Factories{
+		Processors: map[component.Type]component.ProcessorFactory{
+			"counting": component.NewProcessorFactory(
+				"counting",
+				func() component.ProcessorConfig {
+					cfg := config.NewProcessorSettings(component.NewID("counting"))
+					return &cfg
+				},
+				component.WithTracesProcessor(func(_ context.Context, _ component.ProcessorCreateSettings, _ component.ProcessorConfig, next consumer.Traces) (component.TracesProcessor, error) {
+					created++
+					return &passThrough{Traces: next}, nil
+				}, component.StabilityLevelAlpha)),
+		},
+	})
+
+	factory := factories.Processors["counting"]
+	ctx := context.Background()
+	next := &consumertest.TracesSink{}
+	traces, err := factory.CreateTracesProcessor(ctx, componenttest.NewNopProcessorCreateSettings(), factory.CreateDefaultConfig(), next)
+	require.NoError(t, err)
+	require.NoError(t, traces.Start(ctx, componenttest.NewNopHost()))
+	assert.Equal(t, 0, created)
+
+	require.NoError(t, traces.ConsumeTraces(ctx, ptrace.NewTraces()))
+	assert.Equal(t, 1, created)
+	assert.Len(t, next.AllTraces(), 1)
+
+	// Never used, so never shut down
+	require.NoError(t, traces.Shutdown(ctx))
+}
+
+func TestFactoriesStartFailure(t *testing.T) {
+	exporter := &countingExporter{startErr: errors.New("unreachable")}
+	factory := Factories(component.Factories{
+		Exporters: map[component.Type]component.ExporterFactory{"counting": exporter.factory()},
+	}).Exporters["counting"]
+
+	ctx := context.Background()
+	traces, err := factory.CreateTracesExporter(ctx, componenttest.NewNopExporterCreateSettings(), factory.CreateDefaultConfig())
+	require.NoError(t, err)
+
+	// Used before the collector started it
+	assert.Error(t, traces.ConsumeTraces(ctx, ptrace.NewTraces()))
+	assert.Equal(t, 0, exporter.created)
+
+	require.NoError(t, traces.Start(ctx, componenttest.NewNopHost()))
+
+	// A failed start is reported to the caller, and retried with the next traces
+	err = traces.ConsumeTraces(ctx, ptrace.NewTraces())
+	assert.ErrorContains(t, err, "failed to start counting: unreachable")
+	assert.Equal(t, 1, exporter.stopped)
+
+	exporter.startErr = nil
+	require.NoError(t, traces.ConsumeTraces(ctx, ptrace.NewTraces()))
+	assert.Equal(t, 2, exporter.created)
+	assert.Len(t, exporter.AllTraces(), 1)
+
+	require.NoError(t, traces.Shutdown(ctx))
+	assert.Equal(t, 2, exporter.stopped)
+}
+
+type passThrough struct {
+	component.StartFunc
+	component.ShutdownFunc
+	consumer.Traces
+}
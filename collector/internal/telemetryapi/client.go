@@ -18,68 +18,194 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/utility"
 )
 
 const (
-	SchemaVersion20220701          = "2022-07-01"
-	SchemaVersionLatest            = SchemaVersion20220701
+	// APIVersion20220701 is the version of the Telemetry API endpoint
+	APIVersion20220701 = "2022-07-01"
+
+	// SchemaVersion20220701 is the first Telemetry API schema
+	SchemaVersion20220701 SchemaVersion = "2022-07-01"
+	// SchemaVersion20221213 adds the platform.restore* events of SnapStart functions
+	SchemaVersion20221213 SchemaVersion = "2022-12-13"
+	SchemaVersionLatest                 = SchemaVersion20221213
+
 	lambdaAgentIdentifierHeaderKey = "Lambda-Extension-Identifier"
+
+	// LogsAPIVersion20200815 is the version of the legacy Logs API endpoint
+	LogsAPIVersion20200815 = "2020-08-15"
+	// LogsSchemaVersion20210318 is the first Logs API schema including platform.runtimeDone
+	LogsSchemaVersion20210318 = "2021-03-18"
 )
 
+// supportedSchemaVersions are the Telemetry API schemas the records of types.go can decode
+var supportedSchemaVersions = []SchemaVersion{SchemaVersion20220701, SchemaVersion20221213}
+
 // Client is used for subscribing to the Telemetry API
 type Client struct {
-	baseURL    string
-	httpClient *http.Client
+	baseURL       string
+	logsBaseURL   string
+	schemaVersion SchemaVersion
+	httpClient    *http.Client
+	// subscription is the last successful subscription, used by UpdateSubscription
+	subscription *subscription
 }
 
-// NewClient returns a Lambda Telemetry API client.
+type subscription struct {
+	extensionID string
+	listenerURI string
+	eventTypes  []EventType
+}
+
+// NewClient returns a Lambda Telemetry API client subscribing with the given
+// schema version, which decides the events and record fields that are sent.
 //  Reference: https://github.com/awsdocs/aws-lambda-developer-guide/blob/main/doc_source/telemetry-api-reference.md
-func NewClient() *Client {
-	baseURL := fmt.Sprintf("http://%s/%s/telemetry", os.Getenv("AWS_LAMBDA_RUNTIME_API"), SchemaVersionLatest)
+func NewClient(schemaVersion SchemaVersion) *Client {
+	baseURL := fmt.Sprintf("http://%s/%s/telemetry", os.Getenv("AWS_LAMBDA_RUNTIME_API"), APIVersion20220701)
+	logsBaseURL := fmt.Sprintf("http://%s/%s/logs", os.Getenv("AWS_LAMBDA_RUNTIME_API"), LogsAPIVersion20200815)
 
 	return &Client{
-		baseURL:    baseURL,
-		httpClient: &http.Client{},
+		baseURL:       baseURL,
+		logsBaseURL:   logsBaseURL,
+		schemaVersion: schemaVersion,
+		httpClient:    &http.Client{},
 	}
 }
 
 // Subscribe sends subscription request to the Telemetry API Client.
+// If the Telemetry API is not available, it falls back to the legacy Logs API.
 //  PUT http://${AWS_LAMBDA_RUNTIME_API}/2022-07-01/telemetry
 //  Reference:
 //   https://github.com/awsdocs/aws-lambda-developer-guide/blob/main/doc_source/telemetry-api-reference.md#subscribe
 //   https://github.com/awsdocs/aws-lambda-developer-guide/blob/main/doc_source/telemetry-api.md#sending-a-subscription-request-to-the-telemetry-api
-func (c *Client) Subscribe(ctx context.Context, extensionID string, listenerURI string) (string, error) {
-	eventTypes := []EventType{Platform}
+func (c *Client) Subscribe(ctx context.Context, extensionID string, listenerURI string, eventTypes []EventType) (string, error) {
+	defer RecordAPICallLatency("subscribe", time.Now())
 
-	bufferingConfig := BufferingCfg{
-		TimeoutMS: 100,
-		MaxItems:  1000,
-		MaxBytes:  256 * 1024,
+	request := &SubscribeRequest{
+		SchemaVersion: c.schemaVersion,
+		EventTypes:    eventTypes,
+		BufferingCfg:  defaultBufferingCfg(),
+		Destination: Destination{
+			Protocol:   HTTProto,
+			HTTPMethod: HTTPPost,
+			Encoding:   JSON,
+			URI:        URI(listenerURI),
+		},
 	}
 
-	destination := Destination{
-		Protocol:   HTTProto,
-		HTTPMethod: HTTPPost,
-		Encoding:   JSON,
-		URI:        URI(listenerURI),
+	statusCode, body, err := c.subscribe(ctx, c.baseURL, extensionID, request)
+	if err != nil {
+		return "", err
 	}
 
-	request := &SubscribeRequest{
-		SchemaVersion: SchemaVersionLatest,
+	// Older runtimes and local emulators only implement the Logs API
+	if statusCode == http.StatusAccepted || statusCode == http.StatusNotFound {
+		utility.LogError(nil, "Subscribe", "Telemetry API is not supported, falling back to the Logs API", utility.KeyValue{K: "status_code", V: statusCode})
+
+		body, err = c.subscribeLogsAPI(ctx, extensionID, listenerURI, eventTypes)
+		if err != nil {
+			return "", err
+		}
+
+	} else if statusCode != http.StatusOK {
+		utility.LogError(nil, "Subscribe", "Subscription failed.", utility.KeyValue{K: "baseURL", V: c.baseURL}, utility.KeyValue{K: "status_code", V: statusCode})
+		return "", fmt.Errorf("request to %s failed: %d %s", c.baseURL, statusCode, body)
+	}
+
+	c.subscription = &subscription{
+		extensionID: extensionID,
+		listenerURI: listenerURI,
+		eventTypes:  eventTypes,
+	}
+
+	return body, nil
+}
+
+// UpdateSubscription re-subscribes the listener of the last successful
+// Subscribe call when eventTypes differs from the subscribed event types.
+// It is meant to be called at invocation boundaries, e.g. after a
+// configuration reload, and reports whether a new subscription was sent.
+func (c *Client) UpdateSubscription(ctx context.Context, eventTypes []EventType) (bool, error) {
+	if c.subscription == nil {
+		return false, errors.New("cannot update subscription before subscribing")
+	}
+
+	if sameEventTypes(c.subscription.eventTypes, eventTypes) {
+		return false, nil
+	}
+
+	_, err := c.Subscribe(ctx, c.subscription.extensionID, c.subscription.listenerURI, eventTypes)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// sameEventTypes reports whether a and b contain the same event types, regardless of order.
+func sameEventTypes(a, b []EventType) bool {
+	set := make(map[EventType]bool, len(a))
+	for _, eventType := range a {
+		set[eventType] = true
+	}
+
+	for _, eventType := range b {
+		if !set[eventType] {
+			return false
+		}
+
+		delete(set, eventType)
+	}
+
+	return len(set) == 0
+}
+
+// subscribeLogsAPI sends subscription request to the legacy Logs API. Events
+// are delivered to the listener in the same shape as Telemetry API events.
+//  PUT http://${AWS_LAMBDA_RUNTIME_API}/2020-08-15/logs
+//  Reference: https://docs.aws.amazon.com/lambda/latest/dg/runtimes-logs-api.html
+func (c *Client) subscribeLogsAPI(ctx context.Context, extensionID string, listenerURI string, eventTypes []EventType) (string, error) {
+	request := &LogsSubscribeRequest{
+		SchemaVersion: LogsSchemaVersion20210318,
 		EventTypes:    eventTypes,
-		BufferingCfg:  bufferingConfig,
-		Destination:   destination,
+		BufferingCfg:  defaultBufferingCfg(),
+		Destination: LogsDestination{
+			Protocol: HTTProto,
+			URI:      URI(listenerURI),
+		},
 	}
 
+	statusCode, body, err := c.subscribe(ctx, c.logsBaseURL, extensionID, request)
+	if err != nil {
+		return "", err
+	}
+
+	if statusCode == http.StatusAccepted {
+		utility.LogError(nil, "Subscribe", "Subscription failed. Logs API is not supported! Is this extension running in a local sandbox?", utility.KeyValue{K: "status_code", V: statusCode})
+
+	} else if statusCode != http.StatusOK {
+		utility.LogError(nil, "Subscribe", "Subscription failed.", utility.KeyValue{K: "baseURL", V: c.logsBaseURL}, utility.KeyValue{K: "status_code", V: statusCode})
+		return "", fmt.Errorf("request to %s failed: %d %s", c.logsBaseURL, statusCode, body)
+	}
+
+	return body, nil
+}
+
+// subscribe sends the subscription request to url and returns the status
+// code and body of the response.
+func (c *Client) subscribe(ctx context.Context, url string, extensionID string, request interface{}) (int, string, error) {
 	data, err := json.Marshal(request)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal SubscribeRequest: %w", err)
+		return 0, "", fmt.Errorf("failed to marshal %T: %w", request, err)
 	}
 
 	// Before sending a subscription request, you must have an
@@ -88,30 +214,66 @@ func (c *Client) Subscribe(ctx context.Context, extensionID string, listenerURI
 	headers[lambdaAgentIdentifierHeaderKey] = extensionID
 
 	// Send a Subscribe API request
-	response, err := httpPutWithHeaders(ctx, c.httpClient, c.baseURL, data, headers)
+	response, err := httpPutWithHeaders(ctx, c.httpClient, url, data, headers)
 	if err != nil {
 		utility.LogError(err, "Subscribe", "Subscription failed")
-		return "", err
+		return 0, "", err
 	}
 	defer response.Body.Close()
 
-	if response.StatusCode == http.StatusAccepted {
-		utility.LogError(err, "Subscribe", "Subscription failed. Logs API is not supported! Is this extension running in a local sandbox?", utility.KeyValue{K: "status_code", V: response.StatusCode})
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return 0, "", fmt.Errorf("request to %s failed: %d[%s]: %w", url, response.StatusCode, response.Status, err)
+	}
 
-	} else if response.StatusCode != http.StatusOK {
-		utility.LogError(err, "Subscribe", "Subscription failed.", utility.KeyValue{K: "baseURL", V: c.baseURL}, utility.KeyValue{K: "status_code", V: response.StatusCode})
+	return response.StatusCode, string(body), nil
+}
 
-		body, err := io.ReadAll(response.Body)
-		if err != nil {
-			return "", fmt.Errorf("request to %s failed: %d[%s]: %w", c.baseURL, response.StatusCode, response.Status, err)
+// defaultBufferingCfg returns the buffering used for both the Telemetry API and the Logs API.
+func defaultBufferingCfg() BufferingCfg {
+	return BufferingCfg{
+		TimeoutMS: 100,
+		MaxItems:  1000,
+		MaxBytes:  256 * 1024,
+	}
+}
+
+// EventTypesFromEnv returns the event types to subscribe to, read from the
+// comma separated OTEL_LAMBDA_TELEMETRY_TYPES environment variable (default: platform).
+// Platform events are always included since the lifecycle relies on
+// platform.runtimeDone to detect the end of an invocation.
+func EventTypesFromEnv() []EventType {
+	eventTypes := []EventType{Platform}
+
+	for _, val := range strings.Split(utility.GetEnvString("OTEL_LAMBDA_TELEMETRY_TYPES", string(Platform)), ",") {
+		switch eventType := EventType(strings.TrimSpace(val)); eventType {
+		case Platform, "":
+			// Always subscribed
+		case Function, Extension:
+			eventTypes = append(eventTypes, eventType)
+		default:
+			utility.LogError(nil, "EventTypesFromEnv", "Ignoring unknown Telemetry API event type", utility.KeyValue{K: "type", V: eventType})
 		}
+	}
 
-		return "", fmt.Errorf("request to %s failed: %d[%s] %s", c.baseURL, response.StatusCode, response.Status, string(body))
+	return eventTypes
+}
+
+// SchemaVersionFromEnv returns the Telemetry API schema version to subscribe
+// with, read from the OTEL_LAMBDA_TELEMETRY_SCHEMA_VERSION environment
+// variable (default: 2022-07-01). Unsupported versions fall back to the default.
+func SchemaVersionFromEnv() SchemaVersion {
+	schemaVersion := SchemaVersion(utility.GetEnvString("OTEL_LAMBDA_TELEMETRY_SCHEMA_VERSION", string(SchemaVersion20220701)))
+
+	for _, supported := range supportedSchemaVersions {
+		if schemaVersion == supported {
+			return schemaVersion
+		}
 	}
 
-	body, _ := io.ReadAll(response.Body)
+	utility.LogError(nil, "SchemaVersionFromEnv", "Unsupported Telemetry API schema version, using the default", utility.KeyValue{K: "schema_version", V: schemaVersion})
 
-	return string(body), nil
+	return SchemaVersion20220701
 }
 
 // httpPutWithHeaders sends request to Telemetry API Client
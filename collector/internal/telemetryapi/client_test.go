@@ -0,0 +1,162 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribeFallsBackToLogsAPI(t *testing.T) {
+	for _, tc := range []struct {
+		name              string
+		telemetryStatus   int
+		expectedLogsCalls int
+		expectedErr       bool
+	}{
+		{name: "telemetry api available", telemetryStatus: http.StatusOK, expectedLogsCalls: 0},
+		{name: "telemetry api not found", telemetryStatus: http.StatusNotFound, expectedLogsCalls: 1},
+		{name: "telemetry api not supported", telemetryStatus: http.StatusAccepted, expectedLogsCalls: 1},
+		{name: "telemetry api failure", telemetryStatus: http.StatusInternalServerError, expectedErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			logsCalls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "ext-id", r.Header.Get(lambdaAgentIdentifierHeaderKey))
+
+				switch r.URL.Path {
+				case "/telemetry":
+					w.WriteHeader(tc.telemetryStatus)
+
+				case "/logs":
+					logsCalls++
+
+					var request map[string]any
+					assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+					assert.Equal(t, LogsSchemaVersion20210318, request["schemaVersion"])
+					assert.Equal(t, []any{"platform"}, request["types"])
+					w.WriteHeader(http.StatusOK)
+				}
+			}))
+			defer server.Close()
+
+			client := &Client{
+				baseURL:     server.URL + "/telemetry",
+				logsBaseURL: server.URL + "/logs",
+				httpClient:  server.Client(),
+			}
+
+			_, err := client.Subscribe(context.Background(), "ext-id", "http://sandbox:4323/", []EventType{Platform})
+			if tc.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.expectedLogsCalls, logsCalls)
+		})
+	}
+}
+
+func TestUpdateSubscription(t *testing.T) {
+	var subscribed [][]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		subscribed = append(subscribed, request["types"].([]any))
+	}))
+	defer server.Close()
+
+	client := &Client{baseURL: server.URL, httpClient: server.Client()}
+
+	_, err := client.UpdateSubscription(context.Background(), []EventType{Platform})
+	assert.Error(t, err)
+
+	_, err = client.Subscribe(context.Background(), "ext-id", "http://sandbox:4323/", []EventType{Platform, Function})
+	assert.NoError(t, err)
+
+	updated, err := client.UpdateSubscription(context.Background(), []EventType{Function, Platform})
+	assert.NoError(t, err)
+	assert.False(t, updated)
+
+	updated, err = client.UpdateSubscription(context.Background(), []EventType{Platform})
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	assert.Equal(t, [][]any{{"platform", "function"}, {"platform"}}, subscribed)
+}
+
+func TestSubscribeSchemaVersion(t *testing.T) {
+	var schemaVersion any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2022-07-01/telemetry", r.URL.Path)
+
+		var request map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		schemaVersion = request["schemaVersion"]
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", strings.TrimPrefix(server.URL, "http://"))
+
+	_, err := NewClient(SchemaVersion20221213).Subscribe(context.Background(), "ext-id", "http://sandbox:4323/", []EventType{Platform})
+	assert.NoError(t, err)
+	assert.Equal(t, "2022-12-13", schemaVersion)
+}
+
+func TestSchemaVersionFromEnv(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		expected SchemaVersion
+	}{
+		{value: "", expected: SchemaVersion20220701},
+		{value: "2022-07-01", expected: SchemaVersion20220701},
+		{value: "2022-12-13", expected: SchemaVersion20221213},
+		{value: "2099-01-01", expected: SchemaVersion20220701},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Setenv("OTEL_LAMBDA_TELEMETRY_SCHEMA_VERSION", tc.value)
+
+			assert.Equal(t, tc.expected, SchemaVersionFromEnv())
+		})
+	}
+}
+
+func TestEventTypesFromEnv(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		value    string
+		expected []EventType
+	}{
+		{name: "empty", value: "", expected: []EventType{Platform}},
+		{name: "platform", value: "platform", expected: []EventType{Platform}},
+		{name: "all", value: "platform,function,extension", expected: []EventType{Platform, Function, Extension}},
+		{name: "platform implied", value: " function ", expected: []EventType{Platform, Function}},
+		{name: "empty entry", value: "function,,extension,", expected: []EventType{Platform, Function, Extension}},
+		{name: "unknown type", value: "function,metrics", expected: []EventType{Platform, Function}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("OTEL_LAMBDA_TELEMETRY_TYPES", tc.value)
+
+			assert.Equal(t, tc.expected, EventTypesFromEnv())
+		})
+	}
+}
@@ -0,0 +1,437 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	mathrand "math/rand"
+	"strings"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/utility"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+const (
+	scopeName = "github.com/open-telemetry/opentelemetry-lambda/collector/internal/telemetryapi"
+
+	// attributeFaaSInvocationID holds the AWS request ID of the invocation.
+	// Older semantic conventions call it faas.execution.
+	attributeFaaSInvocationID = "faas.invocation_id"
+	attributeEventType        = "type"
+	attributeStatus           = "aws.lambda.status"
+	attributeErrorType        = "aws.lambda.error_type"
+
+	amznTraceIDType = "X-Amzn-Trace-Id"
+)
+
+// invocation identifies the invocation telemetry belongs to.
+type invocation struct {
+	requestID string
+	traceID   pcommon.TraceID
+	spanID    pcommon.SpanID
+}
+
+func newInvocation(requestID string, tracing *TraceContext) invocation {
+	inv := invocation{requestID: requestID}
+	if tracing != nil {
+		inv.traceID, inv.spanID = parseTraceContext(tracing)
+	}
+
+	return inv
+}
+
+// stamp sets faas.invocation_id on attrs, if known.
+func (inv invocation) stamp(attrs pcommon.Map) {
+	if inv.requestID != "" {
+		attrs.PutStr(attributeFaaSInvocationID, inv.requestID)
+	}
+}
+
+// ConverterConfig holds the settings of a Converter.
+type ConverterConfig struct {
+	// ParseJSONLogs maps the fields of JSON function log lines to the severity,
+	// body, timestamp and attributes of the log records.
+	ParseJSONLogs bool
+	// EMFMetrics converts CloudWatch Embedded Metric Format function log lines into metrics.
+	EMFMetrics bool
+	// InternalMetrics adds the internal metrics of the listener to the
+	// metrics of every batch holding a platform.report event.
+	InternalMetrics bool
+	// FunctionLogSampleRate is the fraction of function log lines converted,
+	// from 0 to 1. Lines with a severity of ERROR or above are always kept.
+	FunctionLogSampleRate float64
+}
+
+// Converter converts Telemetry API events into OpenTelemetry logs, traces
+// and metrics. Telemetry derived from an event carrying a request ID is
+// stamped with it as faas.invocation_id, along with the trace context of the
+// invocation where the event or the INVOKE event has one. Function and extension log lines carry
+// no request ID themselves, so the Converter remembers the invocation of the
+// last platform.start event and attributes them to it.
+type Converter struct {
+	config  ConverterConfig
+	current invocation
+	random  *mathrand.Rand
+	// function is the invoked function, described by the resource attributes
+	function *FunctionARN
+	// traceContexts are the trace contexts of the INVOKE events by request ID
+	traceContexts map[string]TraceContext
+}
+
+// NewConverter returns a Converter.
+func NewConverter(config ConverterConfig) *Converter {
+	return &Converter{
+		config: config,
+		random: mathrand.New(mathrand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// SetFunctionARN sets the invoked function, whose cloud and faas attributes
+// are added to the resource of the converted telemetry. A nil function adds none.
+func (c *Converter) SetFunctionARN(function *FunctionARN) {
+	c.function = function
+}
+
+// SetTraceContexts sets the trace contexts of the invocations by request ID,
+// as received in the INVOKE events. They parent the telemetry of the platform
+// events without a trace context of their own, so it joins the function trace.
+func (c *Converter) SetTraceContexts(traceContexts map[string]TraceContext) {
+	c.traceContexts = traceContexts
+}
+
+// invocation returns the invocation of requestID, with the trace context of
+// its event or else the one of its INVOKE event.
+func (c *Converter) invocation(requestID string, tracing *TraceContext) invocation {
+	if tracing == nil {
+		if traceContext, ok := c.traceContexts[requestID]; ok {
+			tracing = &traceContext
+		}
+	}
+
+	return newInvocation(requestID, tracing)
+}
+
+func (c *Converter) stampResource(attrs pcommon.Map) {
+	if c.function != nil {
+		c.function.stamp(attrs)
+	}
+}
+
+// ToLogs converts every event into a log record, sampling function log lines.
+func (c *Converter) ToLogs(events []Event) plog.Logs {
+	logs := plog.NewLogs()
+	resourceLogs := logs.ResourceLogs().AppendEmpty()
+	c.stampResource(resourceLogs.Resource().Attributes())
+	records := resourceLogs.ScopeLogs().AppendEmpty()
+	records.Scope().SetName(scopeName)
+
+	observed := pcommon.NewTimestampFromTime(time.Now())
+	for _, event := range events {
+		lr := plog.NewLogRecord()
+		lr.SetObservedTimestamp(observed)
+		lr.SetTimestamp(eventTimestamp(event))
+		lr.Attributes().PutStr(attributeEventType, event.Type)
+
+		inv := c.current
+		record, err := event.ParseRecord()
+		if err != nil {
+			utility.LogError(err, "TelemetryAPIConvert", "Can't parse event", utility.KeyValue{K: "type", V: event.Type})
+		}
+
+		switch r := record.(type) {
+		case *LogRecord:
+			if event.Type != string(Function) || !c.config.ParseJSONLogs || !parseJSONLog(lr, r.Message, &inv) {
+				lr.Body().SetStr(r.Message)
+			}
+
+		case *PlatformStart:
+			c.current = c.invocation(r.RequestID, r.Tracing)
+			inv = c.current
+			lr.Body().SetStr(string(event.Record))
+
+		case *PlatformRuntimeDone:
+			inv = c.invocation(r.RequestID, r.Tracing)
+			lr.Body().SetStr(string(event.Record))
+
+		case *PlatformReport:
+			inv = c.invocation(r.RequestID, r.Tracing)
+			lr.Body().SetStr(string(event.Record))
+
+		default:
+			inv = invocation{}
+			lr.Body().SetStr(string(event.Record))
+		}
+
+		inv.stamp(lr.Attributes())
+		lr.SetTraceID(inv.traceID)
+		lr.SetSpanID(inv.spanID)
+
+		if event.Type == string(Function) && !c.sampled(lr) {
+			continue
+		}
+
+		lr.MoveTo(records.LogRecords().AppendEmpty())
+	}
+
+	return logs
+}
+
+// sampled reports whether a function log record is kept.
+func (c *Converter) sampled(lr plog.LogRecord) bool {
+	if lr.SeverityNumber() >= plog.SeverityNumberError || c.config.FunctionLogSampleRate >= 1 {
+		return true
+	}
+
+	return c.random.Float64() < c.config.FunctionLogSampleRate
+}
+
+// ToTraces converts the spans reported in platform.initRuntimeDone,
+// platform.restoreRuntimeDone and platform.runtimeDone events. Invocation spans are children of the span in
+// the invocation trace context, if any. Spans carry the status and error type
+// of their event, and have an error status unless it is success.
+func (c *Converter) ToTraces(events []Event) ptrace.Traces {
+	traces := ptrace.NewTraces()
+	resourceSpans := traces.ResourceSpans().AppendEmpty()
+	c.stampResource(resourceSpans.Resource().Attributes())
+	spans := resourceSpans.ScopeSpans().AppendEmpty()
+	spans.Scope().SetName(scopeName)
+
+	for _, event := range events {
+		if event.Type != PLATFORM_INIT_RUNTIME_DONE && event.Type != PLATFORM_RESTORE_RUNTIME_DONE && event.Type != PLATFORM_RUNTIME_DONE {
+			continue
+		}
+
+		record, err := event.ParseRecord()
+		if err != nil {
+			utility.LogError(err, "TelemetryAPIConvert", "Can't parse event", utility.KeyValue{K: "type", V: event.Type})
+			continue
+		}
+
+		var inv invocation
+		var platformSpans []Span
+		var status Status
+		var errorType string
+		switch r := record.(type) {
+		case *PlatformInitRuntimeDone:
+			inv.traceID = newTraceID()
+			platformSpans, status, errorType = r.Spans, r.Status, r.ErrorType
+
+		case *PlatformRestoreRuntimeDone:
+			inv.traceID = newTraceID()
+			platformSpans, status, errorType = r.Spans, r.Status, r.ErrorType
+
+		case *PlatformRuntimeDone:
+			inv = c.invocation(r.RequestID, r.Tracing)
+			if inv.traceID.IsEmpty() {
+				inv.traceID = newTraceID()
+			}
+			platformSpans, status, errorType = r.Spans, r.Status, r.ErrorType
+		}
+
+		for _, platformSpan := range platformSpans {
+			start, err := time.Parse(time.RFC3339Nano, platformSpan.Start)
+			if err != nil {
+				utility.LogError(err, "TelemetryAPIConvert", "Can't parse span start", utility.KeyValue{K: "span", V: platformSpan.Name})
+				continue
+			}
+
+			span := spans.Spans().AppendEmpty()
+			span.SetName(platformSpan.Name)
+			span.SetTraceID(inv.traceID)
+			span.SetSpanID(newSpanID())
+			span.SetParentSpanID(inv.spanID)
+			span.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+			span.SetEndTimestamp(pcommon.NewTimestampFromTime(start.Add(durationFromMs(platformSpan.DurationMs))))
+			setSpanStatus(span, status, errorType)
+			inv.stamp(span.Attributes())
+		}
+	}
+
+	return traces
+}
+
+// ToMetrics converts the metrics of platform.report events into gauges, as
+// well as the metrics of CloudWatch Embedded Metric Format lines found in
+// function logs and the internal metrics of the listener if enabled.
+func (c *Converter) ToMetrics(events []Event) pmetric.Metrics {
+	metrics := pmetric.NewMetrics()
+	resourceMetrics := metrics.ResourceMetrics().AppendEmpty()
+	c.stampResource(resourceMetrics.Resource().Attributes())
+	scopeMetrics := resourceMetrics.ScopeMetrics().AppendEmpty()
+	scopeMetrics.Scope().SetName(scopeName)
+
+	reported := false
+	for _, event := range events {
+		if event.Type != PLATFORM_START && event.Type != PLATFORM_REPORT && event.Type != string(Function) {
+			continue
+		}
+
+		record, err := event.ParseRecord()
+		if err != nil {
+			utility.LogError(err, "TelemetryAPIConvert", "Can't parse event", utility.KeyValue{K: "type", V: event.Type})
+			continue
+		}
+
+		switch r := record.(type) {
+		case *PlatformStart:
+			c.current = c.invocation(r.RequestID, r.Tracing)
+
+		case *PlatformReport:
+			reported = true
+			inv := c.invocation(r.RequestID, r.Tracing)
+			ts := eventTimestamp(event)
+
+			appendGauge(scopeMetrics, "aws.lambda.duration", "ms", r.Metrics.DurationMs, ts, inv)
+			appendGauge(scopeMetrics, "aws.lambda.billed_duration", "ms", float64(r.Metrics.BilledDurationMs), ts, inv)
+			appendGauge(scopeMetrics, "aws.lambda.max_memory_used", "MBy", float64(r.Metrics.MaxMemoryUsedMB), ts, inv)
+			if r.Metrics.InitDurationMs > 0 {
+				appendGauge(scopeMetrics, "aws.lambda.init_duration", "ms", r.Metrics.InitDurationMs, ts, inv)
+			}
+
+		case *LogRecord:
+			if !c.config.EMFMetrics {
+				continue
+			}
+
+			err := appendEMFMetrics(scopeMetrics, r.Message, c.current)
+			if err != nil && !errors.Is(err, errNotEMF) {
+				utility.LogError(err, "TelemetryAPIConvert", "Can't convert embedded metric format log line")
+			}
+		}
+	}
+
+	// The metrics of the extension itself are set apart from those of the function
+	if reported && c.config.InternalMetrics {
+		resourceMetrics := metrics.ResourceMetrics().AppendEmpty()
+		c.stampResource(resourceMetrics.Resource().Attributes())
+		resourceMetrics.Resource().Attributes().PutStr(attributeServiceName, extensionServiceName)
+		scopeMetrics := resourceMetrics.ScopeMetrics().AppendEmpty()
+		scopeMetrics.Scope().SetName(scopeName)
+
+		err := appendInternalMetrics(scopeMetrics, pcommon.NewTimestampFromTime(time.Now()))
+		if err != nil {
+			utility.LogError(err, "TelemetryAPIConvert", "Can't read internal metrics")
+		}
+	}
+
+	return metrics
+}
+
+// setSpanStatus records the status of the phase a span measures.
+func setSpanStatus(span ptrace.Span, status Status, errorType string) {
+	span.Attributes().PutStr(attributeStatus, string(status))
+	if errorType != "" {
+		span.Attributes().PutStr(attributeErrorType, errorType)
+	}
+
+	if status == StatusSuccess {
+		return
+	}
+
+	span.Status().SetCode(ptrace.StatusCodeError)
+	if errorType != "" {
+		span.Status().SetMessage(errorType)
+	} else {
+		span.Status().SetMessage(string(status))
+	}
+}
+
+func appendGauge(scopeMetrics pmetric.ScopeMetrics, name string, unit string, value float64, ts pcommon.Timestamp, inv invocation) {
+	metric := scopeMetrics.Metrics().AppendEmpty()
+	metric.SetName(name)
+	metric.SetUnit(unit)
+
+	dp := metric.SetEmptyGauge().DataPoints().AppendEmpty()
+	dp.SetTimestamp(ts)
+	dp.SetDoubleValue(value)
+	inv.stamp(dp.Attributes())
+}
+
+// eventTimestamp returns the time of an event, or the current time if it can't be parsed.
+func eventTimestamp(event Event) pcommon.Timestamp {
+	t, err := time.Parse(time.RFC3339Nano, event.Time)
+	if err != nil {
+		t = time.Now()
+	}
+
+	return pcommon.NewTimestampFromTime(t)
+}
+
+func durationFromMs(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// parseTraceContext extracts the trace and parent span IDs of an X-Amzn-Trace-Id
+// trace context, e.g. Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1.
+// The span ID reported by the platform takes precedence over the parent.
+func parseTraceContext(tracing *TraceContext) (pcommon.TraceID, pcommon.SpanID) {
+	var traceID pcommon.TraceID
+	var spanID pcommon.SpanID
+
+	if tracing.Type != amznTraceIDType {
+		return traceID, spanID
+	}
+
+	for _, field := range strings.Split(tracing.Value, ";") {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "Root":
+			// 1-<8 hex digits epoch>-<24 hex digits random>
+			parts := strings.Split(value, "-")
+			if len(parts) == 3 {
+				decodeHex(traceID[:], parts[1]+parts[2])
+			}
+
+		case "Parent":
+			decodeHex(spanID[:], value)
+		}
+	}
+
+	if tracing.SpanID != "" {
+		decodeHex(spanID[:], tracing.SpanID)
+	}
+
+	return traceID, spanID
+}
+
+// decodeHex decodes s into dst, leaving dst unchanged unless s is exactly len(dst) bytes.
+func decodeHex(dst []byte, s string) {
+	decoded, err := hex.DecodeString(s)
+	if err != nil || len(decoded) != len(dst) {
+		return
+	}
+
+	copy(dst, decoded)
+}
+
+func newTraceID() pcommon.TraceID {
+	var traceID pcommon.TraceID
+	_, _ = rand.Read(traceID[:])
+
+	return traceID
+}
+
+func newSpanID() pcommon.SpanID {
+	var spanID pcommon.SpanID
+	_, _ = rand.Read(spanID[:])
+
+	return spanID
+}
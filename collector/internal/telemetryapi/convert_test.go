@@ -0,0 +1,313 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+const testTracing = `{"spanId":"6e5ea2a1fe8d8a4d","type":"X-Amzn-Trace-Id","value":"Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"}`
+
+func invocationEvents() []Event {
+	return []Event{
+		{Time: "2022-10-12T00:00:00.000Z", Type: PLATFORM_INIT_START, Record: json.RawMessage(`{"initializationType":"on-demand","phase":"init"}`)},
+		{Time: "2022-10-12T00:00:01.000Z", Type: PLATFORM_START, Record: json.RawMessage(`{"requestId":"req-1","tracing":` + testTracing + `}`)},
+		{Time: "2022-10-12T00:00:01.100Z", Type: string(Function), Record: json.RawMessage(`"hello"`)},
+		{Time: "2022-10-12T00:00:01.200Z", Type: PLATFORM_RUNTIME_DONE, Record: json.RawMessage(`{"requestId":"req-1","status":"success","tracing":` + testTracing + `,"spans":[{"name":"responseLatency","start":"2022-10-12T00:00:01.150Z","durationMs":23.5}]}`)},
+		{Time: "2022-10-12T00:00:01.300Z", Type: PLATFORM_REPORT, Record: json.RawMessage(`{"requestId":"req-1","status":"success","metrics":{"durationMs":200.5,"billedDurationMs":201,"memorySizeMB":128,"maxMemoryUsedMB":64}}`)},
+	}
+}
+
+func TestConverterToLogs(t *testing.T) {
+	logs := NewConverter(ConverterConfig{ParseJSONLogs: true, EMFMetrics: true, FunctionLogSampleRate: 1}).ToLogs(invocationEvents())
+
+	records := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	assert.Equal(t, 5, records.Len())
+
+	for i, expected := range []string{"", "req-1", "req-1", "req-1", "req-1"} {
+		invocationID, ok := records.At(i).Attributes().Get(attributeFaaSInvocationID)
+		if expected == "" {
+			assert.False(t, ok)
+			continue
+		}
+
+		assert.Equal(t, expected, invocationID.Str())
+	}
+
+	functionLog := records.At(2)
+	assert.Equal(t, "hello", functionLog.Body().Str())
+	assert.Equal(t, "5759e988bd862e3fe1be46a994272793", functionLog.TraceID().HexString())
+	assert.Equal(t, "6e5ea2a1fe8d8a4d", functionLog.SpanID().HexString())
+	assert.True(t, records.At(0).TraceID().IsEmpty())
+}
+
+func TestConverterToTraces(t *testing.T) {
+	traces := NewConverter(ConverterConfig{ParseJSONLogs: true, EMFMetrics: true, FunctionLogSampleRate: 1}).ToTraces(invocationEvents())
+
+	spans := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	assert.Equal(t, 1, spans.Len())
+
+	span := spans.At(0)
+	assert.Equal(t, "responseLatency", span.Name())
+	assert.Equal(t, "5759e988bd862e3fe1be46a994272793", span.TraceID().HexString())
+	assert.Equal(t, "6e5ea2a1fe8d8a4d", span.ParentSpanID().HexString())
+	assert.Equal(t, int64(23500000), int64(span.EndTimestamp()-span.StartTimestamp()))
+
+	invocationID, ok := span.Attributes().Get(attributeFaaSInvocationID)
+	assert.True(t, ok)
+	assert.Equal(t, "req-1", invocationID.Str())
+	assert.Equal(t, ptrace.StatusCodeUnset, span.Status().Code())
+}
+
+func TestConverterToTracesInvokeTraceContext(t *testing.T) {
+	record := `{"requestId":"req-1","status":"success","spans":[{"name":"responseLatency","start":"2022-10-12T00:00:01.150Z","durationMs":1}]}`
+	events := []Event{{Type: PLATFORM_RUNTIME_DONE, Record: json.RawMessage(record)}}
+
+	converter := NewConverter(ConverterConfig{})
+	converter.SetTraceContexts(map[string]TraceContext{
+		"req-1": {Type: amznTraceIDType, Value: "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"},
+	})
+
+	span := converter.ToTraces(events).ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	assert.Equal(t, "5759e988bd862e3fe1be46a994272793", span.TraceID().HexString())
+	assert.Equal(t, "53995c3f42cd8ad8", span.ParentSpanID().HexString())
+
+	// The trace context of the event takes precedence
+	events[0].Record = json.RawMessage(`{"requestId":"req-1","status":"success","tracing":` + testTracing + `,"spans":[{"name":"responseLatency","start":"2022-10-12T00:00:01.150Z","durationMs":1}]}`)
+	span = converter.ToTraces(events).ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	assert.Equal(t, "6e5ea2a1fe8d8a4d", span.ParentSpanID().HexString())
+
+	// Invocations without one get a new trace
+	converter.SetTraceContexts(nil)
+	events[0].Record = json.RawMessage(record)
+	span = converter.ToTraces(events).ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	assert.NotEqual(t, "5759e988bd862e3fe1be46a994272793", span.TraceID().HexString())
+	assert.True(t, span.ParentSpanID().IsEmpty())
+}
+
+func TestConverterToTracesStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		record    string
+		code      ptrace.StatusCode
+		message   string
+		errorType string
+	}{
+		{name: "success", record: `{"requestId":"1","status":"success"`, code: ptrace.StatusCodeUnset},
+		{name: "error", record: `{"requestId":"1","status":"error","errorType":"Runtime.ExitError"`, code: ptrace.StatusCodeError, message: "Runtime.ExitError", errorType: "Runtime.ExitError"},
+		{name: "timeout", record: `{"requestId":"1","status":"timeout"`, code: ptrace.StatusCodeError, message: "timeout"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			record := tc.record + `,"spans":[{"name":"responseLatency","start":"2022-10-12T00:00:01.150Z","durationMs":1}]}`
+			events := []Event{{Type: PLATFORM_RUNTIME_DONE, Record: json.RawMessage(record)}}
+
+			span := NewConverter(ConverterConfig{}).ToTraces(events).ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+
+			assert.Equal(t, tc.code, span.Status().Code())
+			assert.Equal(t, tc.message, span.Status().Message())
+
+			status, ok := span.Attributes().Get(attributeStatus)
+			assert.True(t, ok)
+			assert.Equal(t, tc.name, status.Str())
+
+			errorType, ok := span.Attributes().Get(attributeErrorType)
+			assert.Equal(t, tc.errorType != "", ok)
+			if ok {
+				assert.Equal(t, tc.errorType, errorType.Str())
+			}
+		})
+	}
+}
+
+func TestConverterToMetrics(t *testing.T) {
+	metrics := NewConverter(ConverterConfig{ParseJSONLogs: true, EMFMetrics: true, FunctionLogSampleRate: 1}).ToMetrics(invocationEvents())
+
+	scopeMetrics := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	assert.Equal(t, 3, scopeMetrics.Len())
+
+	values := map[string]float64{}
+	for i := 0; i < scopeMetrics.Len(); i++ {
+		dp := scopeMetrics.At(i).Gauge().DataPoints().At(0)
+		values[scopeMetrics.At(i).Name()] = dp.DoubleValue()
+
+		invocationID, ok := dp.Attributes().Get(attributeFaaSInvocationID)
+		assert.True(t, ok)
+		assert.Equal(t, "req-1", invocationID.Str())
+	}
+
+	assert.Equal(t, map[string]float64{
+		"aws.lambda.duration":        200.5,
+		"aws.lambda.billed_duration": 201,
+		"aws.lambda.max_memory_used": 64,
+	}, values)
+}
+
+func TestParseTraceContext(t *testing.T) {
+	traceID, spanID := parseTraceContext(&TraceContext{Type: amznTraceIDType, Value: "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"})
+	assert.Equal(t, "5759e988bd862e3fe1be46a994272793", traceID.HexString())
+	assert.Equal(t, "53995c3f42cd8ad8", spanID.HexString())
+
+	traceID, spanID = parseTraceContext(&TraceContext{Type: amznTraceIDType, Value: "Root=invalid"})
+	assert.True(t, traceID.IsEmpty())
+	assert.True(t, spanID.IsEmpty())
+}
+
+func TestConverterToMetricsEMF(t *testing.T) {
+	emf := `{"_aws":{"Timestamp":1665532801100,"CloudWatchMetrics":[{"Namespace":"orders","Dimensions":[["service"]],"Metrics":[{"Name":"latency","Unit":"Milliseconds"},{"Name":"items","Unit":"Count"}]}]},"service":"checkout","latency":[12.5,20],"items":3}`
+	line, err := json.Marshal(emf)
+	assert.NoError(t, err)
+
+	events := []Event{
+		{Time: "2022-10-12T00:00:01.000Z", Type: PLATFORM_START, Record: json.RawMessage(`{"requestId":"req-1"}`)},
+		{Time: "2022-10-12T00:00:01.100Z", Type: string(Function), Record: line},
+		{Time: "2022-10-12T00:00:01.200Z", Type: string(Function), Record: json.RawMessage(`"not a metric"`)},
+		{Time: "2022-10-12T00:00:01.300Z", Type: string(Function), Record: json.RawMessage(`"{\"_aws\":{\"CloudWatchMetrics\":[{\"Metrics\":[{\"Name\":\"bad\"}]}]},\"bad\":\"x\"}"`)},
+	}
+
+	metrics := NewConverter(ConverterConfig{ParseJSONLogs: true, EMFMetrics: true, FunctionLogSampleRate: 1}).ToMetrics(events).ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	assert.Equal(t, 2, metrics.Len())
+
+	latency := metrics.At(0)
+	assert.Equal(t, "latency", latency.Name())
+	assert.Equal(t, "ms", latency.Unit())
+	assert.Equal(t, 2, latency.Gauge().DataPoints().Len())
+	assert.Equal(t, 20.0, latency.Gauge().DataPoints().At(1).DoubleValue())
+	assert.Equal(t, map[string]interface{}{
+		attributeCloudWatchNamespace: "orders",
+		"service":                    "checkout",
+		attributeFaaSInvocationID:    "req-1",
+	}, latency.Gauge().DataPoints().At(0).Attributes().AsRaw())
+
+	items := metrics.At(1)
+	assert.Equal(t, "items", items.Name())
+	assert.Equal(t, "1", items.Unit())
+	assert.Equal(t, pmetric.AggregationTemporalityDelta, items.Sum().AggregationTemporality())
+	assert.True(t, items.Sum().IsMonotonic())
+	assert.Equal(t, 3.0, items.Sum().DataPoints().At(0).DoubleValue())
+}
+
+func TestAppendEMFMetrics(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		line    string
+		metrics map[string][]map[string]interface{}
+		err     bool
+	}{
+		{
+			name: "dimension sets",
+			line: `{"_aws":{"CloudWatchMetrics":[{"Namespace":"ns","Dimensions":[["service"],["service","region"]],"Metrics":[{"Name":"latency"}]}]},"service":"api","region":"eu","latency":5}`,
+			metrics: map[string][]map[string]interface{}{
+				"latency": {
+					{attributeCloudWatchNamespace: "ns", "service": "api"},
+					{attributeCloudWatchNamespace: "ns", "service": "api", "region": "eu"},
+				},
+			},
+		},
+		{
+			name: "no dimensions",
+			line: `{"_aws":{"CloudWatchMetrics":[{"Namespace":"ns","Metrics":[{"Name":"latency"}]}]},"latency":5}`,
+			metrics: map[string][]map[string]interface{}{
+				"latency": {{attributeCloudWatchNamespace: "ns"}},
+			},
+		},
+		{
+			name: "invalid values",
+			line: `{"_aws":{"CloudWatchMetrics":[{"Namespace":"ns","Metrics":[{"Name":"missing"},{"Name":"text"},{"Name":"null"},{"Name":"empty"},{"Name":"latency"}]}]},"text":"5","null":null,"empty":[],"latency":5}`,
+			metrics: map[string][]map[string]interface{}{
+				"latency": {{attributeCloudWatchNamespace: "ns"}},
+			},
+			err: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			scopeMetrics := pmetric.NewScopeMetrics()
+
+			err := appendEMFMetrics(scopeMetrics, tc.line, invocation{})
+			if tc.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			metrics := make(map[string][]map[string]interface{})
+			for i := 0; i < scopeMetrics.Metrics().Len(); i++ {
+				metric := scopeMetrics.Metrics().At(i)
+				for j := 0; j < metric.Gauge().DataPoints().Len(); j++ {
+					metrics[metric.Name()] = append(metrics[metric.Name()], metric.Gauge().DataPoints().At(j).Attributes().AsRaw())
+				}
+			}
+			assert.Equal(t, tc.metrics, metrics)
+		})
+	}
+}
+
+func TestConverterToLogsJSON(t *testing.T) {
+	events := []Event{
+		{Time: "2022-10-12T00:00:01.000Z", Type: PLATFORM_START, Record: json.RawMessage(`{"requestId":"req-1"}`)},
+		{Time: "2022-10-12T00:00:01.100Z", Type: string(Function), Record: json.RawMessage(`{"timestamp":"2022-10-12T00:00:01.050Z","level":"WARN","message":"low stock","sku":"A-1","count":2}`)},
+		{Time: "2022-10-12T00:00:01.200Z", Type: string(Function), Record: json.RawMessage(`"{\"msg\":\"failed\",\"severity\":\"error\",\"requestId\":\"ignored\"}"`)},
+		{Time: "2022-10-12T00:00:01.300Z", Type: string(Function), Record: json.RawMessage(`"plain {text}"`)},
+	}
+
+	records := NewConverter(ConverterConfig{ParseJSONLogs: true, EMFMetrics: true, FunctionLogSampleRate: 1}).ToLogs(events).ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	assert.Equal(t, 4, records.Len())
+
+	warn := records.At(1)
+	assert.Equal(t, "low stock", warn.Body().Str())
+	assert.Equal(t, "WARN", warn.SeverityText())
+	assert.Equal(t, plog.SeverityNumberWarn, warn.SeverityNumber())
+	assert.Equal(t, "2022-10-12T00:00:01.05Z", warn.Timestamp().AsTime().Format(time.RFC3339Nano))
+	assert.Equal(t, map[string]interface{}{
+		attributeEventType:        "function",
+		attributeFaaSInvocationID: "req-1",
+		"sku":                     "A-1",
+		"count":                   2.0,
+	}, warn.Attributes().AsRaw())
+
+	failed := records.At(2)
+	assert.Equal(t, "failed", failed.Body().Str())
+	assert.Equal(t, plog.SeverityNumberError, failed.SeverityNumber())
+	invocationID, _ := failed.Attributes().Get(attributeFaaSInvocationID)
+	assert.Equal(t, "req-1", invocationID.Str())
+
+	plain := records.At(3)
+	assert.Equal(t, "plain {text}", plain.Body().Str())
+	assert.Equal(t, plog.SeverityNumberUnspecified, plain.SeverityNumber())
+}
+
+func TestConverterToLogsSampling(t *testing.T) {
+	events := []Event{
+		{Time: "2022-10-12T00:00:01.000Z", Type: PLATFORM_START, Record: json.RawMessage(`{"requestId":"req-1"}`)},
+		{Time: "2022-10-12T00:00:01.100Z", Type: string(Function), Record: json.RawMessage(`"plain line"`)},
+		{Time: "2022-10-12T00:00:01.200Z", Type: string(Function), Record: json.RawMessage(`{"level":"DEBUG","message":"noise"}`)},
+		{Time: "2022-10-12T00:00:01.300Z", Type: string(Function), Record: json.RawMessage(`{"level":"ERROR","message":"failed"}`)},
+		{Time: "2022-10-12T00:00:01.400Z", Type: string(Extension), Record: json.RawMessage(`"extension line"`)},
+	}
+
+	records := NewConverter(ConverterConfig{ParseJSONLogs: true, FunctionLogSampleRate: 0}).ToLogs(events).ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+
+	var bodies []string
+	for i := 0; i < records.Len(); i++ {
+		bodies = append(bodies, records.At(i).Body().AsString())
+	}
+	assert.Equal(t, []string{`{"requestId":"req-1"}`, "failed", "extension line"}, bodies)
+}
@@ -0,0 +1,91 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapi
+
+import (
+	"encoding/json"
+)
+
+type dedupKey struct {
+	requestID string
+	eventType string
+}
+
+// deduplicator drops the invocation platform events the Telemetry API
+// redelivers when it retries a batch. It remembers the events of the current
+// and previous invocations, since a retried batch can arrive after the next
+// invocation has started.
+type deduplicator struct {
+	requestID string
+	current   map[dedupKey]struct{}
+	previous  map[dedupKey]struct{}
+}
+
+func newDeduplicator() *deduplicator {
+	return &deduplicator{
+		current:  make(map[dedupKey]struct{}),
+		previous: make(map[dedupKey]struct{}),
+	}
+}
+
+// filter returns the events that were not seen before, reusing the backing array of events.
+func (d *deduplicator) filter(events []Event) []Event {
+	out := events[:0]
+	for _, event := range events {
+		if d.duplicate(event) {
+			recordDuplicateEvent(event.Type)
+			continue
+		}
+
+		out = append(out, event)
+	}
+
+	return out
+}
+
+func (d *deduplicator) duplicate(event Event) bool {
+	if event.Type != PLATFORM_START && event.Type != PLATFORM_RUNTIME_DONE && event.Type != PLATFORM_REPORT {
+		return false
+	}
+
+	var record struct {
+		RequestID string `json:"requestId"`
+	}
+	if json.Unmarshal(event.Record, &record) != nil || record.RequestID == "" {
+		return false
+	}
+
+	key := dedupKey{requestID: record.RequestID, eventType: event.Type}
+	if _, ok := d.current[key]; ok {
+		return true
+	}
+
+	if _, ok := d.previous[key]; ok {
+		return true
+	}
+
+	if record.RequestID != d.requestID {
+		if event.Type == PLATFORM_START {
+			d.previous = d.current
+			d.current = make(map[dedupKey]struct{})
+		}
+
+		d.requestID = record.RequestID
+	}
+
+	d.current[key] = struct{}{}
+
+	return false
+}
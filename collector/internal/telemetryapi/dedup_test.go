@@ -0,0 +1,66 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func requestEvent(eventType string, requestID string) Event {
+	return Event{Type: eventType, Record: json.RawMessage(`{"requestId":"` + requestID + `","status":"success"}`)}
+}
+
+func TestDeduplicatorFilter(t *testing.T) {
+	d := newDeduplicator()
+
+	for _, tc := range []struct {
+		name     string
+		batch    []Event
+		expected int
+	}{
+		{
+			name:     "first invocation",
+			batch:    []Event{requestEvent(PLATFORM_START, "1"), {Type: "function"}, requestEvent(PLATFORM_RUNTIME_DONE, "1")},
+			expected: 3,
+		},
+		{
+			name:     "redelivered batch",
+			batch:    []Event{requestEvent(PLATFORM_START, "1"), {Type: "function"}, requestEvent(PLATFORM_RUNTIME_DONE, "1")},
+			expected: 1,
+		},
+		{
+			name:     "next invocation",
+			batch:    []Event{requestEvent(PLATFORM_REPORT, "1"), requestEvent(PLATFORM_START, "2"), requestEvent(PLATFORM_RUNTIME_DONE, "2")},
+			expected: 3,
+		},
+		{
+			name:     "late redelivery of previous invocation",
+			batch:    []Event{requestEvent(PLATFORM_RUNTIME_DONE, "1"), requestEvent(PLATFORM_REPORT, "1"), requestEvent(PLATFORM_RUNTIME_DONE, "2")},
+			expected: 0,
+		},
+		{
+			name:     "forgotten after two invocations",
+			batch:    []Event{requestEvent(PLATFORM_START, "3"), requestEvent(PLATFORM_RUNTIME_DONE, "1")},
+			expected: 2,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Len(t, d.filter(tc.batch), tc.expected)
+		})
+	}
+}
@@ -0,0 +1,293 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Workiva/go-datastructures/queue"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/utility"
+	"github.com/tiqqe/go-logger"
+)
+
+// Consumer receives the Telemetry API events dispatched by a Listener.
+type Consumer interface {
+	// ConsumeEvents is called from the dispatching goroutine with each batch
+	// of events taken from the queue. Implementations must not retain events.
+	ConsumeEvents(ctx context.Context, events []Event)
+}
+
+// EventTypesConsumer is a Consumer needing specific event types to be
+// subscribed to, e.g. as set in the configuration of a receiver.
+type EventTypesConsumer interface {
+	Consumer
+	// EventTypes returns the event types the consumer needs, or nil if it
+	// makes do with the subscribed ones.
+	EventTypes() []EventType
+}
+
+// ConsumerFunc is an adapter to use ordinary functions as a Consumer.
+type ConsumerFunc func(ctx context.Context, events []Event)
+
+// ConsumeEvents calls f(ctx, events).
+func (f ConsumerFunc) ConsumeEvents(ctx context.Context, events []Event) {
+	f(ctx, events)
+}
+
+// AddConsumer registers a consumer that receives every event dispatched after the call.
+func (s *Listener) AddConsumer(consumer Consumer) {
+	s.consumersMu.Lock()
+	defer s.consumersMu.Unlock()
+
+	s.consumers = append(s.consumers, consumer)
+}
+
+// ConsumerEventTypes returns the event types the consumers need, with
+// platform events always included, or nil if none of them is an
+// EventTypesConsumer stating its needs.
+func (s *Listener) ConsumerEventTypes() []EventType {
+	s.consumersMu.RLock()
+	defer s.consumersMu.RUnlock()
+
+	needed := make(map[EventType]bool)
+	for _, consumer := range s.consumers {
+		if c, ok := consumer.(EventTypesConsumer); ok {
+			for _, eventType := range c.EventTypes() {
+				needed[eventType] = true
+			}
+		}
+	}
+
+	if len(needed) == 0 {
+		return nil
+	}
+
+	eventTypes := []EventType{Platform}
+	for _, eventType := range []EventType{Function, Extension} {
+		if needed[eventType] {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+
+	return eventTypes
+}
+
+// RemoveConsumer unregisters a consumer added with AddConsumer. The consumer
+// must be comparable, e.g. a pointer; batches being delivered may still reach it.
+func (s *Listener) RemoveConsumer(consumer Consumer) {
+	s.consumersMu.Lock()
+	defer s.consumersMu.Unlock()
+
+	consumers := make([]Consumer, 0, len(s.consumers))
+	for _, c := range s.consumers {
+		if c != consumer {
+			consumers = append(consumers, c)
+		}
+	}
+
+	s.consumers = consumers
+}
+
+// ResumeDispatch starts the goroutine dispatching the queued events, which
+// Start does unless DeferDispatch is set. Subsequent calls do nothing.
+func (s *Listener) ResumeDispatch() {
+	s.dispatchOnce.Do(func() {
+		s.dispatchDone = make(chan struct{})
+
+		go func() {
+			defer close(s.dispatchDone)
+			s.dispatch()
+		}()
+	})
+}
+
+// dispatch delivers the queued events to the consumers until the queue is
+// disposed. Each batch holds whatever is queued, up to maxBatchSize events, so
+// a single event such as platform.runtimeDone is dispatched without waiting
+// for more to arrive.
+func (s *Listener) dispatch() {
+	for {
+		items, err := s.queue.Poll(maxBatchSize, dispatchPollTimeout)
+		if errors.Is(err, queue.ErrTimeout) {
+			continue
+		}
+
+		if err != nil {
+			return
+		}
+
+		recordQueueSize(s.queue.Len())
+		s.deliver(items)
+	}
+}
+
+// deliver fans out a batch of queued items to all consumers. Batches are
+// delivered one at a time, so consumers are never called concurrently.
+func (s *Listener) deliver(items []interface{}) {
+	s.deliverMu.Lock()
+	defer s.deliverMu.Unlock()
+	defer atomic.AddInt64(&s.pending, -int64(len(items)))
+
+	events := make([]Event, 0, len(items))
+	for _, item := range items {
+		event, ok := item.(Event)
+		if !ok {
+			logger.WarnStringf("Non-Event found in queue. Item: %v", item)
+			continue
+		}
+
+		events = append(events, event)
+	}
+
+	recordProcessingLatency(events)
+	events = s.dedup.filter(events)
+
+	s.consumersMu.RLock()
+	consumers := s.consumers
+	s.consumersMu.RUnlock()
+
+	for _, consumer := range consumers {
+		consume(consumer, events)
+	}
+}
+
+// consume delivers events to a consumer. A panicking consumer loses the batch
+// but doesn't stop the dispatching, nor the extension.
+func consume(consumer Consumer, events []Event) {
+	defer utility.RecoverPanic("TelemetryAPIDispatch", nil)
+
+	consumer.ConsumeEvents(context.Background(), events)
+}
+
+// runtimeDoneWaiter tracks the platform.runtimeDone events received so Wait
+// can return as soon as the event of its invocation has been dispatched.
+type runtimeDoneWaiter struct {
+	mu   sync.Mutex
+	done map[string]Event
+	// changed is closed and replaced whenever a runtimeDone event is received
+	changed chan struct{}
+}
+
+func newRuntimeDoneWaiter() *runtimeDoneWaiter {
+	return &runtimeDoneWaiter{
+		done:    make(map[string]Event),
+		changed: make(chan struct{}),
+	}
+}
+
+func (w *runtimeDoneWaiter) ConsumeEvents(_ context.Context, events []Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	received := false
+	for _, event := range events {
+		if event.Type != PLATFORM_RUNTIME_DONE {
+			continue
+		}
+
+		record, err := event.ParseRecord()
+		if err != nil {
+			utility.LogError(err, "TelemetryAPIDispatch", "Can't parse platform.runtimeDone event")
+			continue
+		}
+
+		w.done[record.(*PlatformRuntimeDone).RequestID] = event
+		received = true
+	}
+
+	if received {
+		close(w.changed)
+		w.changed = make(chan struct{})
+	}
+}
+
+// wait blocks until the runtimeDone event of requestID is received or the
+// context is done. Events of earlier requests are forgotten once it returns.
+func (w *runtimeDoneWaiter) wait(ctx context.Context, requestID string) (Event, error) {
+	for {
+		w.mu.Lock()
+		event, ok := w.done[requestID]
+		if ok {
+			w.done = make(map[string]Event)
+		}
+		changed := w.changed
+		w.mu.Unlock()
+
+		if ok {
+			return event, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return Event{}, ctx.Err()
+
+		case <-changed:
+		}
+	}
+}
+
+// platformMetricsConsumer records internal metrics from platform events.
+func platformMetricsConsumer(_ context.Context, events []Event) {
+	for _, event := range events {
+		if event.Type != PLATFORM_LOGS_DROPPED && event.Type != PLATFORM_RUNTIME_DONE {
+			continue
+		}
+
+		record, err := event.ParseRecord()
+		if err != nil {
+			utility.LogError(err, "TelemetryAPIDispatch", "Can't parse platform event", utility.KeyValue{K: "type", V: event.Type})
+			continue
+		}
+
+		switch r := record.(type) {
+		case *PlatformLogsDropped:
+			recordLogsDropped(r)
+
+			err := errors.New("failed to process event")
+			utility.LogError(err, "TelemetryAPIDispatch", "Can't process one or more events", utility.KeyValue{K: "event", V: r})
+
+		case *PlatformRuntimeDone:
+			reportRuntimeDone(r)
+		}
+	}
+}
+
+// reportRuntimeDone records the invocation status of a platform.runtimeDone
+// event and logs diagnostics when the invocation errored or timed out.
+func reportRuntimeDone(record *PlatformRuntimeDone) {
+	recordInvocation(record.Status)
+
+	if record.Status == StatusSuccess {
+		return
+	}
+
+	kv := []utility.KeyValue{
+		{K: "request_id", V: record.RequestID},
+		{K: "status", V: record.Status},
+	}
+
+	if record.ErrorType != "" {
+		kv = append(kv, utility.KeyValue{K: "error_type", V: record.ErrorType})
+	}
+
+	if record.Metrics != nil {
+		kv = append(kv, utility.KeyValue{K: "duration_ms", V: record.Metrics.DurationMs})
+	}
+
+	utility.LogError(nil, "RuntimeDone", "Function invocation did not complete successfully", kv...)
+}
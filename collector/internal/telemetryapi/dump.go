@@ -0,0 +1,184 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/utility"
+)
+
+const (
+	// dumpQueueSize is the number of payloads waiting to be dumped before new ones are discarded
+	dumpQueueSize = 16
+	// dumpTimeout bounds a single dump, e.g. an S3 PutObject
+	dumpTimeout = 5 * time.Second
+	// dumpErrorLogInterval is the minimum time between two logged dump failures.
+	// Failures are logged sparingly since with extension logs subscribed, every
+	// log line comes back as a new payload to dump.
+	dumpErrorLogInterval = 5 * time.Minute
+)
+
+// Dumper writes raw Telemetry API payloads, for troubleshooting and for
+// capturing real event shapes as test fixtures.
+type Dumper interface {
+	Dump(ctx context.Context, payload []byte) error
+}
+
+// NewDumper returns a Dumper writing to target, which is either an
+// s3://bucket/prefix URI or the path of a local file, e.g. in /tmp.
+func NewDumper(ctx context.Context, target string) (Dumper, error) {
+	if !strings.HasPrefix(target, "s3://") {
+		return &fileDumper{path: target}, nil
+	}
+
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(target, "s3://"), "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("invalid dump target %q: missing bucket", target)
+	}
+
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load AWS configuration: %w", err)
+	}
+
+	return &s3Dumper{
+		client: s3.NewFromConfig(cfg),
+		bucket: bucket,
+		prefix: prefix,
+	}, nil
+}
+
+// fileDumper appends each payload as a line to a local file.
+type fileDumper struct {
+	path string
+	mu   sync.Mutex
+}
+
+func (d *fileDumper) Dump(_ context.Context, payload []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	file, err := os.OpenFile(d.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(bytes.TrimSpace(payload), '\n'))
+
+	return err
+}
+
+// s3Dumper writes each payload as a separate object under a bucket prefix.
+type s3Dumper struct {
+	client *s3.Client
+	bucket string
+	prefix string
+	seq    uint64
+}
+
+func (d *s3Dumper) Dump(ctx context.Context, payload []byte) error {
+	key := fmt.Sprintf("%s%s/%s-%06d.json",
+		d.prefix,
+		os.Getenv("AWS_LAMBDA_FUNCTION_NAME"),
+		time.Now().UTC().Format("20060102T150405.000000000Z"),
+		atomic.AddUint64(&d.seq, 1),
+	)
+
+	_, err := d.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(d.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(payload),
+		ContentType: aws.String("application/json"),
+	})
+
+	return err
+}
+
+// asyncDumper dumps payloads from a background goroutine, so a slow or
+// failing target, including a forward destination, never delays the
+// Telemetry API deliveries. Payloads are
+// discarded while the dumper is behind.
+type asyncDumper struct {
+	dumper   Dumper
+	target   string
+	payloads chan []byte
+	done     chan struct{}
+
+	// failed and lastErrorLog are only accessed by the dumping goroutine
+	failed       int
+	lastErrorLog time.Time
+}
+
+func newAsyncDumper(dumper Dumper, target string) *asyncDumper {
+	d := &asyncDumper{
+		dumper:   dumper,
+		target:   target,
+		payloads: make(chan []byte, dumpQueueSize),
+		done:     make(chan struct{}),
+	}
+
+	go d.run()
+
+	return d
+}
+
+// enqueue schedules payload to be dumped, discarding it if the queue is full.
+func (d *asyncDumper) enqueue(payload []byte) {
+	select {
+	case d.payloads <- payload:
+	default:
+	}
+}
+
+// close dumps the payloads still queued and stops the dumping goroutine.
+func (d *asyncDumper) close() {
+	close(d.payloads)
+	<-d.done
+}
+
+func (d *asyncDumper) run() {
+	defer close(d.done)
+
+	for payload := range d.payloads {
+		ctx, cancel := context.WithTimeout(context.Background(), dumpTimeout)
+		err := d.dumper.Dump(ctx, payload)
+		cancel()
+
+		if err != nil {
+			d.failed++
+			if time.Since(d.lastErrorLog) >= dumpErrorLogInterval {
+				utility.LogError(err, "TelemetryAPIDump", "Failed writing raw telemetry payloads", utility.KeyValue{K: "target", V: d.target}, utility.KeyValue{K: "failed", V: d.failed})
+				d.failed = 0
+				d.lastErrorLog = time.Now()
+			}
+		}
+	}
+}
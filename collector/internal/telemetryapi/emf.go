@@ -0,0 +1,186 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/multierr"
+)
+
+const attributeCloudWatchNamespace = "aws.cloudwatch.namespace"
+
+// emfUnits maps CloudWatch units to UCUM units.
+var emfUnits = map[string]string{
+	"Seconds":          "s",
+	"Microseconds":     "us",
+	"Milliseconds":     "ms",
+	"Bytes":            "By",
+	"Kilobytes":        "kBy",
+	"Megabytes":        "MBy",
+	"Gigabytes":        "GBy",
+	"Terabytes":        "TBy",
+	"Bits":             "bit",
+	"Kilobits":         "kbit",
+	"Megabits":         "Mbit",
+	"Gigabits":         "Gbit",
+	"Terabits":         "Tbit",
+	"Percent":          "%",
+	"Count":            "1",
+	"Bytes/Second":     "By/s",
+	"Kilobytes/Second": "kBy/s",
+	"Megabytes/Second": "MBy/s",
+	"Gigabytes/Second": "GBy/s",
+	"Terabytes/Second": "TBy/s",
+	"Bits/Second":      "bit/s",
+	"Kilobits/Second":  "kbit/s",
+	"Megabits/Second":  "Mbit/s",
+	"Gigabits/Second":  "Gbit/s",
+	"Terabits/Second":  "Tbit/s",
+	"Count/Second":     "1/s",
+	"None":             "1",
+}
+
+// emfMetadata is the _aws member of a CloudWatch Embedded Metric Format log line.
+// Specification: https://docs.aws.amazon.com/AmazonCloudWatch/latest/monitoring/CloudWatch_Embedded_Metric_Format_Specification.html
+type emfMetadata struct {
+	Timestamp         int64 `json:"Timestamp"`
+	CloudWatchMetrics []struct {
+		Namespace  string     `json:"Namespace"`
+		Dimensions [][]string `json:"Dimensions"`
+		Metrics    []struct {
+			Name string `json:"Name"`
+			Unit string `json:"Unit"`
+		} `json:"Metrics"`
+	} `json:"CloudWatchMetrics"`
+}
+
+var errNotEMF = errors.New("not an embedded metric format log line")
+
+// appendEMFMetrics converts the metrics of an Embedded Metric Format log line.
+// Each dimension set of a metric is a series of its own, with the dimensions
+// as data point attributes. Count metrics, which count occurrences within the
+// invocation, become delta sums of their values, and other metrics gauges
+// with a data point per value. Metrics without a valid value are skipped and
+// reported in the returned error. It returns errNotEMF if message is not an
+// EMF log line.
+func appendEMFMetrics(scopeMetrics pmetric.ScopeMetrics, message string, inv invocation) error {
+	message = strings.TrimSpace(message)
+	if !strings.HasPrefix(message, "{") || !strings.Contains(message, `"_aws"`) {
+		return errNotEMF
+	}
+
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal([]byte(message), &fields)
+	if err != nil {
+		return errNotEMF
+	}
+
+	var metadata emfMetadata
+	err = json.Unmarshal(fields["_aws"], &metadata)
+	if err != nil || len(metadata.CloudWatchMetrics) == 0 {
+		return errNotEMF
+	}
+
+	ts := pcommon.NewTimestampFromTime(time.UnixMilli(metadata.Timestamp))
+
+	var errs error
+	for _, directive := range metadata.CloudWatchMetrics {
+		dimensionSets := directive.Dimensions
+		if len(dimensionSets) == 0 {
+			dimensionSets = [][]string{nil}
+		}
+
+		for _, definition := range directive.Metrics {
+			values, err := emfValues(fields[definition.Name])
+			if err != nil {
+				errs = multierr.Append(errs, fmt.Errorf("invalid value of metric %q: %w", definition.Name, err))
+				continue
+			}
+
+			metric := scopeMetrics.Metrics().AppendEmpty()
+			metric.SetName(definition.Name)
+			if unit, ok := emfUnits[definition.Unit]; ok {
+				metric.SetUnit(unit)
+			} else {
+				metric.SetUnit(definition.Unit)
+			}
+
+			var dataPoints pmetric.NumberDataPointSlice
+			if definition.Unit == "Count" {
+				sum := metric.SetEmptySum()
+				sum.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+				sum.SetIsMonotonic(true)
+				dataPoints = sum.DataPoints()
+
+				var total float64
+				for _, value := range values {
+					total += value
+				}
+				values = []float64{total}
+			} else {
+				dataPoints = metric.SetEmptyGauge().DataPoints()
+			}
+
+			for _, dimensionSet := range dimensionSets {
+				for _, value := range values {
+					dp := dataPoints.AppendEmpty()
+					dp.SetTimestamp(ts)
+					dp.SetDoubleValue(value)
+					dp.Attributes().PutStr(attributeCloudWatchNamespace, directive.Namespace)
+					for _, dimension := range dimensionSet {
+						var dimensionValue string
+						if json.Unmarshal(fields[dimension], &dimensionValue) == nil {
+							dp.Attributes().PutStr(dimension, dimensionValue)
+						}
+					}
+					inv.stamp(dp.Attributes())
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+// emfValues decodes a metric value, which is either a number or a non-empty array of numbers.
+func emfValues(raw json.RawMessage) ([]float64, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, errors.New("missing value")
+	}
+
+	var value float64
+	if json.Unmarshal(raw, &value) == nil {
+		return []float64{value}, nil
+	}
+
+	var values []float64
+	err := json.Unmarshal(raw, &values)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) == 0 {
+		return nil, errors.New("no values")
+	}
+
+	return values, nil
+}
@@ -0,0 +1,121 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapi
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/utility"
+)
+
+// eventFilter drops the events matching the exclusion rules of the listener
+// before they are queued. The invocation platform events the lifecycle relies
+// on are never dropped.
+type eventFilter struct {
+	types   map[string]struct{}
+	pattern *regexp.Regexp
+}
+
+// newEventFilter returns a filter dropping the events of the given types and
+// the function and extension log lines matching pattern, or nil if there is
+// nothing to drop.
+func newEventFilter(types []string, pattern *regexp.Regexp) *eventFilter {
+	f := &eventFilter{
+		types:   make(map[string]struct{}),
+		pattern: pattern,
+	}
+
+	for _, eventType := range types {
+		switch eventType {
+		case "":
+			continue
+		case PLATFORM_START, PLATFORM_RUNTIME_DONE, PLATFORM_REPORT:
+			utility.LogError(nil, "newEventFilter", "Invocation platform events can't be excluded", utility.KeyValue{K: "type", V: eventType})
+			continue
+		}
+
+		f.types[eventType] = struct{}{}
+	}
+
+	if len(f.types) == 0 && f.pattern == nil {
+		return nil
+	}
+
+	return f
+}
+
+// filter returns the events that are not excluded, reusing the backing array of events.
+func (f *eventFilter) filter(events []Event) []Event {
+	out := events[:0]
+	for _, event := range events {
+		if f.excluded(event) {
+			recordEventFiltered(event.Type)
+			continue
+		}
+
+		out = append(out, event)
+	}
+
+	return out
+}
+
+func (f *eventFilter) excluded(event Event) bool {
+	if _, ok := f.types[event.Type]; ok {
+		return true
+	}
+
+	if f.pattern == nil || (event.Type != string(Function) && event.Type != string(Extension)) {
+		return false
+	}
+
+	var message string
+	if json.Unmarshal(event.Record, &message) != nil {
+		// Structured log lines are matched against their JSON encoding
+		message = string(event.Record)
+	}
+
+	return f.pattern.MatchString(message)
+}
+
+// excludedTypesFromEnv returns the comma separated event types of the
+// OTEL_LAMBDA_TELEMETRY_EXCLUDE_TYPES environment variable.
+func excludedTypesFromEnv() []string {
+	var types []string
+	for _, eventType := range strings.Split(utility.GetEnvString("OTEL_LAMBDA_TELEMETRY_EXCLUDE_TYPES", ""), ",") {
+		types = append(types, strings.TrimSpace(eventType))
+	}
+
+	return types
+}
+
+// excludedPatternFromEnv returns the regular expression of the
+// OTEL_LAMBDA_TELEMETRY_EXCLUDE_PATTERN environment variable, or nil if it is
+// not set or invalid.
+func excludedPatternFromEnv() *regexp.Regexp {
+	expr := utility.GetEnvString("OTEL_LAMBDA_TELEMETRY_EXCLUDE_PATTERN", "")
+	if expr == "" {
+		return nil
+	}
+
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		utility.LogError(err, "ListenerConfigFromEnv", "Invalid log exclusion pattern, no log lines are excluded", utility.KeyValue{K: "pattern", V: expr})
+		return nil
+	}
+
+	return pattern
+}
@@ -0,0 +1,85 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventFilter(t *testing.T) {
+	batch := func() []Event {
+		return []Event{
+			requestEvent(PLATFORM_START, "1"),
+			{Type: PLATFORM_EXTENSION, Record: json.RawMessage(`{"name":"ext","state":"Ready"}`)},
+			{Type: string(Function), Record: json.RawMessage(`"DEBUG cache hit"`)},
+			{Type: string(Function), Record: json.RawMessage(`"INFO order placed"`)},
+			{Type: string(Function), Record: json.RawMessage(`{"level":"DEBUG","message":"structured"}`)},
+			{Type: PLATFORM_LOGS_DROPPED, Record: json.RawMessage(`{"reason":"DEBUG"}`)},
+			requestEvent(PLATFORM_RUNTIME_DONE, "1"),
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		types    []string
+		pattern  *regexp.Regexp
+		expected []string
+	}{
+		{
+			name:     "by type",
+			types:    []string{PLATFORM_EXTENSION, ""},
+			expected: []string{PLATFORM_START, "function", "function", "function", PLATFORM_LOGS_DROPPED, PLATFORM_RUNTIME_DONE},
+		},
+		{
+			name:     "by pattern",
+			pattern:  regexp.MustCompile(`DEBUG`),
+			expected: []string{PLATFORM_START, PLATFORM_EXTENSION, "function", PLATFORM_LOGS_DROPPED, PLATFORM_RUNTIME_DONE},
+		},
+		{
+			name:     "invocation events are kept",
+			types:    []string{PLATFORM_START, PLATFORM_RUNTIME_DONE, "function"},
+			expected: []string{PLATFORM_START, PLATFORM_EXTENSION, PLATFORM_LOGS_DROPPED, PLATFORM_RUNTIME_DONE},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newEventFilter(tc.types, tc.pattern)
+
+			var types []string
+			for _, event := range f.filter(batch()) {
+				types = append(types, event.Type)
+			}
+
+			assert.Equal(t, tc.expected, types)
+		})
+	}
+
+	assert.Nil(t, newEventFilter([]string{""}, nil))
+}
+
+func TestHTTPHandlerFilter(t *testing.T) {
+	l := NewListener(ListenerConfig{ExcludeTypes: []string{PLATFORM_EXTENSION}})
+
+	payload := `[{"type":"platform.extension","record":{}},{"type":"function","record":"a"}]`
+	l.httpHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload)))
+
+	assert.Equal(t, []string{"function"}, queuedTypes(t, l))
+}
@@ -0,0 +1,131 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+var (
+	// jsonLevelKeys, jsonMessageKeys and jsonTimestampKeys are the field names
+	// commonly used by JSON loggers, including the Lambda JSON log format.
+	jsonLevelKeys     = []string{"level", "severity", "logLevel", "log_level", "levelname"}
+	jsonMessageKeys   = []string{"message", "msg"}
+	jsonTimestampKeys = []string{"timestamp", "time", "@timestamp", "ts"}
+	jsonRequestIDKeys = []string{"requestId", "request_id", "AWSRequestId"}
+
+	severityNumbers = map[string]plog.SeverityNumber{
+		"TRACE":    plog.SeverityNumberTrace,
+		"DEBUG":    plog.SeverityNumberDebug,
+		"INFO":     plog.SeverityNumberInfo,
+		"NOTICE":   plog.SeverityNumberInfo2,
+		"WARN":     plog.SeverityNumberWarn,
+		"WARNING":  plog.SeverityNumberWarn,
+		"ERROR":    plog.SeverityNumberError,
+		"CRITICAL": plog.SeverityNumberFatal,
+		"FATAL":    plog.SeverityNumberFatal,
+	}
+)
+
+// parseJSONLog fills lr from a JSON function log line. The level, message
+// and timestamp fields are mapped to the severity, body and timestamp of the
+// log record and the remaining fields become attributes, except for the _aws
+// metadata of Embedded Metric Format lines. A message that is not a string,
+// e.g. an object, becomes the body as is. It returns false, leaving lr
+// untouched, if the line is not a JSON object.
+func parseJSONLog(lr plog.LogRecord, message string, inv *invocation) bool {
+	message = strings.TrimSpace(message)
+	if !strings.HasPrefix(message, "{") {
+		return false
+	}
+
+	var fields map[string]interface{}
+	err := json.Unmarshal([]byte(message), &fields)
+	if err != nil {
+		return false
+	}
+
+	if level, ok := takeString(fields, jsonLevelKeys); ok {
+		lr.SetSeverityText(level)
+		lr.SetSeverityNumber(severityNumber(level))
+	}
+
+	if body, ok := takeValue(fields, jsonMessageKeys); ok {
+		if err := lr.Body().FromRaw(body); err != nil {
+			lr.Body().SetStr(fmt.Sprint(body))
+		}
+	} else {
+		lr.Body().SetStr(message)
+	}
+
+	if timestamp, ok := takeString(fields, jsonTimestampKeys); ok {
+		if t, err := time.Parse(time.RFC3339Nano, timestamp); err == nil {
+			lr.SetTimestamp(pcommon.NewTimestampFromTime(t))
+		}
+	}
+
+	if requestID, ok := takeString(fields, jsonRequestIDKeys); ok && inv.requestID == "" {
+		inv.requestID = requestID
+	}
+
+	delete(fields, "_aws")
+
+	for key, value := range fields {
+		if err := lr.Attributes().PutEmpty(key).FromRaw(value); err != nil {
+			lr.Attributes().PutStr(key, fmt.Sprint(value))
+		}
+	}
+
+	return true
+}
+
+// takeString removes and returns the first of keys holding a string in fields.
+func takeString(fields map[string]interface{}, keys []string) (string, bool) {
+	for _, key := range keys {
+		if value, ok := fields[key].(string); ok {
+			delete(fields, key)
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// takeValue removes and returns the first of keys present in fields.
+func takeValue(fields map[string]interface{}, keys []string) (interface{}, bool) {
+	for _, key := range keys {
+		if value, ok := fields[key]; ok {
+			delete(fields, key)
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+// severityNumber maps a log level name to its severity number.
+func severityNumber(level string) plog.SeverityNumber {
+	if number, ok := severityNumbers[strings.ToUpper(level)]; ok {
+		return number
+	}
+
+	return plog.SeverityNumberUnspecified
+}
@@ -0,0 +1,87 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestParseJSONLog(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		message    string
+		parsed     bool
+		body       interface{}
+		attributes map[string]interface{}
+	}{
+		{
+			name:       "string message",
+			message:    `{"level":"INFO","message":"hello","user":"u-1"}`,
+			parsed:     true,
+			body:       "hello",
+			attributes: map[string]interface{}{"user": "u-1"},
+		},
+		{
+			name:       "object message",
+			message:    `{"level":"INFO","message":{"event":"order","id":7},"user":"u-1"}`,
+			parsed:     true,
+			body:       map[string]interface{}{"event": "order", "id": 7.0},
+			attributes: map[string]interface{}{"user": "u-1"},
+		},
+		{
+			name:       "number message",
+			message:    `{"msg":42}`,
+			parsed:     true,
+			body:       42.0,
+			attributes: map[string]interface{}{},
+		},
+		{
+			name:       "without message",
+			message:    `{"level":"INFO","user":"u-1"}`,
+			parsed:     true,
+			body:       `{"level":"INFO","user":"u-1"}`,
+			attributes: map[string]interface{}{"user": "u-1"},
+		},
+		{
+			name:       "embedded metric format",
+			message:    `{"_aws":{"Timestamp":1665532801100,"CloudWatchMetrics":[{"Namespace":"ns","Dimensions":[["service"]],"Metrics":[{"Name":"latency"}]}]},"service":"api","latency":5}`,
+			parsed:     true,
+			body:       `{"_aws":{"Timestamp":1665532801100,"CloudWatchMetrics":[{"Namespace":"ns","Dimensions":[["service"]],"Metrics":[{"Name":"latency"}]}]},"service":"api","latency":5}`,
+			attributes: map[string]interface{}{"service": "api", "latency": 5.0},
+		},
+		{
+			name:    "not an object",
+			message: `[1, 2]`,
+			parsed:  false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			lr := plog.NewLogRecord()
+
+			parsed := parseJSONLog(lr, tc.message, &invocation{})
+
+			assert.Equal(t, tc.parsed, parsed)
+			if !parsed {
+				return
+			}
+
+			assert.Equal(t, tc.body, lr.Body().AsRaw())
+			assert.Equal(t, tc.attributes, lr.Attributes().AsRaw())
+		})
+	}
+}
@@ -15,6 +15,8 @@
 package telemetryapi
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
@@ -22,32 +24,180 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/golang-collections/go-datastructures/queue"
+	"github.com/Workiva/go-datastructures/queue"
 	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/utility"
 	"github.com/tiqqe/go-logger"
 )
 
 const (
-	initialQueueSize    = 5
-	minBatchSize        = 10
+	initialQueueSize = 5
+	// maxBatchSize is the most events taken from the queue per dispatched batch
+	maxBatchSize = 10
+	// dispatchPollTimeout bounds how long the dispatcher waits for events at once
+	dispatchPollTimeout = 100 * time.Millisecond
 	defaultListenerPort = "4323"
+	defaultMaxQueueSize = 10000
+	// defaultDeadlineMarginMs leaves time to report back to the Extensions API before the sandbox is frozen
+	defaultDeadlineMarginMs = 200
+	// defaultPartialFlushThresholdMs leaves time to export the telemetry of an invocation about to time out
+	defaultPartialFlushThresholdMs = 500
+	// deliveryPollInterval is how often WaitDelivered checks whether the events were delivered
+	deliveryPollInterval = 5 * time.Millisecond
+	// maxTraceContexts bounds the INVOKE trace contexts kept for events arriving after their invocation
+	maxTraceContexts = 16
 )
 
+// DropPolicy decides which events are discarded once the listener queue is full.
+type DropPolicy string
+
+const (
+	// DropOldest discards the oldest queued events to make room for incoming ones.
+	DropOldest DropPolicy = "oldest"
+	// DropNewest discards incoming events while the queue is full.
+	DropNewest DropPolicy = "newest"
+	// Reject refuses the batches that don't fit in the queue with a retryable
+	// status, leaving it to the Telemetry API to buffer and redeliver them.
+	Reject DropPolicy = "reject"
+)
+
+// rejectRetryAfter is the Retry-After header of rejected batches, in seconds
+const rejectRetryAfter = "1"
+
+// ListenerConfig holds the settings of a Listener.
+type ListenerConfig struct {
+	// MaxQueueSize is the maximum number of events held in the queue. Zero or less means unbounded.
+	MaxQueueSize int
+	// DropPolicy decides which events are discarded when the queue is full.
+	DropPolicy DropPolicy
+	// DeadlineMargin is how long before the invocation deadline WaitDelivered gives up.
+	DeadlineMargin time.Duration
+	// PartialFlushThreshold is how long before the invocation deadline Wait
+	// gives up, leaving WaitDelivered the time until DeadlineMargin to deliver the
+	// events received so far. It is ignored when not above DeadlineMargin.
+	PartialFlushThreshold time.Duration
+	// DumpTarget is the file or s3://bucket/prefix raw payloads are written to. Empty disables dumping.
+	DumpTarget string
+	// ForwardURL is a local HTTP destination raw payloads are mirrored to. Empty disables forwarding.
+	ForwardURL string
+	// ExcludeTypes are the event types dropped before they are queued.
+	ExcludeTypes []string
+	// ExcludePattern drops the function and extension log lines it matches before they are queued.
+	ExcludePattern *regexp.Regexp
+	// DeferDispatch keeps the received events queued until ResumeDispatch is
+	// called, e.g. while the consumers are not created yet.
+	DeferDispatch bool
+}
+
+// ListenerConfigFromEnv returns the listener configuration read from the
+// OTEL_LAMBDA_TELEMETRY_* and OTEL_LAMBDA_WAIT_* environment variables
+// documented in the README.
+func ListenerConfigFromEnv() ListenerConfig {
+	policy := DropPolicy(utility.GetEnvString("OTEL_LAMBDA_TELEMETRY_QUEUE_DROP_POLICY", string(DropOldest)))
+	if policy != DropOldest && policy != DropNewest && policy != Reject {
+		utility.LogError(nil, "ListenerConfigFromEnv", "Unknown queue drop policy, using default", utility.KeyValue{K: "policy", V: policy})
+		policy = DropOldest
+	}
+
+	return ListenerConfig{
+		MaxQueueSize:          utility.GetEnvInt("OTEL_LAMBDA_TELEMETRY_QUEUE_SIZE", defaultMaxQueueSize),
+		DropPolicy:            policy,
+		DeadlineMargin:        time.Duration(utility.GetEnvInt("OTEL_LAMBDA_WAIT_DEADLINE_MARGIN_MS", defaultDeadlineMarginMs)) * time.Millisecond,
+		PartialFlushThreshold: time.Duration(utility.GetEnvInt("OTEL_LAMBDA_WAIT_PARTIAL_FLUSH_THRESHOLD_MS", defaultPartialFlushThresholdMs)) * time.Millisecond,
+		DumpTarget:            utility.GetEnvString("OTEL_LAMBDA_TELEMETRY_DUMP", ""),
+		ForwardURL:            utility.GetEnvString("OTEL_LAMBDA_TELEMETRY_FORWARD_URL", ""),
+		ExcludeTypes:          excludedTypesFromEnv(),
+		ExcludePattern:        excludedPatternFromEnv(),
+	}
+}
+
 // Listener is used to listen to the Telemetry API
 type Listener struct {
 	httpServer *http.Server
-	// queue is a synchronous queue and is used to put the received log events to be dispatched later
+	config     ListenerConfig
+	// queue is a synchronous queue and is used to put the received log events to be dispatched to the consumers
 	queue *queue.Queue
+	// queueMu serializes the size check and insertion of concurrent batches
+	queueMu sync.Mutex
+	// lastReceived is when events were last queued, guarded by queueMu
+	lastReceived time.Time
+	// pending counts the queued events and the events being delivered, for WaitDelivered
+	pending int64
+	// dumper writes the raw payloads in the background when a dump target is configured
+	dumper *asyncDumper
+	// forwarder mirrors the raw payloads in the background when a forward URL is configured
+	forwarder *asyncDumper
+	// waiter is notified of the dispatched platform.runtimeDone events
+	waiter *runtimeDoneWaiter
+
+	// dispatchDone is closed once the dispatching goroutine exits, nil until it is started
+	dispatchDone chan struct{}
+	dispatchOnce sync.Once
+	// received is closed once the first events are queued
+	received     chan struct{}
+	receivedOnce sync.Once
+
+	consumers   []Consumer
+	consumersMu sync.RWMutex
+	// deliverMu serializes the delivery of batches by the dispatcher and on shutdown
+	deliverMu sync.Mutex
+	// invocationDone is when the runtime completed the invocation Wait last returned for
+	invocationDone time.Time
+	// dedup drops redelivered invocation events before they reach the consumers
+	dedup *deduplicator
+	// filter drops the excluded events before they are queued, nil if none are
+	filter *eventFilter
+
+	// functionARN is the last invoked function ARN, and function its parsed form
+	functionARN string
+	function    *FunctionARN
+	functionMu  sync.RWMutex
+
+	// traceContexts are the trace contexts of the last INVOKE events by
+	// request ID, and traceRequestIDs their request IDs from the oldest
+	traceContexts   map[string]TraceContext
+	traceRequestIDs []string
+	traceMu         sync.RWMutex
 }
 
 // NewListener returns a Lambda Telemetry API listener.
-func NewListener() *Listener {
-	return &Listener{
+func NewListener(config ListenerConfig) *Listener {
+	RegisterMetricViews()
+
+	waiter := newRuntimeDoneWaiter()
+
+	listener := &Listener{
 		httpServer: nil,
+		config:     config,
 		queue:      queue.New(initialQueueSize),
+		received:   make(chan struct{}),
+		waiter:     waiter,
+		dedup:      newDeduplicator(),
+		filter:     newEventFilter(config.ExcludeTypes, config.ExcludePattern),
+		consumers:  []Consumer{waiter, ConsumerFunc(platformMetricsConsumer)},
+	}
+
+	if config.DumpTarget != "" {
+		dumper, err := NewDumper(context.Background(), config.DumpTarget)
+		if err != nil {
+			utility.LogError(err, "NewListener", "Cannot create raw telemetry dumper, dumping is disabled", utility.KeyValue{K: "target", V: config.DumpTarget})
+		}
+
+		if dumper != nil {
+			listener.dumper = newAsyncDumper(dumper, config.DumpTarget)
+		}
+	}
+
+	if config.ForwardURL != "" {
+		listener.forwarder = newAsyncDumper(newHTTPForwarder(config.ForwardURL), config.ForwardURL)
 	}
+
+	return listener
 }
 
 func listenOnAddress() string {
@@ -64,12 +214,17 @@ func listenOnAddress() string {
 }
 
 // Start the server in a goroutine where the log events will be sent. It handles incoming
-// requests from the Telemetry API.
+// requests from the Telemetry API, which are dispatched to the consumers from another goroutine.
 func (s *Listener) Start() (string, error) {
 	address := listenOnAddress()
 
-	s.httpServer = &http.Server{Addr: address}
-	http.HandleFunc("/", s.httpHandler)
+	if !s.config.DeferDispatch {
+		s.ResumeDispatch()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.httpHandler)
+	s.httpServer = &http.Server{Addr: address, Handler: mux}
 
 	go func() {
 		// Listen and handle incoming requests
@@ -94,27 +249,176 @@ func (s *Listener) Start() (string, error) {
 // Otherwise, logging here will cause Telemetry API to send new logs for
 // the printed lines which may create an infinite loop.
 func (s *Listener) httpHandler(w http.ResponseWriter, r *http.Request) {
-	body, err := io.ReadAll(r.Body)
+	reader, err := bodyReader(r)
 	if err != nil {
-		utility.LogError(err, "httpHandler", "Failed reading body")
+		utility.LogError(err, "httpHandler", "Failed decoding body", utility.KeyValue{K: "content_encoding", V: r.Header.Get("Content-Encoding")})
+		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	defer reader.Close()
+
+	if s.dumper != nil || s.forwarder != nil {
+		reader = s.mirror(reader)
+	}
 
 	// Parse and put the log messages into the queue
-	var slice []Event
-	_ = json.Unmarshal(body, &slice)
+	slice, err := decodeEvents(reader, nil)
+	if err != nil {
+		utility.LogError(err, "httpHandler", "Failed decoding events", utility.KeyValue{K: "decoded", V: len(slice)})
+	}
+
+	recordBatchSize(len(slice))
+	if s.filter != nil {
+		slice = s.filter.filter(slice)
+	}
+
+	if !s.enqueue(slice) {
+		w.Header().Set("Retry-After", rejectRetryAfter)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+// decodeEvents streams the JSON array of events read from r into events.
+// Events whose fields do not match the Event type are kept with the
+// mismatching fields left empty, mirroring json.Unmarshal.
+func decodeEvents(r io.Reader, events []Event) ([]Event, error) {
+	decoder := json.NewDecoder(r)
+
+	token, err := decoder.Token()
+	if err != nil {
+		return events, err
+	}
+
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return events, fmt.Errorf("expected a JSON array of events, got %v", token)
+	}
+
+	for decoder.More() {
+		var event Event
+
+		err := decoder.Decode(&event)
+		if err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				return events, err
+			}
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// mirror hands the raw payload read from reader to the dumper and the
+// forwarder and returns a reader over the same payload.
+func (s *Listener) mirror(reader io.ReadCloser) io.ReadCloser {
+	payload, err := io.ReadAll(reader)
+	if err != nil {
+		utility.LogError(err, "httpHandler", "Failed reading body")
+	}
+
+	if s.dumper != nil {
+		s.dumper.enqueue(payload)
+	}
+
+	if s.forwarder != nil {
+		s.forwarder.enqueue(payload)
+	}
+
+	return io.NopCloser(bytes.NewReader(payload))
+}
+
+// bodyReader returns a reader of the request body, decompressing it
+// according to its Content-Encoding header.
+func bodyReader(r *http.Request) (io.ReadCloser, error) {
+	switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+		return r.Body, nil
+
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r.Body)
+
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+}
+
+// enqueue puts the events into the queue, discarding events according to
+// the configured drop policy when the queue would grow past its maximum size.
+// It reports false when the whole batch was rejected by the Reject policy.
+func (s *Listener) enqueue(events []Event) bool {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	dropped := 0
+
+	if max := s.config.MaxQueueSize; max > 0 {
+		free := max - int(s.queue.Len())
+		if free < 0 {
+			free = 0
+		}
+
+		switch s.config.DropPolicy {
+		case Reject:
+			if len(events) > free {
+				recordEventsDropped(Reject, len(events))
+				return false
+			}
+
+		case DropNewest:
+			if len(events) > free {
+				dropped = len(events) - free
+				events = events[:free]
+			}
+
+		default:
+			if len(events) > max {
+				dropped = len(events) - max
+				events = events[dropped:]
+			}
 
-	for _, el := range slice {
-		s.queue.Put(el)
+			if overflow := len(events) - free; overflow > 0 {
+				taken := 0
+				removed, _ := s.queue.TakeUntil(func(interface{}) bool {
+					taken++
+					return taken <= overflow
+				})
+				dropped += len(removed)
+				atomic.AddInt64(&s.pending, -int64(len(removed)))
+			}
+		}
+	}
+
+	if dropped > 0 {
+		recordEventsDropped(s.config.DropPolicy, dropped)
+	}
+
+	received := time.Now()
+	s.lastReceived = received
+	items := make([]interface{}, len(events))
+	for i, el := range events {
+		el.received = received
+		items[i] = el
 	}
 
-	slice = nil
+	atomic.AddInt64(&s.pending, int64(len(items)))
+	_ = s.queue.Put(items...)
+	recordQueueSize(s.queue.Len())
+
+	if len(items) > 0 {
+		s.receivedOnce.Do(func() { close(s.received) })
+	}
+
+	return true
 }
 
-// Shutdown the HTTP server listening for logs
+// Shutdown the HTTP server listening for logs. The events still queued are
+// delivered to the consumers before Shutdown returns.
 func (s *Listener) Shutdown() {
 	if s.httpServer != nil {
-		ctx, _ := context.WithTimeout(context.Background(), 1*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
 
 		err := s.httpServer.Shutdown(ctx)
 		if err != nil {
@@ -124,42 +428,201 @@ func (s *Listener) Shutdown() {
 			s.httpServer = nil
 		}
 	}
+
+	// Stop the dispatching goroutine, then deliver the events it left in the
+	// queue after the batch it may still be delivering. A deferred dispatch
+	// is never started past this point.
+	s.dispatchOnce.Do(func() {})
+	items := s.queue.Dispose()
+	if s.dispatchDone != nil {
+		<-s.dispatchDone
+	}
+
+	if len(items) > 0 {
+		s.deliver(items)
+	}
+
+	if s.dumper != nil {
+		s.dumper.close()
+	}
+
+	if s.forwarder != nil {
+		s.forwarder.close()
+	}
 }
 
+// Wait blocks until the platform.runtimeDone event of the given request has
+// been dispatched. When ctx carries the invocation deadline, Wait gives up
+// PartialFlushThreshold before it, or DeadlineMargin if later, so the
+// extension never holds the sandbox past the function deadline. A function
+// about to time out then still leaves WaitDelivered the time to deliver the events
+// received so far. Without a deadline, Wait waits until ctx is done.
 func (s *Listener) Wait(ctx context.Context, requestId string) error {
-	for {
+	margin := s.config.DeadlineMargin
+	if s.config.PartialFlushThreshold > margin {
+		margin = s.config.PartialFlushThreshold
+	}
+
+	ctx, cancel := withDeadlineMargin(ctx, margin)
+	defer cancel()
+
+	event, err := s.waiter.wait(ctx, requestId)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for %s of request %s: %w", PLATFORM_RUNTIME_DONE, requestId, err)
+	}
+
+	if err == nil {
+		s.invocationDone = event.received
+		if t, err := time.Parse(time.RFC3339Nano, event.Time); err == nil {
+			s.invocationDone = t
+		}
+	}
+
+	return err
+}
+
+// withDeadlineMargin moves the deadline of ctx, if any, margin earlier.
+func withDeadlineMargin(ctx context.Context, margin time.Duration) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithDeadline(ctx, deadline.Add(-margin))
+}
+
+// RecordOverhead records the time elapsed since the function runtime completed
+// the invocation Wait last returned for, i.e. the latency the extension adds
+// to the invocation. Call it right before asking the Extensions API for the
+// next event, from the goroutine calling Wait.
+func (s *Listener) RecordOverhead() {
+	if s.invocationDone.IsZero() {
+		return
+	}
+
+	recordExtensionOverhead(time.Since(s.invocationDone))
+	s.invocationDone = time.Time{}
+}
+
+// WaitDelivered blocks until the events received so far have been handed to
+// the consumers, i.e. to the first component of their pipelines. It doesn't
+// flush the pipelines: the data held by batch or decouple processors, or by
+// the queues of the exporters, is exported later. WaitDelivered gives up
+// DeadlineMargin before the deadline of ctx.
+func (s *Listener) WaitDelivered(ctx context.Context) error {
+	ctx, cancel := withDeadlineMargin(ctx, s.config.DeadlineMargin)
+	defer cancel()
+	defer recordFlushDuration(time.Now())
+
+	for atomic.LoadInt64(&s.pending) > 0 {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 
-		default:
-			items, err := s.queue.Get(minBatchSize)
-			if err != nil {
-				return fmt.Errorf("unable to get telemetry events from queue: %w", err)
-			}
+		case <-time.After(deliveryPollInterval):
+		}
+	}
 
-			for _, item := range items {
-				i, ok := item.(Event)
-				if !ok {
-					logger.WarnStringf("Non-Event found in queue. Item: %v", item)
-					continue
-				}
+	return nil
+}
 
-				if i.Type == PLATFORM_LOGS_DROPPED {
-					err := errors.New("failed to process event")
-					utility.LogError(err, "TelemetryAPIWait", "Can't process one or more events", utility.KeyValue{K: "event", V: i})
+// WaitIdle blocks until no events have been received for the quiet period
+// since the call, e.g. to let the Telemetry API deliver its last batches
+// before shutting down, or until ctx is done.
+func (s *Listener) WaitIdle(ctx context.Context, quiet time.Duration) error {
+	since := time.Now()
 
-					continue
-				}
+	for {
+		s.queueMu.Lock()
+		if s.lastReceived.After(since) {
+			since = s.lastReceived
+		}
+		s.queueMu.Unlock()
 
-				if i.Type != "platform.runtimeDone" {
-					continue
-				}
+		idle := time.Since(since)
+		if idle >= quiet {
+			return nil
+		}
 
-				if i.Record["requestId"] == requestId {
-					return nil
-				}
-			}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-time.After(quiet - idle):
 		}
 	}
 }
+
+// Received returns a channel closed once the first events are queued.
+func (s *Listener) Received() <-chan struct{} {
+	return s.received
+}
+
+// QueueSize returns the number of events waiting to be dispatched to the consumers.
+func (s *Listener) QueueSize() int64 {
+	return s.queue.Len()
+}
+
+// SetInvokedFunctionARN records the InvokedFunctionArn of the last INVOKE
+// event, which describes the function the consumers receive telemetry of.
+func (s *Listener) SetInvokedFunctionARN(arn string) {
+	s.functionMu.Lock()
+	defer s.functionMu.Unlock()
+
+	if arn == s.functionARN {
+		return
+	}
+
+	function, err := ParseFunctionARN(arn)
+	if err != nil {
+		utility.LogError(err, "SetInvokedFunctionARN", "Cannot parse the invoked function ARN")
+	}
+
+	s.functionARN = arn
+	s.function = function
+}
+
+// FunctionARN returns the parsed ARN of the last invoked function, or nil
+// before the first invocation.
+func (s *Listener) FunctionARN() *FunctionARN {
+	s.functionMu.RLock()
+	defer s.functionMu.RUnlock()
+
+	return s.function
+}
+
+// SetTraceContext records the trace context of the INVOKE event of requestID,
+// e.g. the X-Ray trace header, for the consumers to parent the telemetry of
+// its platform events. Only the last maxTraceContexts invocations are kept.
+func (s *Listener) SetTraceContext(requestID string, traceContext TraceContext) {
+	s.traceMu.Lock()
+	defer s.traceMu.Unlock()
+
+	if s.traceContexts == nil {
+		s.traceContexts = make(map[string]TraceContext)
+	}
+
+	if _, ok := s.traceContexts[requestID]; !ok {
+		s.traceRequestIDs = append(s.traceRequestIDs, requestID)
+	}
+	s.traceContexts[requestID] = traceContext
+
+	for len(s.traceRequestIDs) > maxTraceContexts {
+		delete(s.traceContexts, s.traceRequestIDs[0])
+		s.traceRequestIDs = s.traceRequestIDs[1:]
+	}
+}
+
+// TraceContexts returns a copy of the trace contexts recorded by
+// SetTraceContext, by request ID.
+func (s *Listener) TraceContexts() map[string]TraceContext {
+	s.traceMu.RLock()
+	defer s.traceMu.RUnlock()
+
+	traceContexts := make(map[string]TraceContext, len(s.traceContexts))
+	for requestID, traceContext := range s.traceContexts {
+		traceContexts[requestID] = traceContext
+	}
+
+	return traceContexts
+}
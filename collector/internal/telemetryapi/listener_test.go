@@ -0,0 +1,584 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func events(types ...string) []Event {
+	out := make([]Event, 0, len(types))
+	for _, t := range types {
+		out = append(out, Event{Type: t})
+	}
+
+	return out
+}
+
+func queuedTypes(t *testing.T, l *Listener) []string {
+	items, err := l.queue.TakeUntil(func(interface{}) bool { return true })
+	assert.NoError(t, err)
+
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		out = append(out, item.(Event).Type)
+	}
+
+	return out
+}
+
+func TestEnqueue(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		config   ListenerConfig
+		batches  [][]Event
+		expected []string
+	}{
+		{
+			name:     "unbounded",
+			config:   ListenerConfig{},
+			batches:  [][]Event{events("a", "b"), events("c", "d")},
+			expected: []string{"a", "b", "c", "d"},
+		},
+		{
+			name:     "drop oldest",
+			config:   ListenerConfig{MaxQueueSize: 3, DropPolicy: DropOldest},
+			batches:  [][]Event{events("a", "b"), events("c", "d")},
+			expected: []string{"b", "c", "d"},
+		},
+		{
+			name:     "drop oldest with oversized batch",
+			config:   ListenerConfig{MaxQueueSize: 2, DropPolicy: DropOldest},
+			batches:  [][]Event{events("a"), events("b", "c", "d")},
+			expected: []string{"c", "d"},
+		},
+		{
+			name:     "drop newest",
+			config:   ListenerConfig{MaxQueueSize: 3, DropPolicy: DropNewest},
+			batches:  [][]Event{events("a", "b"), events("c", "d")},
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name:     "drop newest when full",
+			config:   ListenerConfig{MaxQueueSize: 2, DropPolicy: DropNewest},
+			batches:  [][]Event{events("a", "b"), events("c")},
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "reject",
+			config:   ListenerConfig{MaxQueueSize: 3, DropPolicy: Reject},
+			batches:  [][]Event{events("a", "b"), events("c", "d"), events("e")},
+			expected: []string{"a", "b", "e"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			l := NewListener(tc.config)
+			for _, batch := range tc.batches {
+				l.enqueue(batch)
+			}
+
+			assert.Equal(t, tc.expected, queuedTypes(t, l))
+		})
+	}
+}
+
+func TestHTTPHandlerReject(t *testing.T) {
+	l := NewListener(ListenerConfig{MaxQueueSize: 1, DropPolicy: Reject})
+
+	payload := `[{"type":"function","record":"a"}]`
+	for _, expected := range []int{http.StatusOK, http.StatusServiceUnavailable} {
+		recorder := httptest.NewRecorder()
+		l.httpHandler(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload)))
+
+		assert.Equal(t, expected, recorder.Code)
+	}
+
+	assert.Equal(t, []string{"function"}, queuedTypes(t, l))
+}
+
+func TestWait(t *testing.T) {
+	l := NewListener(ListenerConfig{DeadlineMargin: 4900 * time.Millisecond})
+	l.ResumeDispatch()
+	defer l.queue.Dispose()
+
+	l.enqueue([]Event{{Type: PLATFORM_RUNTIME_DONE, Record: json.RawMessage(`{"requestId":"1","status":"success"}`)}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	assert.NoError(t, l.Wait(ctx, "1"))
+
+	// Wait gives up 100ms after it starts, well before the deadline itself
+	start := time.Now()
+	ctx, cancel = context.WithDeadline(context.Background(), start.Add(5*time.Second))
+	defer cancel()
+	err := l.Wait(ctx, "2")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestHTTPHandlerContentEncoding(t *testing.T) {
+	payload := []byte(`[{"time":"2022-10-12T00:00:00.000Z","type":"platform.runtimeDone","record":{"requestId":"1"}}]`)
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, err := gz.Write(payload)
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+
+	for _, tc := range []struct {
+		name     string
+		encoding string
+		body     []byte
+		status   int
+		expected []string
+	}{
+		{name: "plain", encoding: "", body: payload, status: http.StatusOK, expected: []string{PLATFORM_RUNTIME_DONE}},
+		{name: "gzip", encoding: "gzip", body: compressed.Bytes(), status: http.StatusOK, expected: []string{PLATFORM_RUNTIME_DONE}},
+		{name: "invalid gzip", encoding: "gzip", body: payload, status: http.StatusBadRequest, expected: []string{}},
+		{name: "unsupported", encoding: "br", body: payload, status: http.StatusBadRequest, expected: []string{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			l := NewListener(ListenerConfig{})
+
+			request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tc.body))
+			request.Header.Set("Content-Encoding", tc.encoding)
+			recorder := httptest.NewRecorder()
+
+			l.httpHandler(recorder, request)
+
+			assert.Equal(t, tc.status, recorder.Code)
+			assert.Equal(t, tc.expected, queuedTypes(t, l))
+		})
+	}
+}
+
+func TestDecodeEvents(t *testing.T) {
+	payload := `[
+		{"time":"2022-10-12T00:00:00.000Z","type":"function","record":"a plain text log line"},
+		{"time":"2022-10-12T00:00:01.000Z","type":"platform.runtimeDone","record":{"requestId":"1","status":"success"}}
+	]`
+
+	decoded, err := decodeEvents(strings.NewReader(payload), nil)
+	assert.NoError(t, err)
+	assert.Len(t, decoded, 2)
+	assert.Equal(t, "function", decoded[0].Type)
+	assert.Equal(t, PLATFORM_RUNTIME_DONE, decoded[1].Type)
+	record, err := decoded[1].ParseRecord()
+	assert.NoError(t, err)
+	assert.Equal(t, "1", record.(*PlatformRuntimeDone).RequestID)
+
+	_, err = decodeEvents(strings.NewReader(`{"type":"platform.start"}`), nil)
+	assert.Error(t, err)
+}
+
+func TestHTTPHandlerDump(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "dump.jsonl")
+	l := NewListener(ListenerConfig{DumpTarget: target})
+
+	payload := `[{"time":"2022-10-12T00:00:00.000Z","type":"platform.start","record":{"requestId":"1"}}]`
+	for i := 0; i < 2; i++ {
+		l.httpHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload)))
+	}
+
+	assert.Equal(t, []string{"platform.start", "platform.start"}, queuedTypes(t, l))
+
+	l.dumper.close()
+	dumped, err := os.ReadFile(target)
+	assert.NoError(t, err)
+	assert.Equal(t, payload+"\n"+payload+"\n", string(dumped))
+}
+
+func TestHTTPHandlerForward(t *testing.T) {
+	forwarded := make(chan string, 2)
+	destination := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		forwarded <- string(body)
+	}))
+	defer destination.Close()
+
+	l := NewListener(ListenerConfig{ForwardURL: destination.URL})
+
+	var compressed bytes.Buffer
+	payload := `[{"time":"2022-10-12T00:00:00.000Z","type":"platform.start","record":{"requestId":"1"}}]`
+	writer := gzip.NewWriter(&compressed)
+	_, _ = writer.Write([]byte(payload))
+	require.NoError(t, writer.Close())
+
+	request := httptest.NewRequest(http.MethodPost, "/", &compressed)
+	request.Header.Set("Content-Encoding", "gzip")
+	l.httpHandler(httptest.NewRecorder(), request)
+
+	assert.Equal(t, []string{"platform.start"}, queuedTypes(t, l))
+
+	// Payloads are forwarded decompressed
+	l.forwarder.close()
+	assert.Equal(t, payload, <-forwarded)
+	assert.Equal(t, 0, l.forwarder.failed)
+}
+
+type blockingDumper struct {
+	release chan struct{}
+}
+
+func (d *blockingDumper) Dump(context.Context, []byte) error {
+	<-d.release
+	return errors.New("access denied")
+}
+
+func TestHTTPHandlerDumpDoesNotBlock(t *testing.T) {
+	dumper := &blockingDumper{release: make(chan struct{})}
+	l := NewListener(ListenerConfig{})
+	l.dumper = newAsyncDumper(dumper, "s3://bucket")
+
+	payload := `[{"time":"2022-10-12T00:00:00.000Z","type":"function","record":"line"}]`
+	for i := 0; i < 2*dumpQueueSize; i++ {
+		recorder := httptest.NewRecorder()
+		l.httpHandler(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload)))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+
+	assert.Len(t, queuedTypes(t, l), 2*dumpQueueSize)
+
+	close(dumper.release)
+	l.dumper.close()
+
+	// Only the first failure is logged, the following ones are counted
+	assert.Greater(t, l.dumper.failed, 0)
+	assert.Less(t, l.dumper.failed, 2*dumpQueueSize)
+}
+
+func TestDispatchFanOut(t *testing.T) {
+	l := NewListener(ListenerConfig{})
+	l.ResumeDispatch()
+	defer l.queue.Dispose()
+
+	received := make(chan []string, 4)
+	for i := 0; i < 2; i++ {
+		l.AddConsumer(ConsumerFunc(func(_ context.Context, events []Event) {
+			var types []string
+			for _, event := range events {
+				types = append(types, event.Type)
+			}
+			received <- types
+		}))
+	}
+
+	l.enqueue(events("platform.start", "function"))
+	l.enqueue([]Event{{Type: PLATFORM_RUNTIME_DONE, Record: json.RawMessage(`{"requestId":"1","status":"success"}`)}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, l.Wait(ctx, "1"))
+
+	var all []string
+	for len(all) < 6 {
+		select {
+		case types := <-received:
+			all = append(all, types...)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for dispatched events")
+		}
+	}
+
+	assert.ElementsMatch(t, []string{"platform.start", "function", PLATFORM_RUNTIME_DONE, "platform.start", "function", PLATFORM_RUNTIME_DONE}, all)
+}
+
+// typesConsumer is an EventTypesConsumer needing the given event types.
+type typesConsumer struct {
+	eventTypes []EventType
+}
+
+func (*typesConsumer) ConsumeEvents(context.Context, []Event) {}
+
+func (c *typesConsumer) EventTypes() []EventType {
+	return c.eventTypes
+}
+
+func TestConsumerEventTypes(t *testing.T) {
+	l := NewListener(ListenerConfig{})
+
+	// Consumers not stating their needs make do with the subscribed types
+	l.AddConsumer(ConsumerFunc(func(context.Context, []Event) {}))
+	l.AddConsumer(&typesConsumer{})
+	assert.Nil(t, l.ConsumerEventTypes())
+
+	extension := &typesConsumer{eventTypes: []EventType{Extension}}
+	l.AddConsumer(extension)
+	l.AddConsumer(&typesConsumer{eventTypes: []EventType{Function, Platform}})
+	assert.Equal(t, []EventType{Platform, Function, Extension}, l.ConsumerEventTypes())
+
+	l.RemoveConsumer(extension)
+	assert.Equal(t, []EventType{Platform, Function}, l.ConsumerEventTypes())
+}
+
+func TestDispatchSingleEvent(t *testing.T) {
+	l := NewListener(ListenerConfig{})
+	l.ResumeDispatch()
+	defer l.queue.Dispose()
+
+	received := make(chan []Event, 1)
+	l.AddConsumer(ConsumerFunc(func(_ context.Context, events []Event) {
+		received <- events
+	}))
+
+	// Let the dispatcher time out polling the empty queue at least once
+	time.Sleep(2 * dispatchPollTimeout)
+	l.enqueue(events("function"))
+
+	select {
+	case batch := <-received:
+		assert.Len(t, batch, 1)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the event to be dispatched")
+	}
+}
+
+func TestDeferDispatch(t *testing.T) {
+	l := NewListener(ListenerConfig{DeferDispatch: true})
+	defer l.queue.Dispose()
+
+	received := make(chan []Event, 1)
+	l.AddConsumer(ConsumerFunc(func(_ context.Context, events []Event) {
+		received <- events
+	}))
+
+	select {
+	case <-l.Received():
+		t.Fatal("Received is closed before any event is queued")
+	default:
+	}
+
+	l.enqueue(events("function"))
+	<-l.Received()
+
+	select {
+	case <-received:
+		t.Fatal("events dispatched before ResumeDispatch")
+	case <-time.After(2 * dispatchPollTimeout):
+	}
+
+	l.ResumeDispatch()
+	l.ResumeDispatch()
+
+	select {
+	case batch := <-received:
+		assert.Len(t, batch, 1)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the event to be dispatched")
+	}
+}
+
+func TestWaitDelivered(t *testing.T) {
+	l := NewListener(ListenerConfig{MaxQueueSize: 2})
+	defer l.queue.Dispose()
+
+	// Nothing received, nothing to wait for
+	assert.NoError(t, l.WaitDelivered(context.Background()))
+
+	release := make(chan struct{})
+	var delivered []string
+	l.AddConsumer(ConsumerFunc(func(_ context.Context, events []Event) {
+		<-release
+		for _, event := range events {
+			delivered = append(delivered, event.Type)
+		}
+	}))
+
+	// The events dropped to make room are not waited for
+	l.enqueue(events("a", "b", "c"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, l.WaitDelivered(ctx), context.DeadlineExceeded)
+
+	l.ResumeDispatch()
+	close(release)
+	assert.NoError(t, l.WaitDelivered(context.Background()))
+	assert.Equal(t, []string{"b", "c"}, delivered)
+}
+
+func TestDispatchConsumerPanic(t *testing.T) {
+	l := NewListener(ListenerConfig{})
+	l.ResumeDispatch()
+	defer l.queue.Dispose()
+
+	var delivered []string
+	l.AddConsumer(ConsumerFunc(func(_ context.Context, events []Event) {
+		if events[0].Type == "a" {
+			panic("consumer bug")
+		}
+	}))
+	l.AddConsumer(ConsumerFunc(func(_ context.Context, events []Event) {
+		for _, event := range events {
+			delivered = append(delivered, event.Type)
+		}
+	}))
+
+	// The batch is lost for the panicking consumer only, and dispatching goes on
+	l.enqueue(events("a"))
+	assert.NoError(t, l.WaitDelivered(context.Background()))
+	l.enqueue(events("b"))
+	assert.NoError(t, l.WaitDelivered(context.Background()))
+	assert.Equal(t, []string{"a", "b"}, delivered)
+}
+
+func TestWaitPartialFlush(t *testing.T) {
+	l := NewListener(ListenerConfig{DeadlineMargin: 100 * time.Millisecond, PartialFlushThreshold: 400 * time.Millisecond})
+	l.ResumeDispatch()
+	defer l.queue.Dispose()
+
+	release := make(chan struct{})
+	var delivered []string
+	l.AddConsumer(ConsumerFunc(func(_ context.Context, events []Event) {
+		<-release
+		for _, event := range events {
+			delivered = append(delivered, event.Type)
+		}
+	}))
+
+	l.enqueue(events("a"))
+
+	// Wait gives up 400ms before the deadline instead of 100ms
+	start := time.Now()
+	ctx, cancel := context.WithDeadline(context.Background(), start.Add(600*time.Millisecond))
+	defer cancel()
+	assert.ErrorIs(t, l.Wait(ctx, "1"), context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 400*time.Millisecond)
+
+	// Leaving WaitDelivered the time to deliver the events received so far
+	close(release)
+	assert.NoError(t, l.WaitDelivered(ctx))
+	assert.Equal(t, []string{"a"}, delivered)
+}
+
+func TestWaitIdle(t *testing.T) {
+	l := NewListener(ListenerConfig{})
+	defer l.queue.Dispose()
+
+	const quiet = 50 * time.Millisecond
+
+	// Without events, WaitIdle returns after the quiet period
+	start := time.Now()
+	assert.NoError(t, l.WaitIdle(context.Background(), quiet))
+	assert.GreaterOrEqual(t, time.Since(start), quiet)
+
+	// Events received meanwhile extend the wait
+	go func() {
+		time.Sleep(quiet / 2)
+		l.enqueue(events("function"))
+	}()
+
+	start = time.Now()
+	assert.NoError(t, l.WaitIdle(context.Background(), quiet))
+	assert.GreaterOrEqual(t, time.Since(start), quiet*3/2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, l.WaitIdle(ctx, quiet), context.Canceled)
+}
+
+func TestShutdownDrainsQueue(t *testing.T) {
+	l := NewListener(ListenerConfig{})
+
+	var delivered []string
+	l.AddConsumer(ConsumerFunc(func(_ context.Context, events []Event) {
+		for _, event := range events {
+			delivered = append(delivered, event.Type)
+		}
+	}))
+
+	l.enqueue(events("a", "b"))
+	l.enqueue(events("c"))
+
+	l.Shutdown()
+
+	assert.Equal(t, []string{"a", "b", "c"}, delivered)
+}
+
+func TestShutdownWaitsForDispatch(t *testing.T) {
+	l := NewListener(ListenerConfig{})
+
+	var delivered []string
+	dispatching := make(chan struct{})
+	release := make(chan struct{})
+	l.AddConsumer(ConsumerFunc(func(_ context.Context, events []Event) {
+		if len(delivered) == 0 {
+			close(dispatching)
+			<-release
+		}
+
+		for _, event := range events {
+			delivered = append(delivered, event.Type)
+		}
+	}))
+	l.ResumeDispatch()
+
+	l.enqueue(events("a"))
+	<-dispatching
+	l.enqueue(events("b", "c"))
+
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		l.Shutdown()
+	}()
+
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown returned while a batch was being delivered")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-shutdown
+
+	assert.Equal(t, []string{"a", "b", "c"}, delivered)
+}
+
+func TestSetTraceContext(t *testing.T) {
+	listener := NewListener(ListenerConfig{})
+	assert.Empty(t, listener.TraceContexts())
+
+	for i := 0; i <= maxTraceContexts; i++ {
+		listener.SetTraceContext(strconv.Itoa(i), TraceContext{Type: amznTraceIDType, Value: "Root=" + strconv.Itoa(i)})
+	}
+
+	traceContexts := listener.TraceContexts()
+	assert.Len(t, traceContexts, maxTraceContexts)
+	assert.NotContains(t, traceContexts, "0")
+	assert.Equal(t, TraceContext{Type: amznTraceIDType, Value: "Root=1"}, traceContexts["1"])
+
+	// The returned map is a copy
+	delete(traceContexts, "1")
+	assert.Contains(t, listener.TraceContexts(), "1")
+}
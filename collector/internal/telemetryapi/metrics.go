@@ -0,0 +1,367 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapi
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/multierr"
+)
+
+var (
+	tagDropPolicy, _ = tag.NewKey("policy")
+	tagReason, _     = tag.NewKey("reason")
+	tagStatus, _     = tag.NewKey("status")
+	tagEventType, _  = tag.NewKey("type")
+	tagCall, _       = tag.NewKey("call")
+
+	mEventsDropped          = stats.Int64("telemetryapi_listener_events_dropped", "Number of Telemetry API events dropped because the listener queue was full", stats.UnitDimensionless)
+	mPlatformDroppedRecords = stats.Int64("telemetryapi_platform_dropped_records", "Number of records the Lambda platform reported as dropped in platform.logsDropped events", stats.UnitDimensionless)
+	mPlatformDroppedBytes   = stats.Int64("telemetryapi_platform_dropped_bytes", "Number of bytes the Lambda platform reported as dropped in platform.logsDropped events", stats.UnitBytes)
+	mInvocations            = stats.Int64("telemetryapi_invocations", "Number of function invocations by platform.runtimeDone status", stats.UnitDimensionless)
+	mQueueSize              = stats.Int64("telemetryapi_listener_queue_size", "Number of events waiting in the listener queue", stats.UnitDimensionless)
+	mBatchSize              = stats.Int64("telemetryapi_listener_batch_size", "Number of events in the batches received from the Telemetry API", stats.UnitDimensionless)
+	mDuplicateEvents        = stats.Int64("telemetryapi_listener_duplicate_events", "Number of platform events dropped because the Telemetry API redelivered them", stats.UnitDimensionless)
+	mEventsFiltered         = stats.Int64("telemetryapi_listener_events_filtered", "Number of Telemetry API events dropped by the listener exclusion rules", stats.UnitDimensionless)
+	mExtensionOverhead      = stats.Float64("telemetryapi_extension_overhead", "Time from the end of an invocation, as reported by platform.runtimeDone, until the extension asks for the next event", stats.UnitMilliseconds)
+	mNextEventFailures      = stats.Int64("telemetryapi_extension_next_event_failures", "Number of failed requests to the Extensions API for the next event", stats.UnitDimensionless)
+	mAPICallLatency         = stats.Float64("telemetryapi_extension_api_call_latency", "Duration of the calls the extension makes to the Extensions API and the Telemetry API", stats.UnitMilliseconds)
+	mProcessingLatency      = stats.Float64("telemetryapi_listener_processing_latency", "Time from the receipt of an event to its dispatch to the consumers", stats.UnitMilliseconds)
+	mExtensionEvents        = stats.Int64("telemetryapi_extension_events", "Number of events received from the Extensions API", stats.UnitDimensionless)
+	mConfigFallbacks        = stats.Int64("telemetryapi_extension_config_fallbacks", "Number of collector starts with the default configuration because the configuration set was invalid", stats.UnitDimensionless)
+	mFlushDuration          = stats.Float64("telemetryapi_listener_flush_duration", "Time taken to deliver the events received so far to the consumers at the end of an invocation", stats.UnitMilliseconds)
+)
+
+// MetricViews returns the metrics views recorded by the Telemetry API listener.
+func MetricViews() []*view.View {
+	return []*view.View{
+		{
+			Name:        mEventsDropped.Name(),
+			Measure:     mEventsDropped,
+			Description: mEventsDropped.Description(),
+			Aggregation: view.Sum(),
+			TagKeys:     []tag.Key{tagDropPolicy},
+		},
+		{
+			Name:        mPlatformDroppedRecords.Name(),
+			Measure:     mPlatformDroppedRecords,
+			Description: mPlatformDroppedRecords.Description(),
+			Aggregation: view.Sum(),
+			TagKeys:     []tag.Key{tagReason},
+		},
+		{
+			Name:        mPlatformDroppedBytes.Name(),
+			Measure:     mPlatformDroppedBytes,
+			Description: mPlatformDroppedBytes.Description(),
+			Aggregation: view.Sum(),
+			TagKeys:     []tag.Key{tagReason},
+		},
+		{
+			Name:        mInvocations.Name(),
+			Measure:     mInvocations,
+			Description: mInvocations.Description(),
+			Aggregation: view.Sum(),
+			TagKeys:     []tag.Key{tagStatus},
+		},
+		{
+			Name:        mQueueSize.Name(),
+			Measure:     mQueueSize,
+			Description: mQueueSize.Description(),
+			Aggregation: view.LastValue(),
+		},
+		{
+			Name:        mBatchSize.Name(),
+			Measure:     mBatchSize,
+			Description: mBatchSize.Description(),
+			Aggregation: view.Distribution(1, 10, 50, 100, 500, 1000, 5000, 10000),
+		},
+		{
+			Name:        mDuplicateEvents.Name(),
+			Measure:     mDuplicateEvents,
+			Description: mDuplicateEvents.Description(),
+			Aggregation: view.Sum(),
+			TagKeys:     []tag.Key{tagEventType},
+		},
+		{
+			Name:        mEventsFiltered.Name(),
+			Measure:     mEventsFiltered,
+			Description: mEventsFiltered.Description(),
+			Aggregation: view.Sum(),
+			TagKeys:     []tag.Key{tagEventType},
+		},
+		{
+			Name:        mProcessingLatency.Name(),
+			Measure:     mProcessingLatency,
+			Description: mProcessingLatency.Description(),
+			Aggregation: view.Distribution(1, 5, 10, 50, 100, 250, 500, 1000, 5000),
+		},
+		{
+			Name:        mExtensionOverhead.Name(),
+			Measure:     mExtensionOverhead,
+			Description: mExtensionOverhead.Description(),
+			Aggregation: view.Distribution(1, 5, 10, 25, 50, 100, 250, 500, 1000, 5000),
+		},
+		{
+			Name:        mNextEventFailures.Name(),
+			Measure:     mNextEventFailures,
+			Description: mNextEventFailures.Description(),
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        mAPICallLatency.Name(),
+			Measure:     mAPICallLatency,
+			Description: mAPICallLatency.Description(),
+			Aggregation: view.Distribution(1, 5, 10, 25, 50, 100, 250, 500, 1000, 5000, 30000),
+			TagKeys:     []tag.Key{tagCall},
+		},
+		{
+			Name:        mExtensionEvents.Name(),
+			Measure:     mExtensionEvents,
+			Description: mExtensionEvents.Description(),
+			Aggregation: view.Sum(),
+			TagKeys:     []tag.Key{tagEventType},
+		},
+		{
+			Name:        mConfigFallbacks.Name(),
+			Measure:     mConfigFallbacks,
+			Description: mConfigFallbacks.Description(),
+			Aggregation: view.Sum(),
+			TagKeys:     []tag.Key{tagReason},
+		},
+		{
+			Name:        mFlushDuration.Name(),
+			Measure:     mFlushDuration,
+			Description: mFlushDuration.Description(),
+			Aggregation: view.Distribution(1, 5, 10, 25, 50, 100, 250, 500, 1000, 5000),
+		},
+	}
+}
+
+// collectorViews are the views of the export outcomes recorded by the
+// collector itself, unless service::telemetry::metrics::level is none.
+var collectorViews = []string{
+	"exporter/sent_spans",
+	"exporter/send_failed_spans",
+	"exporter/sent_metric_points",
+	"exporter/send_failed_metric_points",
+	"exporter/sent_log_records",
+	"exporter/send_failed_log_records",
+}
+
+// internalViews returns the views of MetricViews and the registered views of
+// collectorViews.
+func internalViews() []*view.View {
+	views := MetricViews()
+	for _, name := range collectorViews {
+		if v := view.Find(name); v != nil {
+			views = append(views, v)
+		}
+	}
+
+	return views
+}
+
+// internalMetricName returns the name of the metric of a view, named as on
+// the Prometheus endpoint of the collector, e.g. otelcol_exporter_sent_spans
+// for the views of the collector.
+func internalMetricName(v *view.View) string {
+	if !strings.Contains(v.Name, "/") {
+		return v.Name
+	}
+
+	return "otelcol_" + strings.ReplaceAll(v.Name, "/", "_")
+}
+
+// RegisterMetricViews registers the views of MetricViews, so the metrics
+// recorded before a Listener is created, e.g. at registration, are kept.
+func RegisterMetricViews() {
+	_ = view.Register(MetricViews()...)
+}
+
+func recordEventsDropped(policy DropPolicy, count int) {
+	_ = stats.RecordWithTags(context.Background(), []tag.Mutator{tag.Upsert(tagDropPolicy, string(policy))}, mEventsDropped.M(int64(count)))
+}
+
+// recordLogsDropped records the droppedRecords and droppedBytes fields of a
+// platform.logsDropped event.
+func recordLogsDropped(record *PlatformLogsDropped) {
+	_ = stats.RecordWithTags(context.Background(), []tag.Mutator{tag.Upsert(tagReason, record.Reason)},
+		mPlatformDroppedRecords.M(record.DroppedRecords),
+		mPlatformDroppedBytes.M(record.DroppedBytes),
+	)
+}
+
+func recordInvocation(status Status) {
+	_ = stats.RecordWithTags(context.Background(), []tag.Mutator{tag.Upsert(tagStatus, string(status))}, mInvocations.M(1))
+}
+
+func recordQueueSize(size int64) {
+	stats.Record(context.Background(), mQueueSize.M(size))
+}
+
+func recordBatchSize(size int) {
+	stats.Record(context.Background(), mBatchSize.M(int64(size)))
+}
+
+func recordDuplicateEvent(eventType string) {
+	_ = stats.RecordWithTags(context.Background(), []tag.Mutator{tag.Upsert(tagEventType, eventType)}, mDuplicateEvents.M(1))
+}
+
+func recordEventFiltered(eventType string) {
+	_ = stats.RecordWithTags(context.Background(), []tag.Mutator{tag.Upsert(tagEventType, eventType)}, mEventsFiltered.M(1))
+}
+
+func recordExtensionOverhead(overhead time.Duration) {
+	stats.Record(context.Background(), mExtensionOverhead.M(float64(overhead)/float64(time.Millisecond)))
+}
+
+// RecordExtensionEvent records an event received from the Extensions API,
+// e.g. INVOKE, which is handled outside of the listener.
+func RecordExtensionEvent(eventType string) {
+	_ = stats.RecordWithTags(context.Background(), []tag.Mutator{tag.Upsert(tagEventType, eventType)}, mExtensionEvents.M(1))
+}
+
+func recordFlushDuration(start time.Time) {
+	stats.Record(context.Background(), mFlushDuration.M(float64(time.Since(start))/float64(time.Millisecond)))
+}
+
+// RecordNextEventFailure records a failed request for the next event of the
+// extension, which is made outside of the listener.
+func RecordNextEventFailure() {
+	stats.Record(context.Background(), mNextEventFailures.M(1))
+}
+
+// RecordConfigFallback records a start of the collector with the default
+// configuration, because the configuration set failed with errorType.
+func RecordConfigFallback(errorType string) {
+	_ = stats.RecordWithTags(context.Background(), []tag.Mutator{tag.Upsert(tagReason, errorType)}, mConfigFallbacks.M(1))
+}
+
+// RecordAPICallLatency records the duration of a call to the Extensions API or
+// the Telemetry API, e.g. "register" or "subscribe", made since start.
+func RecordAPICallLatency(call string, start time.Time) {
+	_ = stats.RecordWithTags(context.Background(), []tag.Mutator{tag.Upsert(tagCall, call)}, mAPICallLatency.M(float64(time.Since(start))/float64(time.Millisecond)))
+}
+
+// recordProcessingLatency records the time the events spent in the listener
+// since they were received.
+func recordProcessingLatency(events []Event) {
+	now := time.Now()
+
+	measurements := make([]stats.Measurement, 0, len(events))
+	for _, event := range events {
+		if event.received.IsZero() {
+			continue
+		}
+
+		measurements = append(measurements, mProcessingLatency.M(float64(now.Sub(event.received))/float64(time.Millisecond)))
+	}
+
+	stats.Record(context.Background(), measurements...)
+}
+
+// appendInternalMetrics appends a snapshot of the metrics views recorded by
+// the listener and of the export outcomes recorded by the collector, as
+// cumulative sums, gauges and histograms with the view tags as attributes.
+func appendInternalMetrics(scopeMetrics pmetric.ScopeMetrics, ts pcommon.Timestamp) error {
+	var errs error
+	for _, v := range internalViews() {
+		rows, err := view.RetrieveData(v.Name)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+
+		if len(rows) == 0 {
+			continue
+		}
+
+		metric := scopeMetrics.Metrics().AppendEmpty()
+		metric.SetName(internalMetricName(v))
+		metric.SetDescription(v.Description)
+		metric.SetUnit(v.Measure.Unit())
+
+		switch v.Aggregation.Type {
+		case view.AggTypeSum, view.AggTypeCount:
+			sum := metric.SetEmptySum()
+			sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+			sum.SetIsMonotonic(true)
+			for _, row := range rows {
+				dp := sum.DataPoints().AppendEmpty()
+				dp.SetStartTimestamp(pcommon.NewTimestampFromTime(row.Data.StartTime()))
+				dp.SetTimestamp(ts)
+				putTags(dp.Attributes(), row.Tags)
+				switch data := row.Data.(type) {
+				case *view.SumData:
+					dp.SetDoubleValue(data.Value)
+				case *view.CountData:
+					dp.SetIntValue(data.Value)
+				}
+			}
+
+		case view.AggTypeLastValue:
+			gauge := metric.SetEmptyGauge()
+			for _, row := range rows {
+				dp := gauge.DataPoints().AppendEmpty()
+				dp.SetTimestamp(ts)
+				putTags(dp.Attributes(), row.Tags)
+				if data, ok := row.Data.(*view.LastValueData); ok {
+					dp.SetDoubleValue(data.Value)
+				}
+			}
+
+		case view.AggTypeDistribution:
+			histogram := metric.SetEmptyHistogram()
+			histogram.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+			for _, row := range rows {
+				data, ok := row.Data.(*view.DistributionData)
+				if !ok {
+					continue
+				}
+
+				dp := histogram.DataPoints().AppendEmpty()
+				dp.SetStartTimestamp(pcommon.NewTimestampFromTime(data.StartTime()))
+				dp.SetTimestamp(ts)
+				putTags(dp.Attributes(), row.Tags)
+				dp.SetCount(uint64(data.Count))
+				dp.SetSum(data.Sum())
+				dp.SetMin(data.Min)
+				dp.SetMax(data.Max)
+				dp.ExplicitBounds().FromRaw(v.Aggregation.Buckets)
+
+				counts := make([]uint64, len(data.CountPerBucket))
+				for i, count := range data.CountPerBucket {
+					counts[i] = uint64(count)
+				}
+				dp.BucketCounts().FromRaw(counts)
+			}
+		}
+	}
+
+	return errs
+}
+
+func putTags(attrs pcommon.Map, tags []tag.Tag) {
+	for _, t := range tags {
+		attrs.PutStr(t.Key.Name(), t.Value)
+	}
+}
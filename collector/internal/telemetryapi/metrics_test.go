@@ -0,0 +1,248 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestListenerQueueMetrics(t *testing.T) {
+	// Start from empty views, other tests record into the same global views
+	view.Unregister(MetricViews()...)
+	l := NewListener(ListenerConfig{})
+
+	payload := `[{"type":"function","record":"a"},{"type":"function","record":"b"},{"type":"function","record":"c"}]`
+	l.httpHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload)))
+
+	rows, err := view.RetrieveData(mQueueSize.Name())
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, float64(3), rows[0].Data.(*view.LastValueData).Value)
+
+	rows, err = view.RetrieveData(mBatchSize.Name())
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, float64(3), rows[0].Data.(*view.DistributionData).Max)
+
+	items, err := l.queue.Get(maxBatchSize)
+	assert.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	l.deliver(items)
+
+	rows, err = view.RetrieveData(mProcessingLatency.Name())
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, int64(3), rows[0].Data.(*view.DistributionData).Count)
+	assert.GreaterOrEqual(t, rows[0].Data.(*view.DistributionData).Min, float64(5))
+}
+
+func TestReportRuntimeDone(t *testing.T) {
+	for _, status := range []Status{StatusSuccess, StatusFailure, StatusError, StatusTimeout} {
+		t.Run(string(status), func(t *testing.T) {
+			view.Unregister(MetricViews()...)
+			assert.NoError(t, view.Register(MetricViews()...))
+
+			reportRuntimeDone(&PlatformRuntimeDone{RequestID: "1", Status: status, ErrorType: "Runtime.ExitError"})
+			reportRuntimeDone(&PlatformRuntimeDone{RequestID: "2", Status: status})
+
+			rows, err := view.RetrieveData(mInvocations.Name())
+			assert.NoError(t, err)
+			assert.Len(t, rows, 1)
+			assert.Equal(t, []tag.Tag{{Key: tagStatus, Value: string(status)}}, rows[0].Tags)
+			assert.Equal(t, float64(2), rows[0].Data.(*view.SumData).Value)
+		})
+	}
+}
+
+func TestRecordLogsDropped(t *testing.T) {
+	view.Unregister(MetricViews()...)
+	assert.NoError(t, view.Register(MetricViews()...))
+
+	recordLogsDropped(&PlatformLogsDropped{Reason: "buffer full", DroppedRecords: 12, DroppedBytes: 2048})
+	recordLogsDropped(&PlatformLogsDropped{Reason: "buffer full", DroppedRecords: 3, DroppedBytes: 100})
+
+	for name, expected := range map[string]float64{
+		mPlatformDroppedRecords.Name(): 15,
+		mPlatformDroppedBytes.Name():   2148,
+	} {
+		rows, err := view.RetrieveData(name)
+		assert.NoError(t, err)
+		assert.Len(t, rows, 1)
+		assert.Equal(t, []tag.Tag{{Key: tagReason, Value: "buffer full"}}, rows[0].Tags)
+		assert.Equal(t, expected, rows[0].Data.(*view.SumData).Value)
+	}
+}
+
+func TestPlatformMetricsConsumer(t *testing.T) {
+	view.Unregister(MetricViews()...)
+	assert.NoError(t, view.Register(MetricViews()...))
+
+	platformMetricsConsumer(context.Background(), []Event{
+		{Type: PLATFORM_START, Record: json.RawMessage(`{"requestId":"1"}`)},
+		{Type: PLATFORM_LOGS_DROPPED, Record: json.RawMessage(`{"reason":"buffer full","droppedRecords":4,"droppedBytes":512}`)},
+		{Type: PLATFORM_RUNTIME_DONE, Record: json.RawMessage(`{"requestId":"1","status":"timeout"}`)},
+		{Type: PLATFORM_RUNTIME_DONE, Record: json.RawMessage(`{"status":"success"}`)},
+	})
+
+	rows, err := view.RetrieveData(mPlatformDroppedRecords.Name())
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, float64(4), rows[0].Data.(*view.SumData).Value)
+
+	// The runtimeDone event without request ID is invalid and not counted
+	rows, err = view.RetrieveData(mInvocations.Name())
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, []tag.Tag{{Key: tagStatus, Value: string(StatusTimeout)}}, rows[0].Tags)
+	assert.Equal(t, float64(1), rows[0].Data.(*view.SumData).Value)
+}
+
+func TestAppendInternalMetrics(t *testing.T) {
+	view.Unregister(MetricViews()...)
+	assert.NoError(t, view.Register(MetricViews()...))
+
+	recordInvocation(StatusSuccess)
+	recordQueueSize(7)
+	recordBatchSize(3)
+	recordBatchSize(20)
+
+	scopeMetrics := pmetric.NewScopeMetrics()
+	assert.NoError(t, appendInternalMetrics(scopeMetrics, pcommon.NewTimestampFromTime(time.Now())))
+
+	metrics := make(map[string]pmetric.Metric)
+	for i := 0; i < scopeMetrics.Metrics().Len(); i++ {
+		metrics[scopeMetrics.Metrics().At(i).Name()] = scopeMetrics.Metrics().At(i)
+	}
+	assert.Len(t, metrics, 3)
+
+	invocations := metrics[mInvocations.Name()].Sum()
+	assert.Equal(t, pmetric.AggregationTemporalityCumulative, invocations.AggregationTemporality())
+	assert.Equal(t, 1.0, invocations.DataPoints().At(0).DoubleValue())
+	assert.Equal(t, map[string]interface{}{"status": "success"}, invocations.DataPoints().At(0).Attributes().AsRaw())
+
+	assert.Equal(t, 7.0, metrics[mQueueSize.Name()].Gauge().DataPoints().At(0).DoubleValue())
+
+	batchSize := metrics[mBatchSize.Name()].Histogram().DataPoints().At(0)
+	assert.Equal(t, uint64(2), batchSize.Count())
+	assert.Equal(t, 23.0, batchSize.Sum())
+	assert.Equal(t, []float64{1, 10, 50, 100, 500, 1000, 5000, 10000}, batchSize.ExplicitBounds().AsRaw())
+	assert.Equal(t, []uint64{0, 1, 1, 0, 0, 0, 0, 0, 0}, batchSize.BucketCounts().AsRaw())
+}
+
+func TestAppendInternalMetricsCollectorViews(t *testing.T) {
+	view.Unregister(MetricViews()...)
+	assert.NoError(t, view.Register(MetricViews()...))
+
+	// Registered by the collector unless its telemetry is disabled
+	sentSpans := stats.Int64("exporter/sent_spans", "Number of spans successfully sent to destination.", stats.UnitDimensionless)
+	exporter, _ := tag.NewKey("exporter")
+	sentSpansView := &view.View{Name: sentSpans.Name(), Measure: sentSpans, Aggregation: view.Sum(), TagKeys: []tag.Key{exporter}}
+	assert.NoError(t, view.Register(sentSpansView))
+	defer view.Unregister(sentSpansView)
+
+	_ = stats.RecordWithTags(context.Background(), []tag.Mutator{tag.Upsert(exporter, "otlp")}, sentSpans.M(5))
+
+	scopeMetrics := pmetric.NewScopeMetrics()
+	assert.NoError(t, appendInternalMetrics(scopeMetrics, pcommon.NewTimestampFromTime(time.Now())))
+	assert.Equal(t, 1, scopeMetrics.Metrics().Len())
+
+	metric := scopeMetrics.Metrics().At(0)
+	assert.Equal(t, "otelcol_exporter_sent_spans", metric.Name())
+	assert.Equal(t, 5.0, metric.Sum().DataPoints().At(0).DoubleValue())
+	assert.Equal(t, map[string]interface{}{"exporter": "otlp"}, metric.Sum().DataPoints().At(0).Attributes().AsRaw())
+}
+
+func TestConverterInternalMetrics(t *testing.T) {
+	view.Unregister(MetricViews()...)
+	assert.NoError(t, view.Register(MetricViews()...))
+
+	RecordExtensionEvent("INVOKE")
+	recordFlushDuration(time.Now().Add(-20 * time.Millisecond))
+
+	c := NewConverter(ConverterConfig{InternalMetrics: true})
+	c.SetFunctionARN(&FunctionARN{Region: "eu-west-1", AccountID: "123456789012", FunctionName: "my-function"})
+	metrics := c.ToMetrics(invocationEvents())
+
+	// The metrics of the extension have a resource of their own
+	assert.Equal(t, 2, metrics.ResourceMetrics().Len())
+	assert.NotContains(t, metrics.ResourceMetrics().At(0).Resource().Attributes().AsRaw(), "service.name")
+
+	internal := metrics.ResourceMetrics().At(1)
+	assert.Equal(t, "opentelemetry-lambda-extension", internal.Resource().Attributes().AsRaw()["service.name"])
+	assert.Equal(t, "my-function", internal.Resource().Attributes().AsRaw()["faas.name"])
+
+	names := make(map[string]pmetric.Metric)
+	for i := 0; i < internal.ScopeMetrics().At(0).Metrics().Len(); i++ {
+		metric := internal.ScopeMetrics().At(0).Metrics().At(i)
+		names[metric.Name()] = metric
+	}
+
+	events := names[mExtensionEvents.Name()].Sum().DataPoints().At(0)
+	assert.Equal(t, 1.0, events.DoubleValue())
+	assert.Equal(t, map[string]interface{}{"type": "INVOKE"}, events.Attributes().AsRaw())
+	assert.Equal(t, uint64(1), names[mFlushDuration.Name()].Histogram().DataPoints().At(0).Count())
+
+	// Without a platform.report event, only the metrics of the function are converted
+	assert.Equal(t, 1, c.ToMetrics(invocationEvents()[:1]).ResourceMetrics().Len())
+}
+
+func TestRecordOverhead(t *testing.T) {
+	view.Unregister(MetricViews()...)
+	l := NewListener(ListenerConfig{})
+	l.ResumeDispatch()
+	defer l.queue.Dispose()
+
+	// Nothing is recorded before an invocation completed
+	l.RecordOverhead()
+
+	done := time.Now().Add(-50 * time.Millisecond).UTC().Format(time.RFC3339Nano)
+	l.enqueue([]Event{{Time: done, Type: PLATFORM_RUNTIME_DONE, Record: json.RawMessage(`{"requestId":"1","status":"success"}`)}})
+	assert.NoError(t, l.Wait(context.Background(), "1"))
+
+	l.RecordOverhead()
+	l.RecordOverhead()
+
+	rows, err := view.RetrieveData(mExtensionOverhead.Name())
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, int64(1), rows[0].Data.(*view.DistributionData).Count)
+	assert.GreaterOrEqual(t, rows[0].Data.(*view.DistributionData).Min, float64(50))
+}
+
+func TestRecordAPICallLatency(t *testing.T) {
+	RegisterMetricViews()
+	defer view.Unregister(MetricViews()...)
+
+	RecordAPICallLatency("register", time.Now().Add(-20*time.Millisecond))
+
+	rows, err := view.RetrieveData(mAPICallLatency.Name())
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, []tag.Tag{{Key: tagCall, Value: "register"}}, rows[0].Tags)
+	assert.GreaterOrEqual(t, rows[0].Data.(*view.DistributionData).Min, float64(20))
+}
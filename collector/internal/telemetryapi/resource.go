@@ -0,0 +1,86 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapi
+
+import (
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+const (
+	attributeCloudProvider  = "cloud.provider"
+	attributeCloudRegion    = "cloud.region"
+	attributeCloudAccountID = "cloud.account.id"
+	attributeFaaSID         = "faas.id"
+	attributeFaaSName       = "faas.name"
+	attributeFaaSVersion    = "faas.version"
+	attributeServiceName    = "service.name"
+
+	// extensionServiceName is the service.name of the metrics of the extension itself
+	extensionServiceName = "opentelemetry-lambda-extension"
+)
+
+// FunctionARN is the parsed ARN of the invoked function, e.g.
+// arn:aws:lambda:eu-west-1:123456789012:function:my-function:prod
+type FunctionARN struct {
+	Partition    string
+	Region       string
+	AccountID    string
+	FunctionName string
+	// Qualifier is the version or alias the function was invoked with, if any
+	Qualifier string
+}
+
+// ParseFunctionARN parses the InvokedFunctionArn of an INVOKE event.
+func ParseFunctionARN(arn string) (*FunctionARN, error) {
+	parts := strings.Split(arn, ":")
+	if len(parts) < 7 || len(parts) > 8 || parts[0] != "arn" || parts[2] != "lambda" || parts[5] != "function" {
+		return nil, fmt.Errorf("invalid function ARN %q", arn)
+	}
+
+	function := &FunctionARN{
+		Partition:    parts[1],
+		Region:       parts[3],
+		AccountID:    parts[4],
+		FunctionName: parts[6],
+	}
+
+	if len(parts) == 8 {
+		function.Qualifier = parts[7]
+	}
+
+	return function, nil
+}
+
+// ID returns the ARN of the function without its qualifier, as the
+// semantic conventions expect for faas.id.
+func (f *FunctionARN) ID() string {
+	return fmt.Sprintf("arn:%s:lambda:%s:%s:function:%s", f.Partition, f.Region, f.AccountID, f.FunctionName)
+}
+
+// stamp sets the cloud and faas resource attributes of the function on attrs.
+func (f *FunctionARN) stamp(attrs pcommon.Map) {
+	attrs.PutStr(attributeCloudProvider, "aws")
+	attrs.PutStr(attributeCloudRegion, f.Region)
+	attrs.PutStr(attributeCloudAccountID, f.AccountID)
+	attrs.PutStr(attributeFaaSID, f.ID())
+	attrs.PutStr(attributeFaaSName, f.FunctionName)
+
+	if f.Qualifier != "" {
+		attrs.PutStr(attributeFaaSVersion, f.Qualifier)
+	}
+}
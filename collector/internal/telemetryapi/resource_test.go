@@ -0,0 +1,92 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFunctionARN(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		arn      string
+		expected *FunctionARN
+	}{
+		{
+			name:     "unqualified",
+			arn:      "arn:aws:lambda:eu-west-1:123456789012:function:my-function",
+			expected: &FunctionARN{Partition: "aws", Region: "eu-west-1", AccountID: "123456789012", FunctionName: "my-function"},
+		},
+		{
+			name:     "alias",
+			arn:      "arn:aws-cn:lambda:cn-north-1:123456789012:function:my-function:prod",
+			expected: &FunctionARN{Partition: "aws-cn", Region: "cn-north-1", AccountID: "123456789012", FunctionName: "my-function", Qualifier: "prod"},
+		},
+		{name: "not a function", arn: "arn:aws:lambda:eu-west-1:123456789012:layer:my-layer:1"},
+		{name: "not lambda", arn: "arn:aws:s3:::bucket"},
+		{name: "empty", arn: ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			function, err := ParseFunctionARN(tc.arn)
+			if tc.expected == nil {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, function)
+		})
+	}
+}
+
+func TestConverterFunctionResource(t *testing.T) {
+	function, err := ParseFunctionARN("arn:aws:lambda:eu-west-1:123456789012:function:my-function:7")
+	assert.NoError(t, err)
+
+	c := NewConverter(ConverterConfig{})
+	c.SetFunctionARN(function)
+
+	logs := c.ToLogs(invocationEvents())
+	assert.Equal(t, map[string]any{
+		"cloud.provider":   "aws",
+		"cloud.region":     "eu-west-1",
+		"cloud.account.id": "123456789012",
+		"faas.id":          "arn:aws:lambda:eu-west-1:123456789012:function:my-function",
+		"faas.name":        "my-function",
+		"faas.version":     "7",
+	}, logs.ResourceLogs().At(0).Resource().Attributes().AsRaw())
+
+	traces := c.ToTraces(invocationEvents())
+	assert.Equal(t, "my-function", traces.ResourceSpans().At(0).Resource().Attributes().AsRaw()["faas.name"])
+
+	metrics := c.ToMetrics(invocationEvents())
+	assert.Equal(t, "my-function", metrics.ResourceMetrics().At(0).Resource().Attributes().AsRaw()["faas.name"])
+
+	c.SetFunctionARN(nil)
+	assert.Zero(t, c.ToLogs(invocationEvents()).ResourceLogs().At(0).Resource().Attributes().Len())
+}
+
+func TestListenerFunctionARN(t *testing.T) {
+	l := NewListener(ListenerConfig{})
+	assert.Nil(t, l.FunctionARN())
+
+	l.SetInvokedFunctionARN("arn:aws:lambda:eu-west-1:123456789012:function:my-function")
+	assert.Equal(t, "my-function", l.FunctionARN().FunctionName)
+
+	l.SetInvokedFunctionARN("invalid")
+	assert.Nil(t, l.FunctionARN())
+}
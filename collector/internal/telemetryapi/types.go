@@ -14,6 +14,12 @@
 
 package telemetryapi
 
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
 // EventType represents the type of log events in Lambda
 //  Required: YES
 type EventType string
@@ -26,13 +32,57 @@ const (
 	// Extension is used is to receive log events emitted by the extension
 	Extension EventType = "extension"
 
+	// Indicates that the function initialization phase has started
+	PLATFORM_INIT_START = "platform.initStart"
+
+	// Indicates that the function initialization phase has completed
+	PLATFORM_INIT_RUNTIME_DONE = "platform.initRuntimeDone"
+
+	// Contains a report of the function initialization phase
+	PLATFORM_INIT_REPORT = "platform.initReport"
+
+	// Indicates that the SnapStart restore phase has started (schema 2022-12-13)
+	PLATFORM_RESTORE_START = "platform.restoreStart"
+
+	// Indicates that the SnapStart restore phase has completed (schema 2022-12-13)
+	PLATFORM_RESTORE_RUNTIME_DONE = "platform.restoreRuntimeDone"
+
+	// Contains a report of the SnapStart restore phase (schema 2022-12-13)
+	PLATFORM_RESTORE_REPORT = "platform.restoreReport"
+
+	// Indicates that the function invocation phase has started
+	PLATFORM_START = "platform.start"
+
 	// Indicates that the function invocation phase has completed
 	PLATFORM_RUNTIME_DONE = "platform.runtimeDone"
 
+	// Contains a report of the function invocation phase
+	PLATFORM_REPORT = "platform.report"
+
+	// Contains information about an extension registration
+	PLATFORM_EXTENSION = "platform.extension"
+
+	// Contains information about a Telemetry API subscription
+	PLATFORM_TELEMETRY_SUBSCRIPTION = "platform.telemetrySubscription"
+
 	// Contains information about dropped events
 	PLATFORM_LOGS_DROPPED = "platform.logsDropped"
 )
 
+// Status is the outcome of an invocation phase reported in platform.runtimeDone
+type Status string
+
+const (
+	// StatusSuccess indicates the invocation completed successfully
+	StatusSuccess Status = "success"
+	// StatusFailure indicates the function returned an error
+	StatusFailure Status = "failure"
+	// StatusError indicates the runtime reported an error
+	StatusError Status = "error"
+	// StatusTimeout indicates the invocation exceeded the function timeout
+	StatusTimeout Status = "timeout"
+)
+
 // BufferingCfg holds configuration for receiving telemetry from the Telemetry API.
 // Telemetry will be sent to your listener when one of the conditions below is met.
 //  Required: NO
@@ -93,8 +143,314 @@ type SubscribeRequest struct {
 	Destination   Destination   `json:"destination"`
 }
 
+// Configuration for listeners that would like to receive logs via the Logs API
+//  Required: YES
+type LogsDestination struct {
+	Protocol Protocol `json:"protocol"`
+	URI      URI      `json:"URI"`
+}
+
+// Request body that is sent to the Logs API on subscribe
+type LogsSubscribeRequest struct {
+	// SchemaVersion valid values are "2020-08-15" and "2021-03-18"
+	SchemaVersion SchemaVersion   `json:"schemaVersion"`
+	EventTypes    []EventType     `json:"types"`
+	BufferingCfg  BufferingCfg    `json:"buffering"`
+	Destination   LogsDestination `json:"destination"`
+}
+
+// Event is a single event received from the Telemetry API. The record is
+// kept undecoded until it is parsed with ParseRecord, since its shape depends
+// on the event type and the subscribed schema version. A record type covers
+// every schema version: fields added by later versions are optional, and left
+// empty in the events of earlier ones.
 type Event struct {
-	Time   string         `json:"time"`
-	Type   string         `json:"type"`
-	Record map[string]any `json:"record"`
+	Time   string          `json:"time"`
+	Type   string          `json:"type"`
+	Record json.RawMessage `json:"record"`
+
+	// received is when the listener received the event
+	received time.Time
+}
+
+// Record is the typed record of a platform event.
+type Record interface {
+	// Validate checks the fields required by the Telemetry API schema are set.
+	Validate() error
+}
+
+// ParseRecord decodes and validates the record of a platform event into its
+// typed model, e.g. *PlatformRuntimeDone for platform.runtimeDone events.
+// Function and extension log records are returned as *LogRecord.
+func (e Event) ParseRecord() (Record, error) {
+	var record Record
+
+	switch e.Type {
+	case PLATFORM_INIT_START:
+		record = &PlatformInitStart{}
+	case PLATFORM_INIT_RUNTIME_DONE:
+		record = &PlatformInitRuntimeDone{}
+	case PLATFORM_INIT_REPORT:
+		record = &PlatformInitReport{}
+	case PLATFORM_RESTORE_START:
+		record = &PlatformRestoreStart{}
+	case PLATFORM_RESTORE_RUNTIME_DONE:
+		record = &PlatformRestoreRuntimeDone{}
+	case PLATFORM_RESTORE_REPORT:
+		record = &PlatformRestoreReport{}
+	case PLATFORM_START:
+		record = &PlatformStart{}
+	case PLATFORM_RUNTIME_DONE:
+		record = &PlatformRuntimeDone{}
+	case PLATFORM_REPORT:
+		record = &PlatformReport{}
+	case PLATFORM_EXTENSION:
+		record = &PlatformExtension{}
+	case PLATFORM_TELEMETRY_SUBSCRIPTION:
+		record = &PlatformTelemetrySubscription{}
+	case PLATFORM_LOGS_DROPPED:
+		record = &PlatformLogsDropped{}
+	case string(Function), string(Extension):
+		record = &LogRecord{}
+	default:
+		return nil, fmt.Errorf("unsupported event type %q", e.Type)
+	}
+
+	err := json.Unmarshal(e.Record, record)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s record: %w", e.Type, err)
+	}
+
+	err = record.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s record: %w", e.Type, err)
+	}
+
+	return record, nil
+}
+
+// TraceContext is the tracing information of an invocation.
+type TraceContext struct {
+	SpanID string `json:"spanId,omitempty"`
+	Type   string `json:"type"`
+	Value  string `json:"value"`
+}
+
+// Span describes a phase measured by the Lambda platform.
+type Span struct {
+	Name       string  `json:"name"`
+	Start      string  `json:"start"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// PlatformInitStart is the record of a platform.initStart event.
+type PlatformInitStart struct {
+	InitializationType string `json:"initializationType"`
+	Phase              string `json:"phase"`
+	RuntimeVersion     string `json:"runtimeVersion,omitempty"`
+	RuntimeVersionArn  string `json:"runtimeVersionArn,omitempty"`
+	FunctionName       string `json:"functionName,omitempty"`
+	FunctionVersion    string `json:"functionVersion,omitempty"`
+	InstanceID         string `json:"instanceId,omitempty"`
+	InstanceMaxMemory  int64  `json:"instanceMaxMemory,omitempty"`
+}
+
+func (r *PlatformInitStart) Validate() error {
+	return requireFields("initializationType", r.InitializationType, "phase", r.Phase)
+}
+
+// PlatformInitRuntimeDone is the record of a platform.initRuntimeDone event.
+type PlatformInitRuntimeDone struct {
+	InitializationType string `json:"initializationType"`
+	Phase              string `json:"phase"`
+	Status             Status `json:"status"`
+	ErrorType          string `json:"errorType,omitempty"`
+	Spans              []Span `json:"spans,omitempty"`
+}
+
+func (r *PlatformInitRuntimeDone) Validate() error {
+	return requireFields("initializationType", r.InitializationType, "status", string(r.Status))
+}
+
+// PlatformInitReport is the record of a platform.initReport event.
+type PlatformInitReport struct {
+	InitializationType string            `json:"initializationType"`
+	Phase              string            `json:"phase"`
+	Status             Status            `json:"status,omitempty"`
+	ErrorType          string            `json:"errorType,omitempty"`
+	Metrics            InitReportMetrics `json:"metrics"`
+	Spans              []Span            `json:"spans,omitempty"`
+}
+
+// InitReportMetrics are the metrics of a platform.initReport event.
+type InitReportMetrics struct {
+	DurationMs float64 `json:"durationMs"`
+}
+
+func (r *PlatformInitReport) Validate() error {
+	return requireFields("initializationType", r.InitializationType, "phase", r.Phase)
+}
+
+// PlatformRestoreStart is the record of a platform.restoreStart event.
+type PlatformRestoreStart struct {
+	RuntimeVersion    string `json:"runtimeVersion,omitempty"`
+	RuntimeVersionArn string `json:"runtimeVersionArn,omitempty"`
+	FunctionName      string `json:"functionName,omitempty"`
+	FunctionVersion   string `json:"functionVersion,omitempty"`
+	InstanceID        string `json:"instanceId,omitempty"`
+	InstanceMaxMemory int64  `json:"instanceMaxMemory,omitempty"`
+}
+
+func (r *PlatformRestoreStart) Validate() error {
+	return nil
+}
+
+// PlatformRestoreRuntimeDone is the record of a platform.restoreRuntimeDone event.
+type PlatformRestoreRuntimeDone struct {
+	Status    Status `json:"status"`
+	ErrorType string `json:"errorType,omitempty"`
+	Spans     []Span `json:"spans,omitempty"`
+}
+
+func (r *PlatformRestoreRuntimeDone) Validate() error {
+	return requireFields("status", string(r.Status))
+}
+
+// PlatformRestoreReport is the record of a platform.restoreReport event.
+type PlatformRestoreReport struct {
+	Status    Status               `json:"status"`
+	ErrorType string               `json:"errorType,omitempty"`
+	Metrics   RestoreReportMetrics `json:"metrics"`
+	Spans     []Span               `json:"spans,omitempty"`
+}
+
+// RestoreReportMetrics are the metrics of a platform.restoreReport event.
+type RestoreReportMetrics struct {
+	DurationMs float64 `json:"durationMs"`
+}
+
+func (r *PlatformRestoreReport) Validate() error {
+	return requireFields("status", string(r.Status))
+}
+
+// PlatformStart is the record of a platform.start event.
+type PlatformStart struct {
+	RequestID string        `json:"requestId"`
+	Version   string        `json:"version,omitempty"`
+	Tracing   *TraceContext `json:"tracing,omitempty"`
+}
+
+func (r *PlatformStart) Validate() error {
+	return requireFields("requestId", r.RequestID)
+}
+
+// PlatformRuntimeDone is the record of a platform.runtimeDone event.
+type PlatformRuntimeDone struct {
+	RequestID string              `json:"requestId"`
+	Status    Status              `json:"status"`
+	ErrorType string              `json:"errorType,omitempty"`
+	Metrics   *RuntimeDoneMetrics `json:"metrics,omitempty"`
+	Tracing   *TraceContext       `json:"tracing,omitempty"`
+	Spans     []Span              `json:"spans,omitempty"`
+}
+
+// RuntimeDoneMetrics are the metrics of a platform.runtimeDone event.
+type RuntimeDoneMetrics struct {
+	DurationMs    float64 `json:"durationMs"`
+	ProducedBytes int64   `json:"producedBytes,omitempty"`
+}
+
+func (r *PlatformRuntimeDone) Validate() error {
+	return requireFields("requestId", r.RequestID, "status", string(r.Status))
+}
+
+// PlatformReport is the record of a platform.report event.
+type PlatformReport struct {
+	RequestID string        `json:"requestId"`
+	Status    Status        `json:"status"`
+	ErrorType string        `json:"errorType,omitempty"`
+	Metrics   ReportMetrics `json:"metrics"`
+	Tracing   *TraceContext `json:"tracing,omitempty"`
+	Spans     []Span        `json:"spans,omitempty"`
+}
+
+// ReportMetrics are the metrics of a platform.report event.
+type ReportMetrics struct {
+	DurationMs       float64 `json:"durationMs"`
+	BilledDurationMs int64   `json:"billedDurationMs"`
+	MemorySizeMB     int64   `json:"memorySizeMB"`
+	MaxMemoryUsedMB  int64   `json:"maxMemoryUsedMB"`
+	InitDurationMs   float64 `json:"initDurationMs,omitempty"`
+}
+
+// Validate only requires the request ID, as the platform.report records
+// relayed by the Logs API carry no status.
+func (r *PlatformReport) Validate() error {
+	return requireFields("requestId", r.RequestID)
+}
+
+// PlatformExtension is the record of a platform.extension event.
+type PlatformExtension struct {
+	Name      string   `json:"name"`
+	State     string   `json:"state"`
+	Events    []string `json:"events"`
+	ErrorType string   `json:"errorType,omitempty"`
+}
+
+func (r *PlatformExtension) Validate() error {
+	return requireFields("name", r.Name, "state", r.State)
+}
+
+// PlatformTelemetrySubscription is the record of a platform.telemetrySubscription event.
+type PlatformTelemetrySubscription struct {
+	Name  string      `json:"name"`
+	State string      `json:"state"`
+	Types []EventType `json:"types"`
+}
+
+func (r *PlatformTelemetrySubscription) Validate() error {
+	return requireFields("name", r.Name, "state", r.State)
+}
+
+// PlatformLogsDropped is the record of a platform.logsDropped event.
+type PlatformLogsDropped struct {
+	Reason         string `json:"reason"`
+	DroppedRecords int64  `json:"droppedRecords"`
+	DroppedBytes   int64  `json:"droppedBytes"`
+}
+
+func (r *PlatformLogsDropped) Validate() error {
+	return requireFields("reason", r.Reason)
+}
+
+// LogRecord is the record of a function or extension log event. Depending on
+// the log format configured for the function, the line is either plain text
+// or a JSON object, which is kept as is in Message.
+type LogRecord struct {
+	Message string
+}
+
+func (r *LogRecord) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &r.Message)
+	}
+
+	r.Message = string(data)
+
+	return nil
+}
+
+func (r *LogRecord) Validate() error {
+	return nil
+}
+
+// requireFields returns an error naming the first empty value of the given name/value pairs.
+func requireFields(nameValues ...string) error {
+	for i := 0; i+1 < len(nameValues); i += 2 {
+		if nameValues[i+1] == "" {
+			return fmt.Errorf("missing required field %s", nameValues[i])
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,103 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRecord(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		event    Event
+		expected Record
+		err      bool
+	}{
+		{
+			name:     "runtimeDone",
+			event:    Event{Type: PLATFORM_RUNTIME_DONE, Record: json.RawMessage(`{"requestId":"1","status":"timeout","metrics":{"durationMs":3000.5}}`)},
+			expected: &PlatformRuntimeDone{RequestID: "1", Status: StatusTimeout, Metrics: &RuntimeDoneMetrics{DurationMs: 3000.5}},
+		},
+		{
+			name:     "report without status",
+			event:    Event{Type: PLATFORM_REPORT, Record: json.RawMessage(`{"requestId":"1","metrics":{"durationMs":1.5,"billedDurationMs":2}}`)},
+			expected: &PlatformReport{RequestID: "1", Metrics: ReportMetrics{DurationMs: 1.5, BilledDurationMs: 2}},
+		},
+		{
+			name:     "initStart of schema 2022-07-01",
+			event:    Event{Type: PLATFORM_INIT_START, Record: json.RawMessage(`{"initializationType":"on-demand","phase":"init"}`)},
+			expected: &PlatformInitStart{InitializationType: "on-demand", Phase: "init"},
+		},
+		{
+			name:     "initStart of schema 2022-12-13",
+			event:    Event{Type: PLATFORM_INIT_START, Record: json.RawMessage(`{"initializationType":"snap-start","phase":"init","functionName":"fn","instanceMaxMemory":128}`)},
+			expected: &PlatformInitStart{InitializationType: "snap-start", Phase: "init", FunctionName: "fn", InstanceMaxMemory: 128},
+		},
+		{
+			name:     "restoreReport",
+			event:    Event{Type: PLATFORM_RESTORE_REPORT, Record: json.RawMessage(`{"status":"success","metrics":{"durationMs":42.5}}`)},
+			expected: &PlatformRestoreReport{Status: StatusSuccess, Metrics: RestoreReportMetrics{DurationMs: 42.5}},
+		},
+		{
+			name:     "fields of later schemas are ignored",
+			event:    Event{Type: PLATFORM_START, Record: json.RawMessage(`{"requestId":"1","newField":{"nested":true}}`)},
+			expected: &PlatformStart{RequestID: "1"},
+		},
+		{
+			name:     "logsDropped",
+			event:    Event{Type: PLATFORM_LOGS_DROPPED, Record: json.RawMessage(`{"reason":"buffer full","droppedRecords":12,"droppedBytes":2048}`)},
+			expected: &PlatformLogsDropped{Reason: "buffer full", DroppedRecords: 12, DroppedBytes: 2048},
+		},
+		{
+			name:     "text function log",
+			event:    Event{Type: string(Function), Record: json.RawMessage(`"hello world"`)},
+			expected: &LogRecord{Message: "hello world"},
+		},
+		{
+			name:     "json function log",
+			event:    Event{Type: string(Function), Record: json.RawMessage(`{"level":"INFO","message":"hello"}`)},
+			expected: &LogRecord{Message: `{"level":"INFO","message":"hello"}`},
+		},
+		{
+			name:  "missing required field",
+			event: Event{Type: PLATFORM_START, Record: json.RawMessage(`{"version":"$LATEST"}`)},
+			err:   true,
+		},
+		{
+			name:  "invalid record",
+			event: Event{Type: PLATFORM_REPORT, Record: json.RawMessage(`"not an object"`)},
+			err:   true,
+		},
+		{
+			name:  "unknown type",
+			event: Event{Type: "platform.unknown", Record: json.RawMessage(`{}`)},
+			err:   true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			record, err := tc.event.ParseRecord()
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, record)
+		})
+	}
+}
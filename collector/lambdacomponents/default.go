@@ -31,18 +31,26 @@ import (
 	"github.com/open-telemetry/opentelemetry-collector-contrib/extension/oidcauthextension"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/extension/sigv4authextension"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/attributesprocessor"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/cumulativetodeltaprocessor"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/filterprocessor"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/groupbytraceprocessor"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/logstransformprocessor"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/probabilisticsamplerprocessor"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/redactionprocessor"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourceprocessor"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/spanprocessor"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor"
 )
 
-func Components() (component.Factories, error) {
+// Components returns the factories of the components available in the
+// collector configuration, including the given additional receivers.
+func Components(additionalReceivers ...component.ReceiverFactory) (component.Factories, error) {
 	var errs []error
 
 	receivers, err := component.MakeReceiverFactoryMap(
-		otlpreceiver.NewFactory(),
+		append([]component.ReceiverFactory{
+			otlpreceiver.NewFactory(),
+		}, additionalReceivers...)...,
 	)
 
 	if err != nil {
@@ -70,6 +78,10 @@ func Components() (component.Factories, error) {
 		groupbytraceprocessor.NewFactory(),
 		memorylimiterprocessor.NewFactory(),
 		probabilisticsamplerprocessor.NewFactory(),
+		tailsamplingprocessor.NewFactory(),
+		cumulativetodeltaprocessor.NewFactory(),
+		redactionprocessor.NewFactory(),
+		logstransformprocessor.NewFactory(),
 	)
 
 	if err != nil {
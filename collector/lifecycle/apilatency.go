@@ -0,0 +1,74 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/telemetryapi"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi"
+)
+
+// timedAPI records the latency of the calls made to the Extensions API as
+// internal metrics. The latency of NextEvent includes the time spent waiting
+// for the next event.
+type timedAPI struct {
+	extensionapi.API
+}
+
+func (a timedAPI) Register(ctx context.Context, extensionName string, events ...extensionapi.EventType) (*extensionapi.RegisterResponse, error) {
+	defer telemetryapi.RecordAPICallLatency("register", time.Now())
+	return a.API.Register(ctx, extensionName, events...)
+}
+
+func (a timedAPI) NextEvent(ctx context.Context) (*extensionapi.NextEventResponse, error) {
+	defer telemetryapi.RecordAPICallLatency("event_next", time.Now())
+	return a.API.NextEvent(ctx)
+}
+
+func (a timedAPI) InitError(ctx context.Context, errorType string) (*extensionapi.StatusResponse, error) {
+	defer telemetryapi.RecordAPICallLatency("init_error", time.Now())
+	return a.API.InitError(ctx, errorType)
+}
+
+func (a timedAPI) ExitError(ctx context.Context, errorType string) (*extensionapi.StatusResponse, error) {
+	defer telemetryapi.RecordAPICallLatency("exit_error", time.Now())
+	return a.API.ExitError(ctx, errorType)
+}
+
+// InitErrorRequest reports the details of the error if the client supports
+// it, else its type only.
+func (a timedAPI) InitErrorRequest(ctx context.Context, errorRequest extensionapi.ErrorRequest) (*extensionapi.StatusResponse, error) {
+	client, ok := a.API.(extensionapi.ErrorRequestAPI)
+	if !ok {
+		return a.InitError(ctx, errorRequest.ErrorType)
+	}
+
+	defer telemetryapi.RecordAPICallLatency("init_error", time.Now())
+	return client.InitErrorRequest(ctx, errorRequest)
+}
+
+// ExitErrorRequest reports the details of the error if the client supports
+// it, else its type only.
+func (a timedAPI) ExitErrorRequest(ctx context.Context, errorRequest extensionapi.ErrorRequest) (*extensionapi.StatusResponse, error) {
+	client, ok := a.API.(extensionapi.ErrorRequestAPI)
+	if !ok {
+		return a.ExitError(ctx, errorRequest.ErrorType)
+	}
+
+	defer telemetryapi.RecordAPICallLatency("exit_error", time.Now())
+	return client.ExitErrorRequest(ctx, errorRequest)
+}
@@ -0,0 +1,536 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/confmap/provider/s3provider"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/batchconverter"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/decoupleconverter"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/disablequeuedretryconverter"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/kmsdecryptconverter"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/logconfigconverter"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/memorylimiterconverter"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/migrateconverter"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/otlpenvconverter"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/resourceattributesconverter"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/syncexportconverter"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/unusedconverter"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/provider/cacheprovider"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/provider/fileprovider"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/provider/jsonprovider"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/provider/profileprovider"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/provider/secretsmanagerprovider"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/provider/sigv4httpprovider"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/utility"
+	"github.com/tiqqe/go-logger"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/converter/expandconverter"
+	"go.opentelemetry.io/collector/confmap/provider/envprovider"
+	"go.opentelemetry.io/collector/confmap/provider/httpprovider"
+	"go.opentelemetry.io/collector/confmap/provider/yamlprovider"
+	"go.opentelemetry.io/collector/service"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zapgrpc"
+	"google.golang.org/grpc/grpclog"
+)
+
+var (
+	// Version variable will be replaced at link time after `make` has been run.
+	Version = "latest"
+
+	// GitHash variable will be replaced at link time after `make` has been run.
+	GitHash = "<NOT PROPERLY GENERATED>"
+)
+
+const (
+	// decoupleType is the type of the decouple processor, registered by the Manager
+	decoupleType component.Type = "decouple"
+	// resourceType is the type of the processor adding the resource
+	// attributes of the function, see resourceAttributesFromEnv
+	resourceType component.Type = "resource"
+	// memoryLimiterType is the type of the processor added to the pipelines
+	// when the configuration limits no memory
+	memoryLimiterType component.Type = "memory_limiter"
+	// configCacheDir holds the configurations cached by the s3 provider
+	configCacheDir = "/tmp/otel-lambda/config-cache"
+	// defaultS3ConfigCacheTTL is how long configurations read from S3 are cached
+	defaultS3ConfigCacheTTL = 5 * time.Minute
+)
+
+// Collector is the OpenTelemetry Collector run by the Manager, by default a
+// ServiceCollector running in the extension process.
+type Collector interface {
+	// Start starts the collector, returning once its pipelines are running.
+	Start(ctx context.Context) error
+	// Stop shuts the collector down, waiting for its pipelines to flush until
+	// ctx is done.
+	Stop(ctx context.Context) error
+	// Validate loads the configuration of the collector and checks it is
+	// valid, without starting the collector.
+	Validate(ctx context.Context) error
+	// Exited reports whether the collector stopped running without being
+	// stopped, e.g. after a fatal component error.
+	Exited() bool
+	// Err returns the error the collector stopped running with, if any.
+	Err() error
+	// State describes the state of the collector, e.g. Running.
+	State() string
+}
+
+// defaultLayerConfigFile is the collector configuration bundled with the
+// layer, used if OPENTELEMETRY_COLLECTOR_CONFIG_FILE isn't set
+const defaultLayerConfigFile = "/opt/collector-config/config.yaml"
+
+// defaultConfig is the configuration used if the layer bundles none
+//
+//go:embed config.yaml
+var defaultConfig string
+
+// ServiceCollector implements Collector, running a single otelcol as a go
+// routine within the same process as the extension.
+type ServiceCollector struct {
+	factories      component.Factories
+	configProvider service.ConfigProvider
+	svc            *service.Collector
+	appDone        chan struct{}
+	// runErr is the error the service stopped with, set before appDone is closed
+	runErr  error
+	stopped bool
+}
+
+// grpcLoggerOnce sets the gRPC logger of the process, see setGRPCLogger
+var grpcLoggerOnce sync.Once
+
+// setGRPCLogger sets the logger of the gRPC components, at the warn level of
+// the collector logs, once for the process. The gRPC logger is global and
+// set without synchronization, so the collector services, which would set it
+// on each start, e.g. while the gRPC servers of an isolated configuration or
+// of a collector being replaced still log, leave it alone.
+func setGRPCLogger() {
+	grpcLoggerOnce.Do(func() {
+		logger := zap.New(nil, utility.CustomLoggerOptions()...).With(zap.Bool("grpc_log", true))
+		grpclog.SetLoggerV2(zapgrpc.NewLogger(logger))
+	})
+}
+
+// getConfig returns the URI of the collector configuration, set by the
+// OPENTELEMETRY_COLLECTOR_CONFIG_FILE environment variable, or the YAML or JSON
+// configuration set by OPENTELEMETRY_COLLECTOR_CONFIG_CONTENT, or the first
+// configuration bundled with a layer found, see configSearchPathsFromEnv, or
+// else the default configuration embedded in the extension. The configuration
+// is handed as is to the resolver, so any valid collector configuration can be
+// used.
+func getConfig() string {
+	val, ex := os.LookupEnv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE")
+	if !ex {
+		if content := utility.GetEnvString("OPENTELEMETRY_COLLECTOR_CONFIG_CONTENT", ""); content != "" {
+			if jsonprovider.IsJSON([]byte(content)) {
+				return "json:" + content
+			}
+
+			return "yaml:" + content
+		}
+
+		paths := configSearchPathsFromEnv()
+		for _, path := range paths {
+			if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+				return path
+			}
+		}
+
+		logger.InfoStringf("No collector configuration found at %s, using the default configuration", strings.Join(paths, ", "))
+		return "yaml:" + defaultConfig
+	}
+
+	if _, ok := os.LookupEnv("OPENTELEMETRY_COLLECTOR_CONFIG_CONTENT"); ok {
+		utility.LogError(nil, "getConfig", "Ignoring OPENTELEMETRY_COLLECTOR_CONFIG_CONTENT, OPENTELEMETRY_COLLECTOR_CONFIG_FILE is set")
+	}
+
+	return val
+}
+
+// configProfileFromEnv returns the profile selected in the configurations
+// holding several, read from the OTEL_LAMBDA_CONFIG_PROFILE environment
+// variable (default: default).
+func configProfileFromEnv() string {
+	return utility.GetEnvString("OTEL_LAMBDA_CONFIG_PROFILE", "default")
+}
+
+// configFallbackFromEnv returns whether the collector starts with the default
+// configuration embedded in the extension if the configuration set is
+// invalid, read from the OTEL_LAMBDA_CONFIG_FALLBACK environment variable
+// (default: false). The function then keeps exporting to the extension,
+// which writes the telemetry to its logs, instead of failing its init.
+func configFallbackFromEnv() bool {
+	return utility.GetEnvBool("OTEL_LAMBDA_CONFIG_FALLBACK", false)
+}
+
+// s3ConfigCacheTTLFromEnv returns how long the configurations read from S3
+// are cached in the sandbox, read from the OTEL_LAMBDA_S3_CONFIG_CACHE_TTL_MS
+// environment variable (default: 300000). Zero disables the cache.
+func s3ConfigCacheTTLFromEnv() time.Duration {
+	return time.Duration(utility.GetEnvInt("OTEL_LAMBDA_S3_CONFIG_CACHE_TTL_MS", int(defaultS3ConfigCacheTTL.Milliseconds()))) * time.Millisecond
+}
+
+// configURIs returns the URIs of the collector configuration: the one of
+// getConfig, then the overlay set by the OPENTELEMETRY_COLLECTOR_CONFIG_OVERLAY
+// environment variable, if any, merged over it. The overlay is a YAML or JSON
+// configuration holding the settings of the function which differ from the
+// shared configuration.
+func configURIs() []string {
+	uris := []string{getConfig()}
+
+	if overlay := utility.GetEnvString("OPENTELEMETRY_COLLECTOR_CONFIG_OVERLAY", ""); overlay != "" {
+		if jsonprovider.IsJSON([]byte(overlay)) {
+			return append(uris, "json:"+overlay)
+		}
+
+		return append(uris, "yaml:"+overlay)
+	}
+
+	return uris
+}
+
+// configSearchPathsFromEnv returns the paths where the configuration bundled
+// with a layer is looked for, in order of precedence, read from the comma
+// separated OTEL_LAMBDA_CONFIG_SEARCH_PATHS environment variable (default:
+// /opt/collector-config/config.yaml), e.g. when several layers carry one.
+func configSearchPathsFromEnv() []string {
+	var paths []string
+
+	for _, path := range strings.Split(utility.GetEnvString("OTEL_LAMBDA_CONFIG_SEARCH_PATHS", defaultLayerConfigFile), ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
+
+// NewServiceCollector returns a collector running the configuration set by
+// OPENTELEMETRY_COLLECTOR_CONFIG_FILE or OPENTELEMETRY_COLLECTOR_CONFIG_CONTENT,
+// or the configuration of the layer.
+func NewServiceCollector(factories component.Factories) (*ServiceCollector, error) {
+	return newServiceCollectorWithConfig(factories, configURIs()...)
+}
+
+// newServiceCollectorWithConfig returns a collector running the configuration
+// at uris, merged in order.
+func newServiceCollectorWithConfig(factories component.Factories, uris ...string) (*ServiceCollector, error) {
+	// Create Config Provider Settings
+	settings := service.ConfigProviderSettings{
+		ResolverSettings: resolverSettings(uris, factories),
+	}
+
+	// Get new config provider
+	cfgProvider, err := service.NewConfigProvider(settings)
+	if err != nil {
+		err := errors.New("failed on creating config provider")
+		return nil, err
+	}
+
+	collector := &ServiceCollector{
+		factories:      factories,
+		configProvider: cfgProvider,
+	}
+
+	return collector, nil
+}
+
+// resolverSettings returns the settings resolving the configuration at uris,
+// merged in order: its embedded ${scheme:...} URIs are retrieved first, then
+// its environment variables expanded, its encrypted values decrypted and its
+// deprecated settings rewritten. The configuration is then adapted to the
+// sandbox and to the components of factories.
+func resolverSettings(uris []string, factories component.Factories) confmap.ResolverSettings {
+	// Generate the MapProviders for the Config Provider Settings
+	s3 := s3provider.New()
+	if ttl := s3ConfigCacheTTLFromEnv(); ttl > 0 {
+		s3 = cacheprovider.Wrap(s3, configCacheDir, ttl)
+	}
+
+	providers := []confmap.Provider{fileprovider.New(), envprovider.New(), yamlprovider.New(), jsonprovider.New(), httpprovider.New(), sigv4httpprovider.New(), s3, secretsmanagerprovider.New()}
+	mapProvider := make(map[string]confmap.Provider, len(providers))
+
+	profile := configProfileFromEnv()
+	for _, provider := range providers {
+		mapProvider[provider.Scheme()] = profileprovider.Wrap(provider, profile)
+	}
+
+	converters := []confmap.Converter{expandconverter.New(), kmsdecryptconverter.New(), migrateconverter.New(func(warning string) {
+		logger.WarnStringf("Deprecated collector configuration, %s", warning)
+	})}
+	if settings, ok := otlpExporterSettingsFromEnv(); ok {
+		converters = append(converters, otlpenvconverter.New(settings))
+	}
+
+	if disableQueuedRetryFromEnv() {
+		converters = append(converters, disablequeuedretryconverter.New())
+	}
+
+	if batchDefaultsFromEnv() {
+		converters = append(converters, batchconverter.New())
+	}
+
+	if syncExportFromEnv() {
+		// The data held back by these processors would be exported after the invocation
+		converters = append(converters, syncexportconverter.New())
+	} else if _, ok := factories.Processors[decoupleType]; ok && autoDecoupleFromEnv() {
+		converters = append(converters, decoupleconverter.New())
+	}
+
+	if memory := utility.GetEnvInt("AWS_LAMBDA_FUNCTION_MEMORY_SIZE", 0); memory > 0 {
+		// The limits of the host don't apply to the sandbox
+		_, inject := factories.Processors[memoryLimiterType]
+		converters = append(converters, memorylimiterconverter.New(memory, inject))
+	}
+
+	// The components of forks may not include the resource processor
+	if attributes := resourceAttributesFromEnv(); attributes != nil {
+		if _, ok := factories.Processors[resourceType]; ok {
+			converters = append(converters, resourceattributesconverter.New(attributes))
+		}
+	}
+
+	// After the converters adding components, which use them
+	converters = append(converters, unusedconverter.New(func(warning string) {
+		logger.WarnStringf("Unused collector component, %s", warning)
+	}))
+
+	if logConfigFromEnv() {
+		// Last, to log the configuration the collector runs with
+		converters = append(converters, logconfigconverter.New(func(config string) {
+			logger.InfoStringf("Effective collector configuration:\n%s", config)
+		}))
+	}
+
+	return confmap.ResolverSettings{
+		Providers:  mapProvider,
+		URIs:       uris,
+		Converters: converters,
+	}
+}
+
+// Validate loads the configuration of the collector and checks it is valid,
+// without starting the collector.
+func (c *ServiceCollector) Validate(ctx context.Context) error {
+	// The errors are worded as those of the service, see startErrorType
+	cfg, err := c.configProvider.Get(ctx, c.factories)
+	if err != nil {
+		return fmt.Errorf("failed to get config: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return nil
+}
+
+// Start starts the Lambda Layer Collector. If it isn't running within the
+// start timeout, the start is abandoned and reported with the components
+// still starting and the goroutine stacks.
+func (c *ServiceCollector) Start(ctx context.Context) error {
+	if timeout, ok := preflightFromEnv(); ok {
+		if err := c.preflight(ctx, timeout); err != nil {
+			return err
+		}
+	}
+
+	setGRPCLogger()
+
+	recorder := newStartRecorder()
+	defer recorder.stop()
+
+	params := service.CollectorSettings{
+		BuildInfo: component.BuildInfo{
+			Command:     "otelcol-lambda",
+			Description: "Lambda Collector",
+			Version:     Version,
+		},
+		ConfigProvider:        c.configProvider,
+		Factories:             c.factories,
+		SkipSettingGRPCLogger: true,
+		LoggingOptions: append(utility.CustomLoggerOptions(), zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(core, recorder)
+		})),
+	}
+
+	var err error
+	c.svc, err = service.New(params)
+	if err != nil {
+		return err
+	}
+
+	c.appDone = make(chan struct{})
+
+	go func() {
+		defer close(c.appDone)
+		// A panicking component stops the collector instead of the whole extension
+		defer utility.RecoverPanic("Collector", func(err *utility.PanicError) {
+			c.runErr = err
+		})
+
+		c.runErr = c.svc.Run(ctx)
+	}()
+
+	timeout := collectorStartTimeoutFromEnv()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	ticker := time.NewTicker(startPollInterval)
+	defer ticker.Stop()
+
+	for {
+		switch state := c.svc.GetState(); state {
+		case service.StateStarting:
+			select {
+			// The service may have panicked before it could change state
+			case <-c.appDone:
+				return c.runErr
+
+			case <-deadline.C:
+				err := c.newStartTimeoutError(timeout, recorder)
+				c.svc.Shutdown()
+				return err
+
+			case <-ticker.C:
+			}
+
+		case service.StateRunning:
+			return nil
+
+		default:
+			// While waiting for collector start, an error was found. Most likely
+			// an invalid custom collector configuration file.
+			<-c.appDone
+			if c.runErr != nil {
+				return c.runErr
+			}
+
+			return fmt.Errorf("unable to start, otelcol state is %d", state)
+		}
+	}
+}
+
+// unknownComponentPattern matches the errors of configurations using a component type that isn't built in
+var unknownComponentPattern = regexp.MustCompile(`unknown \w+ type: `)
+
+// startErrorType classifies an error of Start into the error type reported to
+// the Extensions API, so a cold start failure can be diagnosed from the
+// platform logs alone.
+func startErrorType(err error) string {
+	var (
+		opErr      *net.OpError
+		panicErr   *utility.PanicError
+		timeoutErr *startTimeoutError
+		preflight  *preflightError
+	)
+	msg := err.Error()
+
+	switch {
+	case errors.As(err, &panicErr):
+		return extensionapi.ErrorPanic
+
+	case errors.As(err, &timeoutErr):
+		return extensionapi.ErrorCollectorStartTimeout
+
+	case errors.As(err, &preflight):
+		return extensionapi.ErrorExporterUnreachable
+
+	case unknownComponentPattern.MatchString(msg):
+		return extensionapi.ErrorUnknownComponent
+
+	case strings.Contains(msg, "failed to get config: "):
+		return extensionapi.ErrorConfigParseFailure
+
+	case strings.Contains(msg, "invalid configuration: "):
+		return extensionapi.ErrorConfigInvalid
+
+	case errors.As(err, &opErr) && opErr.Op == "listen":
+		return extensionapi.ErrorPortBindFailure
+
+	// All the extensions built in are authenticators
+	case strings.Contains(msg, "failed to start extensions: "), strings.Contains(msg, "authenticator"):
+		return extensionapi.ErrorAuthExtensionFailure
+	}
+
+	return extensionapi.ErrorCollectorStartFailure
+}
+
+// Exited reports whether the collector service stopped running without being
+// stopped, e.g. after a fatal component error.
+func (c *ServiceCollector) Exited() bool {
+	if c.stopped {
+		return false
+	}
+
+	select {
+	case <-c.appDone:
+		return true
+	default:
+		return false
+	}
+}
+
+// Err returns the error the collector service stopped running with, if any.
+func (c *ServiceCollector) Err() error {
+	select {
+	case <-c.appDone:
+		return c.runErr
+	default:
+		return nil
+	}
+}
+
+// State returns the state of the collector service, e.g. Running.
+func (c *ServiceCollector) State() string {
+	if c == nil || c.svc == nil {
+		return "NotStarted"
+	}
+
+	return c.svc.GetState().String()
+}
+
+// Stop shutsdown the Lambda Layer Collector. It waits for the pipelines to
+// flush until the context is done.
+func (c *ServiceCollector) Stop(ctx context.Context) error {
+	if !c.stopped {
+		c.stopped = true
+		c.svc.Shutdown()
+	}
+
+	select {
+	case <-c.appDone:
+		return nil
+
+	case <-ctx.Done():
+		return fmt.Errorf("collector did not stop in time: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,874 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/attributesprocessor"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/batchconverter"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/lambdacomponents"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/utility"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/processor/decoupleprocessor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/config/configtelemetry"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+	"go.opentelemetry.io/collector/exporter/loggingexporter"
+	"go.opentelemetry.io/collector/exporter/otlpexporter"
+	"go.opentelemetry.io/collector/exporter/otlphttpexporter"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/batchprocessor"
+	"go.opentelemetry.io/collector/processor/memorylimiterprocessor"
+)
+
+func TestStartErrorType(t *testing.T) {
+	busy, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer busy.Close()
+
+	for name, test := range map[string]struct {
+		config    string
+		errorType string
+	}{
+		"parse failure": {
+			config:    "receivers: [",
+			errorType: extensionapi.ErrorConfigParseFailure,
+		},
+		"unknown component": {
+			config:    strings.Replace(testCollectorConfig, "logging", "unknown", -1),
+			errorType: extensionapi.ErrorUnknownComponent,
+		},
+		"invalid configuration": {
+			config:    strings.Replace(testCollectorConfig, "exporters: [logging]", "exporters: []", 1),
+			errorType: extensionapi.ErrorConfigInvalid,
+		},
+		"port bind failure": {
+			config:    strings.Replace(testCollectorConfig, "localhost:0", busy.Addr().String(), 1),
+			errorType: extensionapi.ErrorPortBindFailure,
+		},
+		"auth extension failure": {
+			config: testCollectorConfig + `
+  extensions: [oidc]
+extensions:
+  oidc:
+    issuer_url: http://localhost:1
+    audience: test
+`,
+			errorType: extensionapi.ErrorAuthExtensionFailure,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(configFile, []byte(test.config), 0600))
+			t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", configFile)
+
+			factories, err := lambdacomponents.Components()
+			require.NoError(t, err)
+
+			collector, err := NewServiceCollector(factories)
+			require.NoError(t, err)
+
+			err = collector.Start(context.Background())
+			require.Error(t, err)
+			assert.Equal(t, test.errorType, startErrorType(err), err.Error())
+		})
+	}
+
+	assert.Equal(t, extensionapi.ErrorCollectorStartFailure, startErrorType(errors.New("unable to start")))
+}
+
+func TestStartPanic(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(strings.Replace(testCollectorConfig, "logging", "panicking", -1)), 0600))
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", configFile)
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	panicking := component.NewExporterFactory(
+		"panicking",
+		func() component.ExporterConfig {
+			cfg := config.NewExporterSettings(component.NewID("panicking"))
+			return &cfg
+		},
+		component.WithTracesExporter(func(context.Context, component.ExporterCreateSettings, component.ExporterConfig) (component.TracesExporter, error) {
+			return exporterhelper.NewTracesExporter(context.Background(), componenttest.NewNopExporterCreateSettings(), &config.ExporterSettings{},
+				func(context.Context, ptrace.Traces) error { return nil },
+				exporterhelper.WithStart(func(context.Context, component.Host) error { panic("exporter bug") }))
+		}, component.StabilityLevelAlpha))
+	factories.Exporters[panicking.Type()] = panicking
+
+	collector, err := NewServiceCollector(factories)
+	require.NoError(t, err)
+
+	// The panic stops the collector, not the extension
+	err = collector.Start(context.Background())
+	var panicErr *utility.PanicError
+	require.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, "exporter bug", panicErr.Value)
+	assert.Equal(t, extensionapi.ErrorPanic, startErrorType(err))
+	assert.True(t, collector.Exited())
+}
+
+func TestStartTimeout(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(strings.Replace(testCollectorConfig, "logging", "blocking", -1)), 0600))
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", configFile)
+	t.Setenv("OTEL_LAMBDA_COLLECTOR_START_TIMEOUT_MS", "200")
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	blocking := component.NewExporterFactory(
+		"blocking",
+		func() component.ExporterConfig {
+			cfg := config.NewExporterSettings(component.NewID("blocking"))
+			return &cfg
+		},
+		component.WithTracesExporter(func(context.Context, component.ExporterCreateSettings, component.ExporterConfig) (component.TracesExporter, error) {
+			return exporterhelper.NewTracesExporter(context.Background(), componenttest.NewNopExporterCreateSettings(), &config.ExporterSettings{},
+				func(context.Context, ptrace.Traces) error { return nil },
+				exporterhelper.WithStart(func(context.Context, component.Host) error {
+					<-release
+					return nil
+				}))
+		}, component.StabilityLevelAlpha))
+	factories.Exporters[blocking.Type()] = blocking
+
+	collector, err := NewServiceCollector(factories)
+	require.NoError(t, err)
+
+	err = collector.Start(context.Background())
+	var timeoutErr *startTimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.Equal(t, extensionapi.ErrorCollectorStartTimeout, startErrorType(err))
+	assert.Equal(t, []string{"exporter/blocking"}, timeoutErr.pending)
+	assert.Equal(t, []string{"traces: [otlp] -> [] -> [blocking]"}, timeoutErr.pipelines)
+	assert.Contains(t, err.Error(), "still starting: exporter/blocking")
+
+	// The stacks show where the start is stuck
+	assert.Contains(t, strings.Join(timeoutErr.StackTrace(), "\n"), "TestStartTimeout")
+}
+
+func TestLayerConfig(t *testing.T) {
+	config := testCollectorConfig + `
+    logs:
+      receivers: [otlp]
+      processors: [attributes, batch/logs]
+      exporters: [logging]
+processors:
+  attributes:
+    actions:
+      - key: team
+        value: lambda
+        action: upsert
+  batch/logs:
+`
+	layerConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("OTEL_LAMBDA_CONFIG_SEARCH_PATHS", layerConfigFile)
+	require.NoError(t, os.WriteFile(layerConfigFile, []byte(config), 0600))
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", "")
+	os.Unsetenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE")
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	collector, err := NewServiceCollector(factories)
+	require.NoError(t, err)
+
+	cfg, err := collector.configProvider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+
+	// Components the extension knows nothing about are kept
+	logs := cfg.Service.Pipelines[component.NewID("logs")]
+	require.NotNil(t, logs)
+	assert.Equal(t, []component.ID{component.NewID("attributes"), component.NewIDWithName("batch", "logs")}, logs.Processors)
+	assert.Contains(t, cfg.Processors, component.NewID("attributes"))
+}
+
+func TestDefaultConfig(t *testing.T) {
+	// The layer bundles no configuration
+	t.Setenv("OTEL_LAMBDA_CONFIG_SEARCH_PATHS", filepath.Join(t.TempDir(), "config.yaml"))
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", "")
+	os.Unsetenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE")
+	assert.Equal(t, "yaml:"+defaultConfig, getConfig())
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	collector, err := NewServiceCollector(factories)
+	require.NoError(t, err)
+
+	cfg, err := collector.configProvider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+
+	for _, pipeline := range []string{"traces", "metrics", "logs"} {
+		assert.Contains(t, cfg.Service.Pipelines, component.NewID(component.Type(pipeline)))
+	}
+}
+
+func TestLayerConfigEnvExpansion(t *testing.T) {
+	config := `
+receivers:
+  otlp:
+    protocols:
+      grpc:
+        endpoint: ${env:GRPC_ENDPOINT}
+      http:
+        endpoint: ${HTTP_HOST}:4318
+exporters:
+  otlp:
+    endpoint: $OTLP_ENDPOINT
+    headers:
+      authorization: Bearer ${TOKEN}
+      escaped: $${TOKEN}
+service:
+  pipelines:
+    traces:
+      receivers: [otlp]
+      exporters: [otlp, "logging/${TEAM}"]
+`
+	layerConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("OTEL_LAMBDA_CONFIG_SEARCH_PATHS", layerConfigFile)
+	require.NoError(t, os.WriteFile(layerConfigFile, []byte(config), 0600))
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", "")
+	os.Unsetenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE")
+
+	// A whole ${env:...} value is expanded again if it is a placeholder itself
+	t.Setenv("GRPC_ENDPOINT", "${env:GRPC_ADDRESS}")
+	t.Setenv("GRPC_ADDRESS", "localhost:4317")
+	t.Setenv("HTTP_HOST", "localhost")
+	t.Setenv("OTLP_ENDPOINT", "otlp.example.com:4317")
+	t.Setenv("TOKEN", "s3cr3t")
+	t.Setenv("TEAM", "team")
+
+	resolver, err := confmap.NewResolver(resolverSettings([]string{getConfig()}, component.Factories{}))
+	require.NoError(t, err)
+
+	conf, err := resolver.Resolve(context.Background())
+	require.NoError(t, err)
+
+	for key, expected := range map[string]interface{}{
+		"receivers::otlp::protocols::grpc::endpoint": "localhost:4317",
+		"receivers::otlp::protocols::http::endpoint": "localhost:4318",
+		"exporters::otlp::endpoint":                  "otlp.example.com:4317",
+		"exporters::otlp::headers::authorization":    "Bearer s3cr3t",
+		"exporters::otlp::headers::escaped":          "${TOKEN}",
+		"service::pipelines::traces::exporters":      []interface{}{"otlp", "logging/team"},
+	} {
+		assert.Equal(t, expected, conf.Get(key), key)
+	}
+}
+
+func TestConfigSearchPaths(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing.yaml")
+	first := filepath.Join(dir, "first.yaml")
+	second := filepath.Join(dir, "second.yaml")
+	require.NoError(t, os.WriteFile(first, []byte(testCollectorConfig), 0600))
+	require.NoError(t, os.WriteFile(second, []byte(testCollectorConfig), 0600))
+
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", "")
+	os.Unsetenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE")
+
+	for _, tc := range []struct {
+		name     string
+		paths    string
+		expected string
+	}{
+		{
+			name:     "first found",
+			paths:    missing + ", " + first + "," + second,
+			expected: first,
+		},
+		{
+			name:     "none found",
+			paths:    missing,
+			expected: "yaml:" + defaultConfig,
+		},
+		{
+			name:     "no paths",
+			paths:    "",
+			expected: "yaml:" + defaultConfig,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("OTEL_LAMBDA_CONFIG_SEARCH_PATHS", tc.paths)
+			assert.Equal(t, tc.expected, getConfig())
+		})
+	}
+
+	t.Setenv("OTEL_LAMBDA_CONFIG_SEARCH_PATHS", "")
+	os.Unsetenv("OTEL_LAMBDA_CONFIG_SEARCH_PATHS")
+	assert.Equal(t, []string{defaultLayerConfigFile}, configSearchPathsFromEnv())
+}
+
+func TestConfigContent(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_CONTENT", strings.Replace(testCollectorConfig, "exporters: [logging]", `exporters: ["${EXPORTER}"]`, 1))
+	t.Setenv("EXPORTER", "logging")
+
+	// The configuration file takes precedence
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", configFile)
+	assert.Equal(t, configFile, getConfig())
+
+	os.Unsetenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE")
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	collector, err := NewServiceCollector(factories)
+	require.NoError(t, err)
+
+	cfg, err := collector.configProvider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, []component.ID{component.NewID("logging")}, cfg.Service.Pipelines[component.NewID("traces")].Exporters)
+}
+
+func TestJSONConfig(t *testing.T) {
+	config := `{
+	"receivers": {"otlp": {"protocols": {"grpc": {"endpoint": "localhost:0"}}}},
+	"exporters": {"otlphttp": {"endpoint": "https:\/\/example.com"}},
+	"service": {
+		"telemetry": {"metrics": {"level": "none"}},
+		"pipelines": {"traces": {"receivers": ["otlp"], "exporters": ["${EXPORTER}"]}}
+	}
+}`
+	t.Setenv("EXPORTER", "otlphttp")
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_CONTENT", config)
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", "")
+	os.Unsetenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE")
+	assert.Equal(t, "json:"+config, getConfig())
+
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	for _, uri := range []string{"", configFile} {
+		if uri != "" {
+			t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", uri)
+		}
+
+		collector, err := NewServiceCollector(factories)
+		require.NoError(t, err)
+
+		cfg, err := collector.configProvider.Get(context.Background(), factories)
+		require.NoError(t, err)
+		require.NoError(t, cfg.Validate())
+		assert.Equal(t, []component.ID{component.NewID("otlphttp")}, cfg.Service.Pipelines[component.NewID("traces")].Exporters)
+		assert.Equal(t, "https://example.com", cfg.Exporters[component.NewID("otlphttp")].(*otlphttpexporter.Config).Endpoint)
+	}
+}
+
+func TestConfigOverlay(t *testing.T) {
+	config := testCollectorConfig + `
+processors:
+  attributes:
+    actions:
+      - key: team
+        value: lambda
+        action: upsert
+`
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", configFile)
+	assert.Equal(t, []string{getConfig()}, configURIs())
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	for _, overlay := range []string{
+		`
+exporters:
+  otlp:
+    endpoint: ${ENDPOINT}
+processors:
+  attributes:
+    actions:
+      - key: function
+        value: my-function
+        action: upsert
+service:
+  pipelines:
+    traces:
+      processors: [attributes]
+      exporters: [otlp]
+`,
+		`{"exporters": {"otlp": {"endpoint": "${ENDPOINT}"}}, "processors": {"attributes": {"actions": [{"key": "function", "value": "my-function", "action": "upsert"}]}}, "service": {"pipelines": {"traces": {"processors": ["attributes"], "exporters": ["otlp"]}}}}`,
+	} {
+		t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_OVERLAY", overlay)
+		t.Setenv("ENDPOINT", "collector.example.com:4317")
+		assert.Len(t, configURIs(), 2)
+
+		collector, err := NewServiceCollector(factories)
+		require.NoError(t, err)
+
+		cfg, err := collector.configProvider.Get(context.Background(), factories)
+		require.NoError(t, err)
+		require.NoError(t, cfg.Validate())
+
+		// Maps are merged, lists replaced
+		traces := cfg.Service.Pipelines[component.NewID("traces")]
+		assert.Equal(t, []component.ID{component.NewID("otlp")}, traces.Exporters)
+		assert.Equal(t, []component.ID{component.NewID("otlp")}, traces.Receivers)
+		assert.Equal(t, "collector.example.com:4317", cfg.Exporters[component.NewID("otlp")].(*otlpexporter.Config).Endpoint)
+		assert.Contains(t, cfg.Exporters, component.NewID("logging"))
+
+		attributes := cfg.Processors[component.NewID("attributes")].(*attributesprocessor.Config)
+		require.Len(t, attributes.Actions, 1)
+		assert.Equal(t, "function", attributes.Actions[0].Key)
+	}
+}
+
+func TestConfigProfile(t *testing.T) {
+	config := strings.Replace(testCollectorConfig, "exporters:\n", "exporters:\n  otlp:\n    endpoint: localhost:4317\n", 1) + `
+profiles:
+  default:
+  prod:
+    exporters:
+      otlp:
+        endpoint: ${PROD_ENDPOINT}
+    service:
+      pipelines:
+        traces:
+          exporters: [otlp]
+  staging:
+    exporters:
+      otlp:
+        endpoint: ${secretsmanager://staging-endpoint}
+`
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", configFile)
+	t.Setenv("PROD_ENDPOINT", "collector.example.com:4317")
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		profile   string
+		exporters []component.ID
+		endpoint  string
+	}{
+		{profile: "", exporters: []component.ID{component.NewID("logging")}, endpoint: "localhost:4317"},
+		// The secret of the staging profile isn't retrieved
+		{profile: "prod", exporters: []component.ID{component.NewID("otlp")}, endpoint: "collector.example.com:4317"},
+	} {
+		if tc.profile != "" {
+			t.Setenv("OTEL_LAMBDA_CONFIG_PROFILE", tc.profile)
+		}
+
+		collector, err := NewServiceCollector(factories)
+		require.NoError(t, err)
+
+		cfg, err := collector.configProvider.Get(context.Background(), factories)
+		require.NoError(t, err)
+		require.NoError(t, cfg.Validate())
+		assert.Equal(t, tc.exporters, cfg.Service.Pipelines[component.NewID("traces")].Exporters)
+		assert.Equal(t, tc.endpoint, cfg.Exporters[component.NewID("otlp")].(*otlpexporter.Config).Endpoint)
+	}
+
+	t.Setenv("OTEL_LAMBDA_CONFIG_PROFILE", "test")
+	collector, err := NewServiceCollector(factories)
+	require.NoError(t, err)
+
+	_, err = collector.configProvider.Get(context.Background(), factories)
+	assert.ErrorContains(t, err, `has no profile "test", its profiles are: default, prod, staging`)
+}
+
+func TestMigrateConfig(t *testing.T) {
+	config := strings.Replace(testCollectorConfig, "  logging:\n", "  logging:\n    loglevel: debug\nextensions:\n  memory_ballast:\n    size_mib: 64\n", 1)
+	config = strings.Replace(config, "service:\n", "service:\n  extensions: [memory_ballast]\n", 1)
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", configFile)
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	collector, err := NewServiceCollector(factories)
+	require.NoError(t, err)
+
+	cfg, err := collector.configProvider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+	assert.Empty(t, cfg.Service.Extensions)
+
+	exporter := cfg.Exporters[component.NewID("logging")].(*loggingexporter.Config)
+	assert.Equal(t, configtelemetry.LevelDetailed, exporter.Verbosity)
+}
+
+func TestSyncExport(t *testing.T) {
+	config := strings.Replace(testCollectorConfig, "exporters: [logging]", "processors: [batch]\n      exporters: [logging]", 1) + "processors:\n  batch:\n"
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", configFile)
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	for _, sync := range []bool{false, true} {
+		t.Setenv("OTEL_LAMBDA_SYNC_EXPORT", strconv.FormatBool(sync))
+
+		collector, err := NewServiceCollector(factories)
+		require.NoError(t, err)
+
+		cfg, err := collector.configProvider.Get(context.Background(), factories)
+		require.NoError(t, err)
+		require.NoError(t, cfg.Validate())
+
+		// The batch processor is left out of the pipeline
+		processors := cfg.Service.Pipelines[component.NewID("traces")].Processors
+		assert.Equal(t, !sync, len(processors) == 1)
+	}
+}
+
+func TestSyncExportQueues(t *testing.T) {
+	config := strings.Replace(testCollectorConfig, "exporters: [logging]", "exporters: [otlphttp]", 1)
+	config = strings.Replace(config, "exporters:\n", "exporters:\n  otlphttp:\n    endpoint: http://localhost:4318\n", 1)
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", configFile)
+	t.Setenv("OTEL_LAMBDA_SYNC_EXPORT", "true")
+	t.Setenv("OTEL_LAMBDA_DISABLE_QUEUED_RETRY", "false")
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	collector, err := NewServiceCollector(factories)
+	require.NoError(t, err)
+
+	cfg, err := collector.configProvider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+
+	// The queue would export after the invocation
+	exporter := cfg.Exporters[component.NewID("otlphttp")].(*otlphttpexporter.Config)
+	assert.False(t, exporter.QueueSettings.Enabled)
+}
+
+func TestSyncExportBufferingProcessors(t *testing.T) {
+	config := strings.Replace(testCollectorConfig, "exporters: [logging]", "processors: [groupbytrace]\n      exporters: [logging]", 1) + "processors:\n  groupbytrace:\n"
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", configFile)
+	t.Setenv("OTEL_LAMBDA_SYNC_EXPORT", "true")
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	collector, err := NewServiceCollector(factories)
+	require.NoError(t, err)
+
+	_, err = collector.configProvider.Get(context.Background(), factories)
+	assert.ErrorContains(t, err, "the groupbytrace processor of the traces pipeline holds data back")
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	writeTestCollectorConfig(t)
+	t.Setenv("AWS_LAMBDA_FUNCTION_MEMORY_SIZE", "512")
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	collector, err := NewServiceCollector(factories)
+	require.NoError(t, err)
+
+	cfg, err := collector.configProvider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, []component.ID{component.NewID("memory_limiter")}, cfg.Service.Pipelines[component.NewID("traces")].Processors)
+
+	processor := cfg.Processors[component.NewID("memory_limiter")].(*memorylimiterprocessor.Config)
+	assert.Equal(t, uint32(128), processor.MemoryLimitMiB)
+
+	// The components of a fork without the memory_limiter processor
+	delete(factories.Processors, memoryLimiterType)
+
+	collector, err = NewServiceCollector(factories)
+	require.NoError(t, err)
+
+	cfg, err = collector.configProvider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+	assert.Empty(t, cfg.Service.Pipelines[component.NewID("traces")].Processors)
+}
+
+func TestResourceAttributes(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(testCollectorConfig), 0600))
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", configFile)
+	t.Setenv("AWS_LAMBDA_FUNCTION_NAME", "my-function")
+	t.Setenv("AWS_LAMBDA_FUNCTION_VERSION", "$LATEST")
+	t.Setenv("AWS_LAMBDA_FUNCTION_MEMORY_SIZE", "512")
+	t.Setenv("AWS_REGION", "eu-west-1")
+
+	assert.Equal(t, map[string]interface{}{
+		"cloud.provider":  "aws",
+		"cloud.region":    "eu-west-1",
+		"faas.name":       "my-function",
+		"faas.version":    "$LATEST",
+		"faas.max_memory": 512,
+	}, resourceAttributesFromEnv())
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	for _, enabled := range []bool{false, true} {
+		t.Setenv("OTEL_LAMBDA_RESOURCE_ATTRIBUTES", strconv.FormatBool(enabled))
+
+		collector, err := NewServiceCollector(factories)
+		require.NoError(t, err)
+
+		cfg, err := collector.configProvider.Get(context.Background(), factories)
+		require.NoError(t, err)
+		require.NoError(t, cfg.Validate())
+
+		// After the memory_limiter processor added for the memory of the function
+		processors := cfg.Service.Pipelines[component.NewID("traces")].Processors
+		if enabled {
+			assert.Equal(t, []component.ID{component.NewID("memory_limiter"), component.NewIDWithName("resource", "lambda")}, processors)
+		} else {
+			assert.Equal(t, []component.ID{component.NewID("memory_limiter")}, processors)
+		}
+	}
+}
+
+func TestResourceAttributesWithoutResourceProcessor(t *testing.T) {
+	writeTestCollectorConfig(t)
+	t.Setenv("AWS_LAMBDA_FUNCTION_NAME", "my-function")
+
+	// The components of a fork without the resource processor
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+	delete(factories.Processors, resourceType)
+
+	collector, err := NewServiceCollector(factories)
+	require.NoError(t, err)
+
+	cfg, err := collector.configProvider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+	assert.Empty(t, cfg.Service.Pipelines[component.NewID("traces")].Processors)
+}
+
+func TestBatchDefaults(t *testing.T) {
+	config := strings.Replace(testCollectorConfig, "exporters: [logging]", "processors: [batch]\n      exporters: [otlphttp]", 1) + `
+processors:
+  batch:
+    timeout: 10s
+`
+	config = strings.Replace(config, "exporters:\n", `exporters:
+  otlphttp:
+    endpoint: http://localhost:4318
+    sending_queue:
+      storage: file_storage
+`, 1)
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", configFile)
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	for _, enabled := range []bool{false, true} {
+		t.Setenv("OTEL_LAMBDA_BATCH_DEFAULTS", strconv.FormatBool(enabled))
+
+		collector, err := NewServiceCollector(factories)
+		require.NoError(t, err)
+
+		cfg, err := collector.configProvider.Get(context.Background(), factories)
+		require.NoError(t, err)
+		require.NoError(t, cfg.Validate())
+
+		batch := cfg.Processors[component.NewID("batch")].(*batchprocessor.Config)
+		exporter := cfg.Exporters[component.NewID("otlphttp")].(*otlphttpexporter.Config)
+		if enabled {
+			assert.Equal(t, batchconverter.MaxBatchTimeout, batch.Timeout)
+			assert.Nil(t, exporter.QueueSettings.StorageID)
+		} else {
+			assert.Equal(t, 10*time.Second, batch.Timeout)
+			assert.NotNil(t, exporter.QueueSettings.StorageID)
+		}
+	}
+}
+
+func TestDisableQueuedRetry(t *testing.T) {
+	config := strings.Replace(testCollectorConfig, "exporters: [logging]", "exporters: [otlphttp]", 1)
+	config = strings.Replace(config, "exporters:\n", "exporters:\n  otlphttp:\n    endpoint: http://localhost:4318\n", 1)
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", configFile)
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	for _, disabled := range []bool{false, true} {
+		t.Setenv("OTEL_LAMBDA_DISABLE_QUEUED_RETRY", strconv.FormatBool(disabled))
+
+		collector, err := NewServiceCollector(factories)
+		require.NoError(t, err)
+
+		cfg, err := collector.configProvider.Get(context.Background(), factories)
+		require.NoError(t, err)
+		require.NoError(t, cfg.Validate())
+
+		exporter := cfg.Exporters[component.NewID("otlphttp")].(*otlphttpexporter.Config)
+		assert.Equal(t, !disabled, exporter.QueueSettings.Enabled)
+	}
+}
+
+func TestLogConfig(t *testing.T) {
+	writeTestCollectorConfig(t)
+	t.Setenv("OTEL_LAMBDA_LOG_CONFIG", "true")
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	collector, err := NewServiceCollector(factories)
+	require.NoError(t, err)
+
+	// The configuration is logged as it is resolved, and left as is
+	cfg, err := collector.configProvider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, []component.ID{component.NewID("logging")}, cfg.Service.Pipelines[component.NewID("traces")].Exporters)
+}
+
+func TestAutoDecouple(t *testing.T) {
+	writeTestCollectorConfig(t)
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	// Without the decouple processor, e.g. in a collector of its own
+	collector, err := NewServiceCollector(factories)
+	require.NoError(t, err)
+
+	cfg, err := collector.configProvider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Service.Pipelines[component.NewID("traces")].Processors)
+
+	decouple := decoupleprocessor.NewFactory()
+	factories.Processors[decouple.Type()] = decouple
+
+	for _, tc := range []struct {
+		auto     bool
+		sync     bool
+		expected []component.ID
+	}{
+		{auto: true, expected: []component.ID{component.NewID("decouple")}},
+		{auto: false},
+		{auto: true, sync: true},
+	} {
+		t.Setenv("OTEL_LAMBDA_AUTO_DECOUPLE", strconv.FormatBool(tc.auto))
+		t.Setenv("OTEL_LAMBDA_SYNC_EXPORT", strconv.FormatBool(tc.sync))
+
+		collector, err := NewServiceCollector(factories)
+		require.NoError(t, err)
+
+		cfg, err := collector.configProvider.Get(context.Background(), factories)
+		require.NoError(t, err)
+		require.NoError(t, cfg.Validate())
+		assert.Equal(t, tc.expected, cfg.Service.Pipelines[component.NewID("traces")].Processors)
+	}
+}
+
+func TestOTLPExporterEnv(t *testing.T) {
+	config := strings.Replace(testCollectorConfig, "exporters: [logging]", "exporters: [otlphttp]", 1)
+	config = strings.Replace(config, "exporters:\n", "exporters:\n  otlphttp:\n    endpoint: http://localhost:4318\n    headers:\n      x-scope: team\n", 1)
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", configFile)
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "api-key=secret")
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		endpoint         string
+		protocol         string
+		expectedEndpoint string
+		expectedHeaders  map[string]string
+	}{
+		{
+			endpoint:         "https://otlp.example.com",
+			expectedEndpoint: "https://otlp.example.com",
+			expectedHeaders:  map[string]string{"x-scope": "team", "api-key": "secret"},
+		},
+		{
+			endpoint:         "https://otlp.example.com",
+			protocol:         "grpc",
+			expectedEndpoint: "http://localhost:4318",
+			expectedHeaders:  map[string]string{"x-scope": "team"},
+		},
+		{
+			// The SDK of the function exports to the collector
+			endpoint:         "http://localhost:4318",
+			expectedEndpoint: "http://localhost:4318",
+			expectedHeaders:  map[string]string{"x-scope": "team"},
+		},
+	} {
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", tc.endpoint)
+		t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", tc.protocol)
+
+		collector, err := NewServiceCollector(factories)
+		require.NoError(t, err)
+
+		cfg, err := collector.configProvider.Get(context.Background(), factories)
+		require.NoError(t, err)
+		require.NoError(t, cfg.Validate())
+
+		exporter := cfg.Exporters[component.NewID("otlphttp")].(*otlphttpexporter.Config)
+		assert.Equal(t, tc.expectedEndpoint, exporter.Endpoint)
+		assert.Equal(t, tc.expectedHeaders, exporter.Headers)
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	for endpoint, expected := range map[string]bool{
+		"http://localhost:4318":     true,
+		"localhost:4317":            true,
+		"http://127.0.0.1:4318":     true,
+		"[::1]:4317":                true,
+		"https://otlp.example.com":  false,
+		"otlp.example.com:4317":     false,
+		"http://10.0.0.1:4318/otlp": false,
+	} {
+		assert.Equal(t, expected, isLoopback(endpoint), endpoint)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	"errors"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi"
+)
+
+// Exit codes of the extension process by class of failure, see ExitCode.
+const (
+	// ExitCodeFailure is the exit code of failures of no other class
+	ExitCodeFailure = 1
+	// ExitCodeRuntimeAPI is the exit code of failures to register or to receive the next event
+	ExitCodeRuntimeAPI = 2
+	// ExitCodeTelemetryAPI is the exit code of failures to start the listener or to subscribe
+	ExitCodeTelemetryAPI = 3
+	// ExitCodeConfig is the exit code of missing, unparsable or invalid collector configurations
+	ExitCodeConfig = 4
+	// ExitCodeCollector is the exit code of the other failures to start the collector
+	ExitCodeCollector = 5
+	// ExitCodeExport is the exit code of failures to flush the exporters on shutdown
+	ExitCodeExport = 6
+	// ExitCodeHook is the exit code of failures of the lifecycle hooks
+	ExitCodeHook = 7
+	// ExitCodePanic is the exit code of panics of the extension or of the collector
+	ExitCodePanic = 8
+)
+
+// exitCodes maps the error types reported to the Extensions API to exit codes
+var exitCodes = map[string]int{
+	extensionapi.ErrorRegisterFailure:       ExitCodeRuntimeAPI,
+	extensionapi.ErrorNextEventFailure:      ExitCodeRuntimeAPI,
+	extensionapi.ErrorListenerStartFailure:  ExitCodeTelemetryAPI,
+	extensionapi.ErrorSubscribeFailure:      ExitCodeTelemetryAPI,
+	extensionapi.ErrorConfigParseFailure:    ExitCodeConfig,
+	extensionapi.ErrorUnknownComponent:      ExitCodeConfig,
+	extensionapi.ErrorConfigInvalid:         ExitCodeConfig,
+	extensionapi.ErrorPortBindFailure:       ExitCodeCollector,
+	extensionapi.ErrorAuthExtensionFailure:  ExitCodeCollector,
+	extensionapi.ErrorCollectorStartFailure: ExitCodeCollector,
+	extensionapi.ErrorCollectorStartTimeout: ExitCodeCollector,
+	extensionapi.ErrorExporterUnreachable:   ExitCodeCollector,
+	extensionapi.ErrorExportFailure:         ExitCodeExport,
+	extensionapi.ErrorHookFailure:           ExitCodeHook,
+	extensionapi.ErrorPanic:                 ExitCodePanic,
+}
+
+// Error is a failure the extension reported to the Extensions API before
+// giving up, returned by New and Run.
+type Error struct {
+	// Type is the error type reported, e.g. Extension.RegisterFailure
+	Type string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Type
+	}
+
+	return e.Type + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of the extension process for an error
+// returned by New or Run: 0 if nil, or the code of the class of its error
+// type.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var lifecycleErr *Error
+	if errors.As(err, &lifecycleErr) {
+		if code, ok := exitCodes[lifecycleErr.Type]; ok {
+			return code
+		}
+	}
+
+	return ExitCodeFailure
+}
+
+// stackTracer is implemented by the errors carrying stack traces worth
+// reporting along with them, e.g. those of a stuck collector start
+type stackTracer interface {
+	StackTrace() []string
+}
+
+// errorRequest returns the details of err reported to the Extensions API
+// along with its type.
+func errorRequest(errorType string, err error) extensionapi.ErrorRequest {
+	request := extensionapi.ErrorRequest{ErrorType: errorType}
+	if err != nil {
+		request.ErrorMessage = err.Error()
+	}
+
+	var tracer stackTracer
+	if errors.As(err, &tracer) {
+		request.StackTrace = tracer.StackTrace()
+	}
+
+	return request
+}
+
+// initError reports an init failure to the Extensions API, with its details
+// if the client supports it, and returns it.
+func initError(ctx context.Context, client extensionapi.API, errorType string, err error) error {
+	if reporter, ok := client.(extensionapi.ErrorRequestAPI); ok {
+		reporter.InitErrorRequest(ctx, errorRequest(errorType, err))
+	} else {
+		client.InitError(ctx, errorType)
+	}
+
+	return &Error{Type: errorType, Err: err}
+}
+
+// exitError reports a failure after the init to the Extensions API, with its
+// details if the client supports it, and returns it.
+func (lm *Manager) exitError(ctx context.Context, errorType string, err error) error {
+	if reporter, ok := lm.extensionClient.(extensionapi.ErrorRequestAPI); ok {
+		reporter.ExitErrorRequest(ctx, errorRequest(errorType, err))
+	} else {
+		lm.extensionClient.ExitError(ctx, errorType)
+	}
+
+	return &Error{Type: errorType, Err: err}
+}
@@ -0,0 +1,78 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi/extensionapitest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"success", nil, 0},
+		{"register", &Error{Type: extensionapi.ErrorRegisterFailure}, ExitCodeRuntimeAPI},
+		{"subscribe", &Error{Type: extensionapi.ErrorSubscribeFailure}, ExitCodeTelemetryAPI},
+		{"config", &Error{Type: extensionapi.ErrorUnknownComponent}, ExitCodeConfig},
+		{"collector", &Error{Type: extensionapi.ErrorPortBindFailure}, ExitCodeCollector},
+		{"export", &Error{Type: extensionapi.ErrorExportFailure}, ExitCodeExport},
+		{"hook", &Error{Type: extensionapi.ErrorHookFailure}, ExitCodeHook},
+		{"panic", &Error{Type: extensionapi.ErrorPanic}, ExitCodePanic},
+		{"wrapped", fmt.Errorf("init: %w", &Error{Type: extensionapi.ErrorConfigInvalid}), ExitCodeConfig},
+		{"unknown type", &Error{Type: "Extension.Custom"}, ExitCodeFailure},
+		{"other", errors.New("failure"), ExitCodeFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ExitCode(tt.err))
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := &Error{Type: extensionapi.ErrorRegisterFailure, Err: cause}
+
+	assert.EqualError(t, err, "Extension.RegisterFailure: connection refused")
+	assert.ErrorIs(t, err, cause)
+	assert.EqualError(t, &Error{Type: extensionapi.ErrorPanic}, "Extension.Panic")
+}
+
+func TestInitErrorRequest(t *testing.T) {
+	client := &extensionapitest.Fake{}
+	cause := &startTimeoutError{timeout: time.Second, pending: []string{"exporter/otlp"}, stacks: "goroutine 1 [running]:\nmain.main()\n"}
+
+	err := initError(context.Background(), timedAPI{client}, extensionapi.ErrorCollectorStartTimeout, cause)
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, ExitCodeCollector, ExitCode(err))
+
+	assert.Equal(t, []string{"InitError"}, client.Calls())
+	assert.Equal(t, []extensionapi.ErrorRequest{{
+		ErrorType:    extensionapi.ErrorCollectorStartTimeout,
+		ErrorMessage: "collector not running after 1s, still starting: exporter/otlp",
+		StackTrace:   []string{"goroutine 1 [running]:", "main.main()"},
+	}}, client.ErrorRequests())
+}
@@ -0,0 +1,243 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/converter/otlpenvconverter"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/utility"
+	"github.com/tiqqe/go-logger"
+)
+
+// extensionEventTypesFromEnv returns the events to register for, read from
+// the comma separated OTEL_LAMBDA_EXTENSION_EVENTS environment variable
+// (default: INVOKE,SHUTDOWN). SHUTDOWN is always included since the extension
+// relies on it to flush its telemetry before the environment is shut down.
+func extensionEventTypesFromEnv() []extensionapi.EventType {
+	invoke := false
+
+	for _, val := range strings.Split(utility.GetEnvString("OTEL_LAMBDA_EXTENSION_EVENTS", string(extensionapi.Invoke)+","+string(extensionapi.Shutdown)), ",") {
+		switch eventType := extensionapi.EventType(strings.ToUpper(strings.TrimSpace(val))); eventType {
+		case extensionapi.Invoke:
+			invoke = true
+		case extensionapi.Shutdown, "":
+			// Always registered
+		default:
+			utility.LogError(nil, "extensionEventTypesFromEnv", "Ignoring unknown extension event type", utility.KeyValue{K: "type", V: eventType})
+		}
+	}
+
+	if !invoke {
+		return []extensionapi.EventType{extensionapi.Shutdown}
+	}
+
+	return []extensionapi.EventType{extensionapi.Invoke, extensionapi.Shutdown}
+}
+
+const (
+	lifecycleModeActive  = "active"
+	lifecycleModePassive = "passive"
+)
+
+// passiveFromEnv returns whether the extension runs in passive mode, read
+// from the OTEL_LAMBDA_LIFECYCLE_MODE environment variable (active or
+// passive, default: active). In active mode each invocation waits for its
+// platform.runtimeDone event and the export of its telemetry, in passive mode
+// the extension asks for the next event at once. An extension registered for
+// SHUTDOWN only is always passive.
+func passiveFromEnv(events []extensionapi.EventType) bool {
+	if len(events) == 1 && events[0] == extensionapi.Shutdown {
+		return true
+	}
+
+	switch mode := strings.ToLower(strings.TrimSpace(utility.GetEnvString("OTEL_LAMBDA_LIFECYCLE_MODE", lifecycleModeActive))); mode {
+	case lifecycleModePassive:
+		return true
+	case lifecycleModeActive:
+	default:
+		utility.LogError(nil, "passiveFromEnv", "Ignoring unknown lifecycle mode", utility.KeyValue{K: "mode", V: mode})
+	}
+
+	return false
+}
+
+// disableQueuedRetryFromEnv returns whether the sending queues of the
+// exporters are disabled, read from the OTEL_LAMBDA_DISABLE_QUEUED_RETRY
+// environment variable (default: true). Exporters then send the telemetry
+// before the pipelines are flushed, instead of in the background.
+func disableQueuedRetryFromEnv() bool {
+	return utility.GetEnvBool("OTEL_LAMBDA_DISABLE_QUEUED_RETRY", true)
+}
+
+// autoDecoupleFromEnv returns whether the decouple processor is added to the
+// pipelines of the collector configurations not using one, read from the
+// OTEL_LAMBDA_AUTO_DECOUPLE environment variable (default: true). It isn't
+// added in sync export mode, see syncExportFromEnv.
+func autoDecoupleFromEnv() bool {
+	return utility.GetEnvBool("OTEL_LAMBDA_AUTO_DECOUPLE", true)
+}
+
+// defaultPreflightTimeout bounds the check of each exporter endpoint
+const defaultPreflightTimeout = 2 * time.Second
+
+// preflightFromEnv returns how long each exporter endpoint is given to be
+// reached before the collector starts, read from the
+// OTEL_LAMBDA_PREFLIGHT_TIMEOUT_MS environment variable (default: 2000), and
+// whether the endpoints are checked, read from the
+// OTEL_LAMBDA_PREFLIGHT_CHECK environment variable (default: false).
+func preflightFromEnv() (time.Duration, bool) {
+	if !utility.GetEnvBool("OTEL_LAMBDA_PREFLIGHT_CHECK", false) {
+		return 0, false
+	}
+
+	return time.Duration(utility.GetEnvInt("OTEL_LAMBDA_PREFLIGHT_TIMEOUT_MS", int(defaultPreflightTimeout.Milliseconds()))) * time.Millisecond, true
+}
+
+// batchDefaultsFromEnv returns whether the batching of the collector
+// configurations is adapted to the sandbox, read from the
+// OTEL_LAMBDA_BATCH_DEFAULTS environment variable (default: true). The
+// timeouts of the batch processors are then lowered, and the persistent
+// storage of the sending queues removed.
+func batchDefaultsFromEnv() bool {
+	return utility.GetEnvBool("OTEL_LAMBDA_BATCH_DEFAULTS", true)
+}
+
+// syncExportFromEnv returns whether the export of the telemetry of each
+// invocation must be acknowledged before the extension asks for the next
+// event, read from the OTEL_LAMBDA_SYNC_EXPORT environment variable (default:
+// false). The batch and decouple processors are then left out of the
+// pipelines, and the queues of the exporters disabled, so the exports are
+// done once the telemetry is flushed. Pipelines with processors holding data
+// back, such as groupbytrace, are rejected.
+func syncExportFromEnv() bool {
+	return utility.GetEnvBool("OTEL_LAMBDA_SYNC_EXPORT", false)
+}
+
+// logConfigFromEnv returns whether the effective collector configuration is
+// logged, with its secrets masked, each time it is loaded, read from the
+// OTEL_LAMBDA_LOG_CONFIG environment variable (default: false).
+func logConfigFromEnv() bool {
+	return utility.GetEnvBool("OTEL_LAMBDA_LOG_CONFIG", false)
+}
+
+// resourceAttributesFromEnv returns the cloud and faas resource attributes of
+// the function, read from the environment variables set by Lambda, to add to
+// the telemetry of all pipelines. It returns nil outside of Lambda or if the
+// OTEL_LAMBDA_RESOURCE_ATTRIBUTES environment variable is false (default:
+// true).
+func resourceAttributesFromEnv() map[string]interface{} {
+	name := utility.GetEnvString("AWS_LAMBDA_FUNCTION_NAME", "")
+	if name == "" || !utility.GetEnvBool("OTEL_LAMBDA_RESOURCE_ATTRIBUTES", true) {
+		return nil
+	}
+
+	attributes := map[string]interface{}{
+		"cloud.provider": "aws",
+		"faas.name":      name,
+	}
+
+	if region := utility.GetEnvString("AWS_REGION", ""); region != "" {
+		attributes["cloud.region"] = region
+	}
+
+	if version := utility.GetEnvString("AWS_LAMBDA_FUNCTION_VERSION", ""); version != "" {
+		attributes["faas.version"] = version
+	}
+
+	if memory := utility.GetEnvInt("AWS_LAMBDA_FUNCTION_MEMORY_SIZE", 0); memory > 0 {
+		attributes["faas.max_memory"] = memory
+	}
+
+	return attributes
+}
+
+// otlpExporterSettingsFromEnv returns the settings of the OTLP exporters read
+// from the OTEL_EXPORTER_OTLP_ENDPOINT, OTEL_EXPORTER_OTLP_HEADERS and
+// OTEL_EXPORTER_OTLP_PROTOCOL environment variables of the SDKs, and whether
+// any is set. They are ignored if the endpoint is a loopback address: the SDK
+// of the function then exports to the collector, which must not export to
+// itself.
+func otlpExporterSettingsFromEnv() (otlpenvconverter.Settings, bool) {
+	settings := otlpenvconverter.Settings{
+		Endpoint: utility.GetEnvString("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		Protocol: utility.GetEnvString("OTEL_EXPORTER_OTLP_PROTOCOL", ""),
+	}
+
+	if headers := utility.GetEnvString("OTEL_EXPORTER_OTLP_HEADERS", ""); headers != "" {
+		var err error
+		if settings.Headers, err = otlpenvconverter.ParseHeaders(headers); err != nil {
+			utility.LogError(err, "otlpExporterSettingsFromEnv", "Ignoring invalid OTEL_EXPORTER_OTLP_HEADERS")
+		}
+	}
+
+	if settings.Endpoint == "" && len(settings.Headers) == 0 {
+		return settings, false
+	}
+
+	if isLoopback(settings.Endpoint) {
+		logger.InfoStringf("Ignoring the OTEL_EXPORTER_OTLP_* environment variables, %s is the collector itself", settings.Endpoint)
+		return settings, false
+	}
+
+	return settings, true
+}
+
+// isLoopback returns whether the host of endpoint, a URL or a host:port, is a
+// loopback address.
+func isLoopback(endpoint string) bool {
+	host := endpoint
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// defaultCollectorStartTimeout bounds the wait for the collector to run
+const defaultCollectorStartTimeout = 5 * time.Second
+
+// collectorStartTimeoutFromEnv returns how long the collector is given to
+// start, read from the OTEL_LAMBDA_COLLECTOR_START_TIMEOUT_MS environment
+// variable.
+func collectorStartTimeoutFromEnv() time.Duration {
+	return time.Duration(utility.GetEnvInt("OTEL_LAMBDA_COLLECTOR_START_TIMEOUT_MS", int(defaultCollectorStartTimeout.Milliseconds()))) * time.Millisecond
+}
+
+// extensionTimeoutsFromEnv returns the default Extensions API timeouts, with
+// the timeout of the endpoints other than /event/next read from the
+// OTEL_LAMBDA_EXTENSION_API_TIMEOUT_MS environment variable.
+func extensionTimeoutsFromEnv() extensionapi.Timeouts {
+	timeouts := extensionapi.DefaultTimeouts()
+	timeout := time.Duration(utility.GetEnvInt("OTEL_LAMBDA_EXTENSION_API_TIMEOUT_MS", int(timeouts.Register.Milliseconds()))) * time.Millisecond
+
+	timeouts.Register = timeout
+	timeouts.Error = timeout
+
+	return timeouts
+}
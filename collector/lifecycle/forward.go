@@ -0,0 +1,230 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/telemetryapi"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/utility"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+)
+
+// forwardTimeout bounds each request made to the forwarding endpoint
+const forwardTimeout = 5 * time.Second
+
+// forwardingCollector implements Collector without the otelcol service: it
+// converts the Telemetry API events dispatched by the listener and sends
+// them to an OTLP/HTTP endpoint, for functions which only need the platform
+// telemetry and their logs, at a fraction of the memory of the collector.
+type forwardingCollector struct {
+	listener   *telemetryapi.Listener
+	endpoint   string
+	headers    map[string]string
+	httpClient *http.Client
+
+	// converters holds a converter per signal, as converters track the
+	// current invocation across the batches they convert
+	traces  *telemetryapi.Converter
+	metrics *telemetryapi.Converter
+	logs    *telemetryapi.Converter
+
+	mu      sync.Mutex
+	started bool
+	stopped bool
+}
+
+// forwardEndpointFromEnv returns the OTLP/HTTP endpoint the Telemetry API
+// events are forwarded to in place of running the collector, read from the
+// OTEL_LAMBDA_FORWARD_ENDPOINT environment variable. Empty if unset.
+func forwardEndpointFromEnv() string {
+	return strings.TrimSuffix(utility.GetEnvString("OTEL_LAMBDA_FORWARD_ENDPOINT", ""), "/")
+}
+
+// forwardHeadersFromEnv returns the headers sent to the forwarding endpoint,
+// read from the comma separated key=value pairs of the
+// OTEL_LAMBDA_FORWARD_HEADERS environment variable.
+func forwardHeadersFromEnv() map[string]string {
+	headers := map[string]string{}
+
+	for _, pair := range strings.Split(utility.GetEnvString("OTEL_LAMBDA_FORWARD_HEADERS", ""), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		key, value, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(key) == "" {
+			utility.LogError(nil, "forwardHeadersFromEnv", "Ignoring invalid forwarding header", utility.KeyValue{K: "header", V: pair})
+			continue
+		}
+
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return headers
+}
+
+func newForwardingCollector(listener *telemetryapi.Listener, endpoint string, headers map[string]string) *forwardingCollector {
+	return &forwardingCollector{
+		listener:   listener,
+		endpoint:   endpoint,
+		headers:    headers,
+		httpClient: &http.Client{Timeout: forwardTimeout},
+		traces:     telemetryapi.NewConverter(telemetryapi.ConverterConfig{}),
+		metrics:    telemetryapi.NewConverter(telemetryapi.ConverterConfig{}),
+		logs:       telemetryapi.NewConverter(telemetryapi.ConverterConfig{}),
+	}
+}
+
+// Validate checks the Telemetry API events can be forwarded.
+func (c *forwardingCollector) Validate(_ context.Context) error {
+	if c.listener == nil {
+		return fmt.Errorf("forwarding to %s requires the Telemetry API", c.endpoint)
+	}
+
+	return nil
+}
+
+// Start subscribes the collector to the events of the listener.
+func (c *forwardingCollector) Start(ctx context.Context) error {
+	if err := c.Validate(ctx); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.started = true
+	c.mu.Unlock()
+
+	c.listener.AddConsumer(c)
+
+	return nil
+}
+
+// Stop unsubscribes the collector from the listener. As events are sent as
+// they are dispatched, there is nothing left to flush.
+func (c *forwardingCollector) Stop(_ context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.started && !c.stopped {
+		c.stopped = true
+		c.listener.RemoveConsumer(c)
+	}
+
+	return nil
+}
+
+// Exited always returns false, the collector has nothing running on its own.
+func (c *forwardingCollector) Exited() bool {
+	return false
+}
+
+// Err always returns nil, failed requests are logged.
+func (c *forwardingCollector) Err() error {
+	return nil
+}
+
+// State returns Forwarding once started, until stopped.
+func (c *forwardingCollector) State() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case c.stopped:
+		return "Closed"
+	case c.started:
+		return "Forwarding"
+	}
+
+	return "NotStarted"
+}
+
+// ConsumeEvents converts a batch of events and sends each signal to the
+// endpoint. The events of a failed request are dropped.
+func (c *forwardingCollector) ConsumeEvents(ctx context.Context, events []telemetryapi.Event) {
+	function := c.listener.FunctionARN()
+	traceContexts := c.listener.TraceContexts()
+	for _, converter := range []*telemetryapi.Converter{c.traces, c.metrics, c.logs} {
+		converter.SetFunctionARN(function)
+		converter.SetTraceContexts(traceContexts)
+	}
+
+	if traces := c.traces.ToTraces(events); traces.SpanCount() > 0 {
+		c.send(ctx, "/v1/traces", func() ([]byte, error) {
+			return ptraceotlp.NewExportRequestFromTraces(traces).MarshalProto()
+		})
+	}
+
+	if metrics := c.metrics.ToMetrics(events); metrics.DataPointCount() > 0 {
+		c.send(ctx, "/v1/metrics", func() ([]byte, error) {
+			return pmetricotlp.NewExportRequestFromMetrics(metrics).MarshalProto()
+		})
+	}
+
+	if logs := c.logs.ToLogs(events); logs.LogRecordCount() > 0 {
+		c.send(ctx, "/v1/logs", func() ([]byte, error) {
+			return plogotlp.NewExportRequestFromLogs(logs).MarshalProto()
+		})
+	}
+}
+
+// send posts the OTLP request returned by marshal to path, logging failures.
+func (c *forwardingCollector) send(ctx context.Context, path string, marshal func() ([]byte, error)) {
+	url := c.endpoint + path
+
+	if err := c.post(ctx, url, marshal); err != nil {
+		utility.LogError(err, "forwardingCollector", "Failed to forward telemetry", utility.KeyValue{K: "url", V: url})
+	}
+}
+
+func (c *forwardingCollector) post(ctx context.Context, url string, marshal func() ([]byte, error)) error {
+	body, err := marshal()
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/x-protobuf")
+	for key, value := range c.headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("request to %s failed: %s", url, response.Status)
+	}
+
+	return nil
+}
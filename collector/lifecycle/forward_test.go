@@ -0,0 +1,129 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/lambdaemulator"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/telemetryapi"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+)
+
+func TestLifecycleForwarding(t *testing.T) {
+	t.Setenv("AWS_SAM_LOCAL", "true")
+
+	var (
+		mu       sync.Mutex
+		requests = map[string]int{}
+		logs     int
+	)
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "secret", r.Header.Get("X-Api-Key"))
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests[r.URL.Path]++
+
+		if r.URL.Path == "/v1/logs" {
+			request := plogotlp.NewExportRequest()
+			require.NoError(t, request.UnmarshalProto(body))
+			logs += request.Logs().LogRecordCount()
+		}
+	}))
+	defer endpoint.Close()
+
+	t.Setenv("OTEL_LAMBDA_FORWARD_ENDPOINT", endpoint.URL+"/")
+	t.Setenv("OTEL_LAMBDA_FORWARD_HEADERS", "X-Api-Key=secret")
+	t.Setenv("OTEL_LAMBDA_TELEMETRY_TYPES", "platform,function")
+	// The collector configuration isn't read in forwarding mode
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", "/nonexistent/config.yaml")
+
+	invocation := lambdaemulator.NewInvocation("1", telemetryapi.StatusSuccess, "hello")
+	invocation.Timeout = time.Minute
+
+	emulator := lambdaemulator.New(invocation)
+	address, err := emulator.Start("127.0.0.1:0")
+	require.NoError(t, err)
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", address)
+
+	ctx, lm, err := New(context.Background(), Settings{})
+	require.NoError(t, err)
+	assert.Nil(t, lm.configReloader)
+
+	require.NoError(t, lm.processEvents(ctx))
+	require.NoError(t, emulator.Close())
+	assert.Empty(t, emulator.Errors())
+	assert.Equal(t, "Closed", lm.collector.State())
+
+	// The telemetry of the invocation is sent before the next event is requested
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, requests["/v1/metrics"])
+	assert.Equal(t, 1, requests["/v1/logs"])
+	assert.Positive(t, logs)
+	// The platform events of the emulator report no spans
+	assert.Zero(t, requests["/v1/traces"])
+}
+
+func TestLifecycleForwardingWithoutTelemetryAPI(t *testing.T) {
+	t.Setenv("OTEL_LAMBDA_DISABLE_TELEMETRY_API", "true")
+	t.Setenv("OTEL_LAMBDA_FORWARD_ENDPOINT", "http://localhost:4318")
+
+	emulator := lambdaemulator.New()
+	address, err := emulator.Start("127.0.0.1:0")
+	require.NoError(t, err)
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", address)
+
+	_, lm, err := New(context.Background(), Settings{})
+	assert.Nil(t, lm)
+	assert.Equal(t, ExitCodeCollector, ExitCode(err))
+
+	require.NoError(t, emulator.Close())
+	assert.Equal(t, []string{extensionapi.ErrorCollectorStartFailure}, emulator.Errors())
+}
+
+func TestForwardHeadersFromEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected map[string]string
+	}{
+		{"unset", "", map[string]string{}},
+		{"single", "Authorization=Bearer token", map[string]string{"Authorization": "Bearer token"}},
+		{"several", " a=1 , b=x=y,", map[string]string{"a": "1", "b": "x=y"}},
+		{"invalid", "a,=2,c=3", map[string]string{"c": "3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_LAMBDA_FORWARD_HEADERS", tt.value)
+			assert.Equal(t, tt.expected, forwardHeadersFromEnv())
+		})
+	}
+}
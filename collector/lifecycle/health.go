@@ -0,0 +1,95 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/utility"
+)
+
+// healthStatus is the state of the extension reported by the health endpoint.
+type healthStatus struct {
+	LastEventType  extensionapi.EventType `json:"lastEventType,omitempty"`
+	LastRequestID  string                 `json:"lastRequestId,omitempty"`
+	CollectorState string                 `json:"collectorState"`
+	// QueueSize is the number of events waiting in the Telemetry API listener queue
+	QueueSize int64 `json:"queueSize"`
+}
+
+// lastEvent is the last event received from the Extensions API.
+type lastEvent struct {
+	eventType extensionapi.EventType
+	requestID string
+	mu        sync.Mutex
+}
+
+func (e *lastEvent) set(response *extensionapi.NextEventResponse) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.eventType = response.EventType
+	e.requestID = response.RequestID
+}
+
+func (e *lastEvent) get() (extensionapi.EventType, string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.eventType, e.requestID
+}
+
+// startHealthServer serves the state of the extension as JSON on address,
+// e.g. "localhost:4324", to debug it inside the sandbox or with SAM local.
+func startHealthServer(address string, lm *Manager) (*http.Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", lm.healthHandler)
+
+	server := &http.Server{Handler: mux}
+	go func() {
+		err := server.Serve(listener)
+		if err != http.ErrServerClosed {
+			utility.LogError(err, "HealthServer", "Unexpected stop on HTTP Server")
+		}
+	}()
+
+	return server, nil
+}
+
+func (lm *Manager) healthHandler(w http.ResponseWriter, _ *http.Request) {
+	var status healthStatus
+	status.LastEventType, status.LastRequestID = lm.lastEvent.get()
+	status.CollectorState = "NotStarted"
+	if collector := lm.currentCollector(); collector != nil {
+		status.CollectorState = collector.State()
+	}
+
+	if lm.listener != nil {
+		status.QueueSize = lm.listener.QueueSize()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,92 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/utility"
+)
+
+// Hooks are callbacks run at points of the extension lifecycle, e.g. to warm
+// a cache or refresh secrets. Extensions built on this package pass them in
+// Settings.Hooks, while forks of the layer can register them from the init
+// function of a file of their own, leaving main.go untouched:
+//
+//	func init() {
+//		lifecycle.RegisterHooks(lifecycle.Hooks{
+//			OnInvoke: func(ctx context.Context, event *extensionapi.NextEventResponse) error {
+//				return refreshSecrets(ctx)
+//			},
+//		})
+//	}
+//
+// Hooks run on the goroutine processing the events, so they hold up the
+// extension while they run. Any of them may be nil.
+type Hooks struct {
+	// OnInit runs once the extension is registered and the collector started,
+	// unless its start is deferred. An error fails the extension init.
+	OnInit func(ctx context.Context) error
+	// OnInvoke runs when an INVOKE event is received, while the function runs.
+	// Its ctx carries the metadata of the invocation, see invocation.FromContext.
+	OnInvoke EventHook
+	// OnRuntimeDone runs once the function finished an invocation and its
+	// telemetry is flushed. It only runs in active mode with the Telemetry
+	// API, as the end of an invocation isn't known otherwise.
+	OnRuntimeDone EventHook
+	// OnShutdown runs when the SHUTDOWN event is received, before the
+	// collector is stopped, so telemetry emitted by the hook is exported.
+	OnShutdown EventHook
+}
+
+// EventHook is a lifecycle hook run on an event of the Extensions API.
+type EventHook func(ctx context.Context, event *extensionapi.NextEventResponse) error
+
+// registeredHooks are the hooks of the Managers created afterwards
+var registeredHooks []Hooks
+
+// RegisterHooks adds hooks run by the Managers created afterwards, in the
+// order they are registered.
+func RegisterHooks(hooks Hooks) {
+	registeredHooks = append(registeredHooks, hooks)
+}
+
+// runInitHooks runs the OnInit hooks, stopping at the first error.
+func (lm *Manager) runInitHooks(ctx context.Context) error {
+	for _, hooks := range lm.hooks {
+		if hooks.OnInit == nil {
+			continue
+		}
+
+		if err := hooks.OnInit(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runEventHooks runs the event hook selected by hook from each registered
+// set, logging their errors.
+func (lm *Manager) runEventHooks(ctx context.Context, name string, event *extensionapi.NextEventResponse, hook func(Hooks) EventHook) {
+	for _, hooks := range lm.hooks {
+		if h := hook(hooks); h != nil {
+			if err := h(ctx, event); err != nil {
+				utility.LogError(err, "LifecycleHooks", "Lifecycle hook failed", utility.KeyValue{K: "hook", V: name}, utility.KeyValue{K: "request_id", V: event.RequestID})
+			}
+		}
+	}
+}
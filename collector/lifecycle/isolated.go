@@ -0,0 +1,129 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/utility"
+	"go.uber.org/multierr"
+)
+
+// isolatedConfigsFromEnv returns the URIs of the collector configurations
+// run as service instances of their own next to the main configuration, read
+// from the comma separated OTEL_LAMBDA_ISOLATED_CONFIGS environment variable.
+func isolatedConfigsFromEnv() []string {
+	var uris []string
+
+	for _, uri := range strings.Split(utility.GetEnvString("OTEL_LAMBDA_ISOLATED_CONFIGS", ""), ",") {
+		if uri = strings.TrimSpace(uri); uri != "" {
+			uris = append(uris, uri)
+		}
+	}
+
+	return uris
+}
+
+// multiCollector implements Collector by running several collectors, e.g.
+// service instances of isolated configurations, which don't share any
+// pipeline, extension or authenticator. They start, stop and restart
+// together. They share the gRPC logger of the process, see setGRPCLogger.
+type multiCollector struct {
+	collectors []Collector
+}
+
+func newMultiCollector(collectors []Collector) *multiCollector {
+	return &multiCollector{collectors: collectors}
+}
+
+// Validate validates the configuration of each collector.
+func (c *multiCollector) Validate(ctx context.Context) error {
+	var errs []error
+	for i, collector := range c.collectors {
+		if err := collector.Validate(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("collector %d: %w", i, err))
+		}
+	}
+
+	return multierr.Combine(errs...)
+}
+
+// Start starts the collectors in order. If one of them can't start, the
+// ones already started are stopped.
+func (c *multiCollector) Start(ctx context.Context) error {
+	for i, collector := range c.collectors {
+		if err := collector.Start(ctx); err != nil {
+			for _, started := range c.collectors[:i] {
+				if stopErr := started.Stop(ctx); stopErr != nil {
+					utility.LogError(stopErr, "multiCollector", "Failed stopping a collector after another one failed to start")
+				}
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Stop stops every collector, in the reverse order of their start.
+func (c *multiCollector) Stop(ctx context.Context) error {
+	var errs []error
+	for i := len(c.collectors) - 1; i >= 0; i-- {
+		errs = append(errs, c.collectors[i].Stop(ctx))
+	}
+
+	return multierr.Combine(errs...)
+}
+
+// Exited reports whether any of the collectors stopped running on its own.
+func (c *multiCollector) Exited() bool {
+	for _, collector := range c.collectors {
+		if collector.Exited() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Err returns the errors the collectors stopped running with, if any.
+func (c *multiCollector) Err() error {
+	var errs []error
+	for _, collector := range c.collectors {
+		errs = append(errs, collector.Err())
+	}
+
+	return multierr.Combine(errs...)
+}
+
+// State returns the state shared by the collectors, or their states joined
+// with commas if they differ, e.g. Running,Closed.
+func (c *multiCollector) State() string {
+	states := make([]string, len(c.collectors))
+	same := true
+	for i, collector := range c.collectors {
+		states[i] = collector.State()
+		same = same && states[i] == states[0]
+	}
+
+	if same && len(states) > 0 {
+		return states[0]
+	}
+
+	return strings.Join(states, ",")
+}
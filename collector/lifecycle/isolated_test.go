@@ -0,0 +1,113 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi/extensionapitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsolatedConfigsFromEnv(t *testing.T) {
+	t.Setenv("OTEL_LAMBDA_ISOLATED_CONFIGS", "")
+	assert.Nil(t, isolatedConfigsFromEnv())
+
+	t.Setenv("OTEL_LAMBDA_ISOLATED_CONFIGS", " /opt/ops.yaml, ,s3://bucket.s3.eu-west-1.amazonaws.com/team.yaml")
+	assert.Equal(t, []string{"/opt/ops.yaml", "s3://bucket.s3.eu-west-1.amazonaws.com/team.yaml"}, isolatedConfigsFromEnv())
+}
+
+func TestLifecycleIsolatedConfigs(t *testing.T) {
+	t.Setenv("OTEL_LAMBDA_DISABLE_TELEMETRY_API", "true")
+	writeTestCollectorConfig(t)
+
+	isolatedConfig := filepath.Join(t.TempDir(), "isolated.yaml")
+	require.NoError(t, os.WriteFile(isolatedConfig, []byte(testCollectorConfig), 0600))
+	t.Setenv("OTEL_LAMBDA_ISOLATED_CONFIGS", isolatedConfig)
+
+	client := &extensionapitest.Fake{
+		Events: []extensionapi.NextEventResponse{
+			{EventType: extensionapi.Invoke, RequestID: "1"},
+			{EventType: extensionapi.Shutdown, ShutdownReason: extensionapi.Spindown},
+		},
+	}
+
+	ctx, lm, err := New(context.Background(), Settings{ExtensionClient: client})
+	require.NoError(t, err)
+
+	multi, ok := lm.collector.(*multiCollector)
+	require.True(t, ok)
+	require.Len(t, multi.collectors, 2)
+	assert.Equal(t, "Running", multi.State())
+
+	require.NoError(t, lm.Run(ctx))
+
+	for _, collector := range multi.collectors {
+		assert.True(t, collector.(*ServiceCollector).stopped)
+	}
+	assert.Empty(t, client.ErrorTypes())
+}
+
+// fakeCollector is a Collector recording its calls.
+type fakeCollector struct {
+	name     string
+	startErr error
+	exited   bool
+	calls    *[]string
+}
+
+func (c *fakeCollector) Start(context.Context) error {
+	*c.calls = append(*c.calls, "start "+c.name)
+	return c.startErr
+}
+
+func (c *fakeCollector) Stop(context.Context) error {
+	*c.calls = append(*c.calls, "stop "+c.name)
+	return nil
+}
+
+func (c *fakeCollector) Validate(context.Context) error { return nil }
+func (c *fakeCollector) Exited() bool                   { return c.exited }
+func (c *fakeCollector) Err() error                     { return nil }
+func (c *fakeCollector) State() string                  { return c.name }
+
+func TestMultiCollector(t *testing.T) {
+	var calls []string
+	first := &fakeCollector{name: "first", calls: &calls}
+	second := &fakeCollector{name: "second", calls: &calls}
+	third := &fakeCollector{name: "third", calls: &calls, startErr: errors.New("port in use")}
+
+	multi := newMultiCollector([]Collector{first, second})
+	require.NoError(t, multi.Start(context.Background()))
+	require.NoError(t, multi.Stop(context.Background()))
+	assert.Equal(t, []string{"start first", "start second", "stop second", "stop first"}, calls)
+	assert.Equal(t, "first,second", multi.State())
+
+	assert.False(t, multi.Exited())
+	second.exited = true
+	assert.True(t, multi.Exited())
+
+	// The collectors started are stopped when one fails to start
+	calls = nil
+	multi = newMultiCollector([]Collector{first, third})
+	assert.EqualError(t, multi.Start(context.Background()), "port in use")
+	assert.Equal(t, []string{"start first", "start third", "stop first"}, calls)
+}
@@ -0,0 +1,832 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lifecycle implements the lifecycle of the collector extension: it
+// registers with the Extensions API, receives the Telemetry API events and
+// runs the collector until the SHUTDOWN event. It can be embedded in the
+// extension binaries of other teams, with their own components and hooks:
+//
+//	ctx, lm, err := lifecycle.New(context.Background(), lifecycle.Settings{
+//		Components: myComponents,
+//	})
+//	if err == nil {
+//		err = lm.Run(ctx)
+//	}
+//	os.Exit(lifecycle.ExitCode(err))
+package lifecycle
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/provider/cacheprovider"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/lazycomponent"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/telemetryapi"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/lambdacomponents"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/invocation"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/utility"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/processor/decoupleprocessor"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/receiver/telemetryapireceiver"
+	"github.com/tiqqe/go-logger"
+	"go.opentelemetry.io/collector/component"
+)
+
+var (
+	extensionName = filepath.Base(os.Args[0]) // extension name has to match the filename
+)
+
+const (
+	// registerAttempts bounds the Register calls made before the extension gives up
+	registerAttempts = 5
+	// registerBackoff is the delay before the first Register retry, doubled after each failure
+	registerBackoff = 50 * time.Millisecond
+	// nextEventRetryWindow bounds the time spent retrying failed NextEvent calls before the extension gives up
+	nextEventRetryWindow = time.Second
+	// nextEventBackoff is the delay before the first NextEvent retry, doubled after each failure
+	nextEventBackoff = 50 * time.Millisecond
+	// shutdownDrainPeriod is how long the Telemetry API must stay quiet on SHUTDOWN before a
+	// passive extension stops listening, a few times the buffering timeout of its subscription
+	shutdownDrainPeriod = 300 * time.Millisecond
+	// collectorRestartAttempts bounds the restarts of a collector which stopped on its own before the extension gives up
+	collectorRestartAttempts = 3
+	// collectorRestartBackoff is the delay before the second restart attempt, doubled after each failure
+	collectorRestartBackoff = 100 * time.Millisecond
+	// shutdownDeadlineMargin leaves time to exit before the environment is killed after an abnormal shutdown
+	shutdownDeadlineMargin = 100 * time.Millisecond
+)
+
+// Settings customize a Manager, e.g. in extensions of other teams built on
+// the lifecycle of this one. The zero value runs the extension of the layer.
+type Settings struct {
+	// ExtensionClient calls the Extensions API, by default at the address of
+	// AWS_LAMBDA_RUNTIME_API.
+	ExtensionClient extensionapi.API
+	// Components returns the components the collector is built with, given
+	// the receivers of the extension to add. lambdacomponents.Components by
+	// default.
+	Components func(additionalReceivers ...component.ReceiverFactory) (component.Factories, error)
+	// NewCollector returns a collector running the given components, ready
+	// to start. NewServiceCollector by default.
+	NewCollector func(factories component.Factories) (Collector, error)
+	// Hooks run custom code at points of the lifecycle, after the hooks added
+	// with RegisterHooks.
+	Hooks []Hooks
+}
+
+// Manager runs the extension: it registers with the Extensions API, receives
+// the Telemetry API events, and runs the collector they are turned into
+// telemetry by.
+type Manager struct {
+	// collector is set once started, see startCollector
+	collector          Collector
+	collectorMu        sync.RWMutex
+	collectorOnce      sync.Once
+	collectorErrorType string
+	collectorErr       error
+
+	extensionClient extensionapi.API
+	// components and collectorFactory build the collector, see Settings
+	components       func(additionalReceivers ...component.ReceiverFactory) (component.Factories, error)
+	collectorFactory func(factories component.Factories) (Collector, error)
+	// lazyPipelines defers creating the exporters and processors until the first telemetry reaches them
+	lazyPipelines bool
+	// forwardEndpoint is set in forwarding mode, see forwardEndpointFromEnv:
+	// the Telemetry API events are sent to it instead of running the collector
+	forwardEndpoint string
+	// collectorBinary is the otelcol binary run as a child process instead
+	// of the collector of the extension, if set, see collectorBinaryFromEnv
+	collectorBinary string
+	// isolatedConfigs are the URIs of the configurations run by service
+	// instances of their own, see isolatedConfigsFromEnv
+	isolatedConfigs []string
+	// configFallback starts the collector with the default configuration if
+	// the configuration set is invalid, see configFallbackFromEnv
+	configFallback bool
+	// invoked is set once the first INVOKE event is received, see invocationInfo
+	invoked bool
+	// passive is set in the passive lifecycle mode, see passiveFromEnv: the
+	// telemetry is streamed as it comes instead of being awaited at the end of
+	// each invocation
+	passive         bool
+	listener        *telemetryapi.Listener
+	telemetryClient *telemetryapi.Client
+
+	// eventTypes are the desired Telemetry API event types, applied at the next invocation boundary
+	eventTypes   []telemetryapi.EventType
+	eventTypesMu sync.Mutex
+
+	// configReloader restarts the collector when its configuration changes, if enabled
+	configReloader *configReloader
+
+	// hooks run custom code at points of the lifecycle, see RegisterHooks
+	hooks []Hooks
+
+	// lastEvent is reported by the health endpoint, if enabled
+	lastEvent    lastEvent
+	healthServer *http.Server
+}
+
+// New registers the extension, subscribes to the Telemetry API and starts the
+// collector. If the init fails, it is reported to the Extensions API and
+// returned as an *Error. The returned context is cancelled on SIGTERM and
+// SIGINT.
+func New(ctx context.Context, settings Settings) (context.Context, *Manager, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		<-sigs
+		cancel()
+	}()
+
+	// Record the latency of the calls to the Runtime API from the start
+	telemetryapi.RegisterMetricViews()
+
+	// Step 1: Register the Lambda Extension API
+	client := settings.ExtensionClient
+	if client == nil {
+		client = extensionapi.NewClient(os.Getenv("AWS_LAMBDA_RUNTIME_API"), extensionapi.SchemaVersionLatest, extensionTimeoutsFromEnv())
+	}
+
+	extensionClient := timedAPI{client}
+	events := extensionEventTypesFromEnv()
+	response, err := register(ctx, extensionClient, events)
+	if err != nil {
+		utility.LogError(err, "LifecycleManager", "Cannot register extension.")
+		return ctx, nil, initError(ctx, extensionClient, extensionapi.ErrorRegisterFailure, err)
+	}
+
+	lm := &Manager{
+		extensionClient:  extensionClient,
+		components:       settings.Components,
+		collectorFactory: settings.NewCollector,
+		lazyPipelines:    utility.GetEnvBool("OTEL_LAMBDA_LAZY_PIPELINES", false),
+		forwardEndpoint:  forwardEndpointFromEnv(),
+		collectorBinary:  collectorBinaryFromEnv(),
+		isolatedConfigs:  isolatedConfigsFromEnv(),
+		configFallback:   configFallbackFromEnv(),
+		passive:          passiveFromEnv(events),
+		hooks:            append(append([]Hooks(nil), registeredHooks...), settings.Hooks...),
+	}
+
+	if lm.passive && syncExportFromEnv() {
+		utility.LogError(nil, "LifecycleManager", "The exports can't be awaited in passive mode, OTEL_LAMBDA_SYNC_EXPORT only removes the batch and decouple processors")
+	}
+
+	lazyStart := utility.GetEnvBool("OTEL_LAMBDA_LAZY_COLLECTOR_START", false)
+
+	if utility.GetEnvBool("OTEL_LAMBDA_DISABLE_TELEMETRY_API", false) {
+		logger.InfoStringf("Telemetry API integration is disabled")
+	} else {
+		// Step 2: Start the local HTTP listener which will receive data from Telemetry API
+		listenerConfig := telemetryapi.ListenerConfigFromEnv()
+		// The receivers of a lazily started collector consume the events queued in the meantime
+		listenerConfig.DeferDispatch = lazyStart
+		listener := telemetryapi.NewListener(listenerConfig)
+		addrress, err := listener.Start()
+		if err != nil {
+			utility.LogError(err, "LifecycleManager", "Cannot start Telemetry API Listener.")
+			return ctx, nil, initError(ctx, extensionClient, extensionapi.ErrorListenerStartFailure, err)
+		}
+
+		// Step 3: Subscribe the listener to Telemetry API
+		telemetryClient := telemetryapi.NewClient(telemetryapi.SchemaVersionFromEnv())
+		eventTypes := telemetryapi.EventTypesFromEnv()
+		_, err = telemetryClient.Subscribe(ctx, response.ExtensionID, addrress, eventTypes)
+		if err != nil {
+			utility.LogError(err, "LifecycleManager", "Cannot register Telemetry API client.")
+			return ctx, nil, initError(ctx, extensionClient, extensionapi.ErrorSubscribeFailure, err)
+		}
+
+		lm.listener = listener
+		lm.telemetryClient = telemetryClient
+		lm.eventTypes = eventTypes
+	}
+
+	if lm.forwardEndpoint != "" {
+		// There is no collector configuration to reload
+		logger.InfoStringf("Forwarding the Telemetry API events to %s without the collector", lm.forwardEndpoint)
+	} else {
+		if lm.collectorBinary != "" {
+			logger.InfoStringf("Running the collector as a child process: %s", lm.collectorBinary)
+		}
+
+		lm.configReloader = configReloaderFromEnv(ctx)
+	}
+
+	// Step 4: Start the collector, or defer it to the first invocation or
+	// telemetry in lazy mode, trading first invocation latency for init time
+	if lazyStart {
+		logger.InfoStringf("Deferring the collector start until the first invocation")
+
+		if lm.listener != nil {
+			go lm.startCollectorOnTelemetry(ctx)
+		}
+
+	} else if errorType, err := lm.startCollector(ctx); err != nil {
+		return ctx, nil, initError(ctx, extensionClient, errorType, err)
+	}
+
+	if address := utility.GetEnvString("OTEL_LAMBDA_EXTENSION_HEALTH_ADDRESS", ""); address != "" {
+		lm.healthServer, err = startHealthServer(address, lm)
+		if err != nil {
+			// The health endpoint is a debugging aid, the extension works without it
+			utility.LogError(err, "LifecycleManager", "Cannot start the health endpoint.")
+		}
+	}
+
+	if err := lm.runInitHooks(ctx); err != nil {
+		utility.LogError(err, "LifecycleManager", "Lifecycle hook failed the init.")
+		return ctx, nil, initError(ctx, extensionClient, extensionapi.ErrorHookFailure, err)
+	}
+
+	return ctx, lm, nil
+}
+
+// startCollector builds and starts the collector once. It returns the error
+// type to report to the Extensions API if the collector can't be started.
+// Later calls return the outcome of the first one.
+func (lm *Manager) startCollector(ctx context.Context) (string, error) {
+	lm.collectorOnce.Do(func() {
+		if lm.currentCollector() == nil {
+			lm.collectorErrorType, lm.collectorErr = lm.newCollector(ctx)
+		}
+
+		// The events held by a deferred dispatch now have consumers
+		if lm.listener != nil {
+			lm.listener.ResumeDispatch()
+		}
+	})
+
+	return lm.collectorErrorType, lm.collectorErr
+}
+
+// newCollector builds and starts the collector. If its configuration is
+// invalid and the fallback is enabled, see configFallbackFromEnv, the
+// collector of the default configuration is started instead.
+func (lm *Manager) newCollector(ctx context.Context) (string, error) {
+	collector, errorType, err := lm.buildCollector()
+	if err == nil {
+		errorType, err = lm.runCollector(ctx, collector)
+	}
+
+	if err == nil || !lm.configFallback || !isConfigErrorType(errorType) {
+		return errorType, err
+	}
+
+	utility.LogError(err, "LifecycleManager", "The collector configuration is invalid, starting with the default configuration", utility.KeyValue{K: "error_type", V: errorType})
+	telemetryapi.RecordConfigFallback(errorType)
+
+	collector, errorType, err = lm.buildDefaultCollector()
+	if err != nil {
+		return errorType, err
+	}
+
+	return lm.runCollector(ctx, collector)
+}
+
+// isConfigErrorType reports whether errorType is the error type of an
+// invalid collector configuration.
+func isConfigErrorType(errorType string) bool {
+	switch errorType {
+	case extensionapi.ErrorConfigParseFailure, extensionapi.ErrorConfigInvalid, extensionapi.ErrorUnknownComponent:
+		return true
+	}
+
+	return false
+}
+
+// buildCollector returns a collector with the components of the extension,
+// ready to start, the collector forwarding the Telemetry API events in
+// forwarding mode, or the collector process running an external binary.
+func (lm *Manager) buildCollector() (Collector, string, error) {
+	if lm.forwardEndpoint != "" {
+		return newForwardingCollector(lm.listener, lm.forwardEndpoint, forwardHeadersFromEnv()), "", nil
+	}
+
+	if lm.collectorBinary != "" {
+		return newProcessCollector(lm.collectorBinary, configURIs()...), "", nil
+	}
+
+	factories, err := lm.factories()
+	if err != nil {
+		return nil, extensionapi.ErrorCollectorStartFailure, err
+	}
+
+	newCollector := lm.collectorFactory
+	if newCollector == nil {
+		newCollector = newServiceCollector
+	}
+
+	collector, err := newCollector(factories)
+	if err != nil {
+		utility.LogError(err, "LifecycleManager", "Failed to initialize new collector")
+		return nil, extensionapi.ErrorConfigInvalid, err
+	}
+
+	if len(lm.isolatedConfigs) == 0 {
+		return collector, "", nil
+	}
+
+	// The components are created by each service instance, from its own configuration
+	collectors := []Collector{collector}
+	for _, uri := range lm.isolatedConfigs {
+		isolated, err := newServiceCollectorWithConfig(factories, uri)
+		if err != nil {
+			utility.LogError(err, "LifecycleManager", "Failed to initialize the collector of an isolated configuration", utility.KeyValue{K: "uri", V: uri})
+			return nil, extensionapi.ErrorConfigInvalid, err
+		}
+
+		collectors = append(collectors, isolated)
+	}
+
+	return newMultiCollector(collectors), "", nil
+}
+
+// buildDefaultCollector returns a collector running the default
+// configuration embedded in the extension, or the collector process running
+// it, see configFallbackFromEnv. The isolated configurations aren't run.
+func (lm *Manager) buildDefaultCollector() (Collector, string, error) {
+	uri := "yaml:" + defaultConfig
+
+	if lm.collectorBinary != "" {
+		return newProcessCollector(lm.collectorBinary, uri), "", nil
+	}
+
+	factories, err := lm.factories()
+	if err != nil {
+		return nil, extensionapi.ErrorCollectorStartFailure, err
+	}
+
+	collector, err := newServiceCollectorWithConfig(factories, uri)
+	if err != nil {
+		utility.LogError(err, "LifecycleManager", "Failed to initialize the collector of the default configuration")
+		return nil, extensionapi.ErrorConfigInvalid, err
+	}
+
+	return collector, "", nil
+}
+
+// factories returns the components of the collector, with the Telemetry API
+// receiver of the listener and the decouple processor.
+func (lm *Manager) factories() (component.Factories, error) {
+	components := lm.components
+	if components == nil {
+		components = lambdacomponents.Components
+	}
+
+	factories, err := components(telemetryapireceiver.NewFactory(lm.listener))
+	if err != nil {
+		utility.LogError(err, "LifecycleManager", "Failed to initialize lambda components")
+		return factories, err
+	}
+
+	decouple := decoupleprocessor.NewFactory()
+	factories.Processors[decouple.Type()] = decouple
+
+	if lm.lazyPipelines {
+		factories = lazycomponent.Factories(factories)
+	}
+
+	return factories, nil
+}
+
+// newServiceCollector is NewServiceCollector returning a Collector.
+func newServiceCollector(factories component.Factories) (Collector, error) {
+	collector, err := NewServiceCollector(factories)
+	if err != nil {
+		return nil, err
+	}
+
+	return collector, nil
+}
+
+// Run processes the events of the Extensions API until the SHUTDOWN event is
+// handled or ctx is done. A failure which stopped the extension is reported
+// to the Extensions API and returned as an *Error.
+func (lm *Manager) Run(ctx context.Context) error {
+	return lm.processEvents(ctx)
+}
+
+// runCollector starts collector and makes it the current one.
+func (lm *Manager) runCollector(ctx context.Context, collector Collector) (string, error) {
+	err := collector.Start(ctx)
+	if err != nil {
+		errorType := startErrorType(err)
+		utility.LogError(err, "LifecycleManager", "Failed to start the lambda layer collector extension", utility.KeyValue{K: "error_type", V: errorType})
+		return errorType, err
+	}
+
+	lm.collectorMu.Lock()
+	lm.collector = collector
+	lm.collectorMu.Unlock()
+
+	lm.syncEventTypes()
+
+	return "", nil
+}
+
+// superviseCollector restarts the collector if it stopped running on its
+// own, e.g. after a fatal component error, retrying with exponential backoff
+// instead of leaving the extension without pipelines. It returns the error
+// type to report to the Extensions API once the restarts are exhausted.
+func (lm *Manager) superviseCollector(ctx context.Context) (string, error) {
+	collector := lm.currentCollector()
+	if collector == nil || !collector.Exited() {
+		return "", nil
+	}
+
+	utility.LogError(collector.Err(), "LifecycleManager", "The collector stopped unexpectedly, restarting it")
+
+	// Collectors still running alongside the one which stopped must release their ports
+	if err := collector.Stop(ctx); err != nil {
+		utility.LogError(err, "LifecycleManager", "Failed stopping the collector before restarting it")
+	}
+
+	backoff := collectorRestartBackoff
+
+	for attempt := 1; ; attempt++ {
+		errorType, err := lm.newCollector(ctx)
+		if err == nil || attempt == collectorRestartAttempts {
+			return errorType, err
+		}
+
+		utility.LogError(err, "LifecycleManager", "Cannot restart the collector, retrying", utility.KeyValue{K: "attempt", V: attempt}, utility.KeyValue{K: "backoff", V: backoff})
+
+		select {
+		case <-ctx.Done():
+			return errorType, ctx.Err()
+
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+// reloadConfig restarts the collector if its configuration changed. An
+// invalid configuration is logged and the running collector kept, until the
+// configuration changes again. It returns the error type to report if the
+// collector can't be restarted.
+func (lm *Manager) reloadConfig(ctx context.Context) (string, error) {
+	if lm.configReloader == nil {
+		return "", nil
+	}
+
+	version, changed := lm.configReloader.changed(ctx)
+	if !changed {
+		return "", nil
+	}
+
+	lm.configReloader.version = version
+
+	// The new configuration must not be read from the cache
+	if err := cacheprovider.Invalidate(configCacheDir); err != nil {
+		utility.LogError(err, "LifecycleManager", "Failed to clear the configuration cache")
+	}
+
+	collector, errorType, err := lm.buildCollector()
+	if err != nil {
+		return errorType, err
+	}
+
+	if err := collector.Validate(ctx); err != nil {
+		utility.LogError(err, "LifecycleManager", "The new collector configuration is invalid, keeping the running collector", utility.KeyValue{K: "version", V: version})
+		return "", nil
+	}
+
+	logger.InfoStringf("The collector configuration changed, restarting the collector")
+
+	// The receivers of the running collector must release their ports first
+	if running := lm.currentCollector(); running != nil {
+		if err := running.Stop(ctx); err != nil {
+			utility.LogError(err, "LifecycleManager", "Failed stopping the collector before reloading its configuration")
+		}
+	}
+
+	return lm.runCollector(ctx, collector)
+}
+
+// startCollectorOnTelemetry starts a lazily started collector as soon as the
+// listener receives telemetry, e.g. for extensions registered for SHUTDOWN only.
+func (lm *Manager) startCollectorOnTelemetry(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+	case <-lm.listener.Received():
+		_, _ = lm.startCollector(ctx)
+	}
+}
+
+// currentCollector returns the collector, nil until it is started.
+func (lm *Manager) currentCollector() Collector {
+	lm.collectorMu.RLock()
+	defer lm.collectorMu.RUnlock()
+
+	return lm.collector
+}
+
+// register registers the extension with the Extensions API, retrying with
+// exponential backoff so a transient Runtime API failure at cold start doesn't
+// take the extension down.
+func register(ctx context.Context, client extensionapi.API, events []extensionapi.EventType) (*extensionapi.RegisterResponse, error) {
+	backoff := registerBackoff
+
+	for attempt := 1; ; attempt++ {
+		response, err := client.Register(ctx, extensionName, events...)
+		if err == nil || attempt == registerAttempts {
+			return response, err
+		}
+
+		utility.LogError(err, "LifecycleManager", "Cannot register extension, retrying", utility.KeyValue{K: "attempt", V: attempt}, utility.KeyValue{K: "backoff", V: backoff})
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+// nextEvent waits for the next event, retrying failed calls with a jittered
+// exponential backoff for up to nextEventRetryWindow, so a transient Runtime
+// API failure doesn't take the extension down.
+func (lm *Manager) nextEvent(ctx context.Context) (*extensionapi.NextEventResponse, error) {
+	giveUp := time.Now().Add(nextEventRetryWindow)
+	backoff := nextEventBackoff
+
+	for {
+		response, err := lm.extensionClient.NextEvent(ctx)
+		if err == nil {
+			telemetryapi.RecordExtensionEvent(string(response.EventType))
+			return response, nil
+		}
+
+		telemetryapi.RecordNextEventFailure()
+
+		// Half of the backoff is jitter, so that retries don't align with the failures
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		if ctx.Err() != nil || time.Now().Add(delay).After(giveUp) {
+			return nil, err
+		}
+
+		utility.LogError(err, "processEvents", "Error waiting for extension event, retrying", utility.KeyValue{K: "backoff", V: delay})
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+
+		case <-time.After(delay):
+		}
+
+		backoff *= 2
+	}
+}
+
+// invocationContext returns the context of the work done for an invocation,
+// bounded by the invocation deadline so none of it holds the sandbox past it.
+func invocationContext(ctx context.Context, response *extensionapi.NextEventResponse) (context.Context, context.CancelFunc) {
+	if response.DeadlineMs == 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithDeadline(ctx, time.UnixMilli(response.DeadlineMs))
+}
+
+// invocationInfo returns the metadata of the invocation of an INVOKE event.
+// The first invocation handled by the extension is the cold start.
+func (lm *Manager) invocationInfo(response *extensionapi.NextEventResponse) invocation.Info {
+	info := invocation.Info{
+		RequestID:   response.RequestID,
+		ColdStart:   !lm.invoked,
+		FunctionARN: response.InvokedFunctionArn,
+	}
+
+	if response.DeadlineMs != 0 {
+		info.Deadline = time.UnixMilli(response.DeadlineMs)
+	}
+
+	lm.invoked = true
+
+	return info
+}
+
+// shutdownContext returns the context bounding the collector flush on
+// shutdown. A spindown leaves the exporters as long as they need, while after
+// a timeout or a failure they are given up on shortly before the environment
+// is killed, so the extension still exits cleanly.
+func shutdownContext(ctx context.Context, response *extensionapi.NextEventResponse) (context.Context, context.CancelFunc) {
+	if response.ShutdownReason == extensionapi.Spindown || response.DeadlineMs == 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithDeadline(ctx, time.UnixMilli(response.DeadlineMs).Add(-shutdownDeadlineMargin))
+}
+
+// invocationBoundary applies the changes held until the end of an invocation:
+// a new collector configuration and the desired Telemetry API subscription.
+// It returns the reported error if the extension must exit.
+func (lm *Manager) invocationBoundary(ctx context.Context) error {
+	if errorType, err := lm.reloadConfig(ctx); err != nil {
+		return lm.exitError(ctx, errorType, err)
+	}
+
+	if lm.telemetryClient != nil {
+		lm.updateSubscription(ctx)
+	}
+
+	return nil
+}
+
+// setEventTypes changes the Telemetry API event types the listener is
+// subscribed to. The subscription is updated at the next invocation boundary.
+func (lm *Manager) setEventTypes(eventTypes []telemetryapi.EventType) {
+	lm.eventTypesMu.Lock()
+	defer lm.eventTypesMu.Unlock()
+
+	lm.eventTypes = eventTypes
+}
+
+// syncEventTypes sets the event types of the Telemetry API subscription to
+// those the telemetryapi receivers of the running collector are configured
+// with, or to OTEL_LAMBDA_TELEMETRY_TYPES if none of them sets types.
+func (lm *Manager) syncEventTypes() {
+	if lm.listener == nil {
+		return
+	}
+
+	eventTypes := lm.listener.ConsumerEventTypes()
+	if eventTypes == nil {
+		eventTypes = telemetryapi.EventTypesFromEnv()
+	}
+
+	lm.setEventTypes(eventTypes)
+}
+
+// updateSubscription re-subscribes to the Telemetry API if the desired event types changed.
+func (lm *Manager) updateSubscription(ctx context.Context) {
+	lm.eventTypesMu.Lock()
+	eventTypes := lm.eventTypes
+	lm.eventTypesMu.Unlock()
+
+	_, err := lm.telemetryClient.UpdateSubscription(ctx, eventTypes)
+	if err != nil {
+		utility.LogError(err, "updateSubscription", "Failed to update Telemetry API subscription", utility.KeyValue{K: "types", V: eventTypes})
+	}
+}
+
+func (lm *Manager) processEvents(ctx context.Context) (exitErr error) {
+	// Report a panic, e.g. of a lifecycle hook, as the reason the extension exits
+	defer utility.RecoverPanic("processEvents", func(err *utility.PanicError) {
+		exitErr = lm.exitError(context.Background(), extensionapi.ErrorPanic, err)
+	})
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		default:
+			// This is a blocking action
+			response, err := lm.nextEvent(ctx)
+			if err != nil {
+				utility.LogError(err, "processEvents", "Error waiting for extension event")
+				return lm.exitError(ctx, extensionapi.ErrorNextEventFailure, err)
+			}
+
+			lm.lastEvent.set(response)
+
+			// A lazily started collector is started by the first event at the latest
+			if errorType, err := lm.startCollector(ctx); err != nil {
+				return lm.exitError(ctx, errorType, err)
+			}
+
+			// A collector which stopped running can't flush anything on SHUTDOWN
+			if response.EventType != extensionapi.Shutdown {
+				if errorType, err := lm.superviseCollector(ctx); err != nil {
+					return lm.exitError(ctx, errorType, err)
+				}
+			}
+
+			// Exit if we receive a SHUTDOWN event
+			if response.EventType == extensionapi.Shutdown {
+				logger.InfoStringf("Shutting down, reason: %s", response.ShutdownReason)
+
+				stopCtx, cancel := shutdownContext(ctx, response)
+
+				lm.runEventHooks(stopCtx, "OnShutdown", response, func(h Hooks) EventHook {
+					return h.OnShutdown
+				})
+
+				if lm.listener != nil {
+					// Nothing awaited the telemetry of the last invocation of a passive extension
+					if lm.passive {
+						_ = lm.listener.WaitIdle(stopCtx, shutdownDrainPeriod)
+					}
+
+					lm.listener.Shutdown()
+				}
+
+				if lm.healthServer != nil {
+					_ = lm.healthServer.Close()
+				}
+
+				// A lazily started collector may not run yet, or a restart be in progress
+				if collector := lm.currentCollector(); collector != nil {
+					err = collector.Stop(stopCtx)
+				}
+				cancel()
+				if err != nil {
+					utility.LogError(err, "processEvents", "Failed stopping the collector", utility.KeyValue{K: "request_id", V: response.RequestID})
+					return lm.exitError(ctx, extensionapi.ErrorExportFailure, err)
+				}
+
+				return nil
+			}
+
+			// Let the components enrich the telemetry of the invocation with its metadata
+			info := lm.invocationInfo(response)
+			invocation.Set(info)
+			invokeCtx := invocation.NewContext(ctx, info)
+
+			lm.runEventHooks(invokeCtx, "OnInvoke", response, func(h Hooks) EventHook {
+				return h.OnInvoke
+			})
+
+			// Without the Telemetry API there is nothing to wait for
+			if lm.listener == nil {
+				if err := lm.invocationBoundary(ctx); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			lm.listener.SetInvokedFunctionARN(response.InvokedFunctionArn)
+			if response.Tracing.Value != "" {
+				lm.listener.SetTraceContext(response.RequestID, telemetryapi.TraceContext{
+					Type:  response.Tracing.Type,
+					Value: response.Tracing.Value,
+				})
+			}
+
+			// A passive extension lets the function run on without waiting for its telemetry
+			if lm.passive {
+				if err := lm.invocationBoundary(ctx); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			invocationCtx, cancel := invocationContext(invokeCtx, response)
+			err = lm.listener.Wait(invocationCtx, response.RequestID)
+			if err != nil {
+				utility.LogError(err, "processEvents", "Problem waiting for platform.runtimeDone event, flushing the telemetry received so far", utility.KeyValue{K: "request_id", V: response.RequestID})
+			}
+
+			// Hand the telemetry of the invocation to the pipelines before the environment can be
+			// frozen, or what was received of it when the function is about to time out
+			err = lm.listener.WaitDelivered(invocationCtx)
+			if err != nil {
+				utility.LogError(err, "processEvents", "Problem delivering the telemetry of the invocation", utility.KeyValue{K: "request_id", V: response.RequestID})
+			}
+
+			lm.runEventHooks(invocationCtx, "OnRuntimeDone", response, func(h Hooks) EventHook {
+				return h.OnRuntimeDone
+			})
+
+			cancel()
+			invocation.Clear()
+
+			// A restarted collector runs for the lifetime of the extension, not of the invocation
+			if err := lm.invocationBoundary(ctx); err != nil {
+				return err
+			}
+
+			lm.listener.RecordOverhead()
+		}
+	}
+}
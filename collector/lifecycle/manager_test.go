@@ -0,0 +1,923 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/lambdaemulator"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/telemetryapi"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/lambdacomponents"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi/extensionapitest"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/invocation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
+	"go.opentelemetry.io/collector/component"
+)
+
+const testCollectorConfig = `
+receivers:
+  otlp:
+    protocols:
+      grpc:
+        endpoint: localhost:0
+exporters:
+  logging:
+service:
+  telemetry:
+    metrics:
+      level: none
+  pipelines:
+    traces:
+      receivers: [otlp]
+      exporters: [logging]
+`
+
+// writeTestCollectorConfig makes the collector use testCollectorConfig.
+func writeTestCollectorConfig(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(testCollectorConfig), 0600))
+
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", configFile)
+}
+
+// startTestCollector starts a collector running testCollectorConfig.
+func startTestCollector(t *testing.T) *ServiceCollector {
+	writeTestCollectorConfig(t)
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	collector, err := NewServiceCollector(factories)
+	require.NoError(t, err)
+	require.NoError(t, collector.Start(context.Background()))
+
+	return collector
+}
+
+// runLifecycle runs the extension against an emulated Runtime API handing
+// out the given invocations, and returns the emulator once the lifecycle
+// manager stopped.
+func runLifecycle(t *testing.T, invocations ...lambdaemulator.Invocation) (*lambdaemulator.Emulator, *Manager) {
+	emulator := lambdaemulator.New(invocations...)
+	address, err := emulator.Start("127.0.0.1:0")
+	require.NoError(t, err)
+
+	writeTestCollectorConfig(t)
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", address)
+
+	ctx, lm, err := New(context.Background(), Settings{})
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, lm.processEvents(ctx))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("processEvents did not return after the SHUTDOWN event")
+	}
+
+	require.NoError(t, emulator.Close())
+	assert.True(t, lm.collector.(*ServiceCollector).stopped)
+
+	return emulator, lm
+}
+
+func TestLifecycleWithoutTelemetryAPI(t *testing.T) {
+	t.Setenv("OTEL_LAMBDA_DISABLE_TELEMETRY_API", "true")
+
+	emulator, lm := runLifecycle(t,
+		lambdaemulator.Invocation{RequestID: "1", Timeout: time.Minute},
+		lambdaemulator.Invocation{RequestID: "2", Timeout: time.Minute},
+	)
+	assert.Nil(t, lm.listener)
+	assert.Nil(t, lm.telemetryClient)
+
+	// No Telemetry API subscription is made and every event is acknowledged
+	// without waiting for platform.runtimeDone.
+	assert.Equal(t, []string{
+		"POST /2020-01-01/extension/register",
+		"GET /2020-01-01/extension/event/next",
+		"GET /2020-01-01/extension/event/next",
+		"GET /2020-01-01/extension/event/next",
+	}, emulator.Requests())
+}
+
+func TestNewSettings(t *testing.T) {
+	t.Setenv("OTEL_LAMBDA_DISABLE_TELEMETRY_API", "true")
+	writeTestCollectorConfig(t)
+
+	client := &extensionapitest.Fake{
+		Events: []extensionapi.NextEventResponse{
+			{EventType: extensionapi.Invoke, RequestID: "1"},
+			{EventType: extensionapi.Shutdown, ShutdownReason: extensionapi.Spindown},
+		},
+	}
+
+	var calls []string
+	ctx, lm, err := New(context.Background(), Settings{
+		ExtensionClient: client,
+		Components: func(additionalReceivers ...component.ReceiverFactory) (component.Factories, error) {
+			calls = append(calls, "components")
+			return lambdacomponents.Components(additionalReceivers...)
+		},
+		NewCollector: func(factories component.Factories) (Collector, error) {
+			calls = append(calls, "collector")
+			return NewServiceCollector(factories)
+		},
+		Hooks: []Hooks{{
+			OnInvoke: func(_ context.Context, event *extensionapi.NextEventResponse) error {
+				calls = append(calls, "invoke "+event.RequestID)
+				return nil
+			},
+		}},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, lm.Run(ctx))
+
+	assert.Equal(t, []string{"components", "collector", "invoke 1"}, calls)
+	assert.Equal(t, []string{"Register", "NextEvent", "NextEvent"}, client.Calls())
+	assert.True(t, lm.collector.(*ServiceCollector).stopped)
+}
+
+func TestLifecycleWithTelemetryAPI(t *testing.T) {
+	// Listen on all interfaces rather than the sandbox hostname
+	t.Setenv("AWS_SAM_LOCAL", "true")
+
+	// Every invocation is acknowledged as soon as its platform.runtimeDone
+	// event is received, long before the deadline.
+	invocations := []lambdaemulator.Invocation{
+		lambdaemulator.NewInvocation("1", telemetryapi.StatusSuccess, "hello"),
+		lambdaemulator.NewInvocation("2", telemetryapi.StatusFailure),
+	}
+	for i := range invocations {
+		invocations[i].Timeout = time.Minute
+	}
+
+	emulator, lm := runLifecycle(t, invocations...)
+	assert.NotNil(t, lm.listener)
+
+	require.Len(t, emulator.Subscriptions(), 1)
+	assert.Equal(t, []telemetryapi.EventType{telemetryapi.Platform}, emulator.Subscriptions()[0].Request.EventTypes)
+	assert.Empty(t, emulator.Errors())
+}
+
+func TestLifecycleShutdownOnly(t *testing.T) {
+	t.Setenv("AWS_SAM_LOCAL", "true")
+	t.Setenv("OTEL_LAMBDA_EXTENSION_EVENTS", "SHUTDOWN")
+	// Start from empty views, other tests record into the same global views
+	view.Unregister(telemetryapi.MetricViews()...)
+
+	emulator, lm := runLifecycle(t, lambdaemulator.NewInvocation("1", telemetryapi.StatusSuccess))
+	assert.NotNil(t, lm.listener)
+	assert.True(t, lm.passive)
+
+	// The telemetry sent with the SHUTDOWN event is received before the listener stops
+	rows, err := view.RetrieveData("telemetryapi_invocations")
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, float64(1), rows[0].Data.(*view.SumData).Value)
+
+	// The extension is only woken up to shut down
+	assert.Equal(t, []string{
+		"POST /2020-01-01/extension/register",
+		"PUT /2022-07-01/telemetry",
+		"GET /2020-01-01/extension/event/next",
+	}, emulator.Requests())
+}
+
+func TestLifecyclePassiveMode(t *testing.T) {
+	t.Setenv("AWS_SAM_LOCAL", "true")
+	t.Setenv("OTEL_LAMBDA_LIFECYCLE_MODE", "passive")
+	view.Unregister(telemetryapi.MetricViews()...)
+
+	// The invocation would hold an active extension until its deadline
+	invocation := lambdaemulator.NewInvocation("1", telemetryapi.StatusSuccess)
+	invocation.Events = invocation.Events[:1]
+	invocation.Timeout = time.Minute
+
+	emulator, lm := runLifecycle(t, invocation)
+	assert.True(t, lm.passive)
+
+	// The telemetry of the invocation is still received before shutting down
+	rows, err := view.RetrieveData("telemetryapi_listener_batch_size")
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, int64(1), rows[0].Data.(*view.DistributionData).Count)
+	assert.Empty(t, emulator.Errors())
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	t.Setenv("AWS_SAM_LOCAL", "true")
+	t.Cleanup(func() { registeredHooks = nil })
+
+	var calls []string
+	EventHook := func(name string) EventHook {
+		return func(_ context.Context, event *extensionapi.NextEventResponse) error {
+			calls = append(calls, name+" "+event.RequestID)
+			return errors.New("hook failures are only logged")
+		}
+	}
+
+	RegisterHooks(Hooks{
+		OnInit: func(context.Context) error {
+			calls = append(calls, "init")
+			return nil
+		},
+		OnInvoke:      EventHook("invoke"),
+		OnRuntimeDone: EventHook("runtimeDone"),
+		OnShutdown:    EventHook("shutdown"),
+	})
+	RegisterHooks(Hooks{OnInvoke: EventHook("second invoke")})
+
+	invocations := []lambdaemulator.Invocation{
+		lambdaemulator.NewInvocation("1", telemetryapi.StatusSuccess),
+		lambdaemulator.NewInvocation("2", telemetryapi.StatusSuccess),
+	}
+	for i := range invocations {
+		invocations[i].Timeout = time.Minute
+	}
+
+	emulator, _ := runLifecycle(t, invocations...)
+
+	assert.Equal(t, []string{
+		"init",
+		"invoke 1", "second invoke 1", "runtimeDone 1",
+		"invoke 2", "second invoke 2", "runtimeDone 2",
+		"shutdown ",
+	}, calls)
+	assert.Empty(t, emulator.Errors())
+}
+
+func TestLifecycleInitHookError(t *testing.T) {
+	t.Setenv("OTEL_LAMBDA_DISABLE_TELEMETRY_API", "true")
+	t.Cleanup(func() { registeredHooks = nil })
+
+	RegisterHooks(Hooks{
+		OnInit: func(context.Context) error { return errors.New("cache warming failed") },
+	})
+
+	emulator := lambdaemulator.New()
+	address, err := emulator.Start("127.0.0.1:0")
+	require.NoError(t, err)
+
+	writeTestCollectorConfig(t)
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", address)
+
+	_, lm, err := New(context.Background(), Settings{})
+	assert.Nil(t, lm)
+	assert.Equal(t, ExitCodeHook, ExitCode(err))
+
+	require.NoError(t, emulator.Close())
+	assert.Equal(t, []string{extensionapi.ErrorHookFailure}, emulator.Errors())
+}
+
+func TestLifecycleLazyStart(t *testing.T) {
+	t.Setenv("AWS_SAM_LOCAL", "true")
+	t.Setenv("OTEL_LAMBDA_LAZY_COLLECTOR_START", "true")
+
+	emulator := lambdaemulator.New(lambdaemulator.NewInvocation("1", telemetryapi.StatusSuccess, "hello"))
+	address, err := emulator.Start("127.0.0.1:0")
+	require.NoError(t, err)
+
+	writeTestCollectorConfig(t)
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", address)
+
+	ctx, lm, err := New(context.Background(), Settings{})
+	require.NoError(t, err)
+
+	// Registered and subscribed, but the collector waits for the first event
+	require.Len(t, emulator.Subscriptions(), 1)
+	assert.Nil(t, lm.currentCollector())
+
+	require.NoError(t, lm.processEvents(ctx))
+	require.NoError(t, emulator.Close())
+
+	require.NotNil(t, lm.currentCollector())
+	assert.True(t, lm.collector.(*ServiceCollector).stopped)
+	assert.Empty(t, emulator.Errors())
+}
+
+func TestLifecycleLazyPipelines(t *testing.T) {
+	t.Setenv("AWS_SAM_LOCAL", "true")
+	t.Setenv("OTEL_LAMBDA_LAZY_PIPELINES", "true")
+
+	emulator, lm := runLifecycle(t, lambdaemulator.NewInvocation("1", telemetryapi.StatusSuccess))
+	assert.True(t, lm.lazyPipelines)
+	assert.Empty(t, emulator.Errors())
+}
+
+func TestRegisterRetries(t *testing.T) {
+	client := &extensionapitest.Fake{RegisterFailures: registerAttempts - 1}
+	response, err := register(context.Background(), client, []extensionapi.EventType{extensionapi.Shutdown})
+	require.NoError(t, err)
+	assert.Equal(t, extensionapitest.ExtensionID, response.ExtensionID)
+	assert.Len(t, client.Calls(), registerAttempts)
+	assert.Equal(t, []extensionapi.EventType{extensionapi.Shutdown}, client.Registered())
+
+	client = &extensionapitest.Fake{RegisterFailures: registerAttempts}
+	_, err = register(context.Background(), client, nil)
+	assert.ErrorIs(t, err, extensionapitest.ErrRegister)
+	assert.Len(t, client.Calls(), registerAttempts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client = &extensionapitest.Fake{RegisterFailures: registerAttempts}
+	_, err = register(ctx, client, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, client.Calls(), 1)
+}
+
+func TestShutdownContext(t *testing.T) {
+	deadline := time.Now().Add(2 * time.Second)
+
+	for _, tc := range []struct {
+		reason      extensionapi.ShutdownReason
+		hasDeadline bool
+	}{
+		{reason: extensionapi.Spindown},
+		{reason: extensionapi.Timeout, hasDeadline: true},
+		{reason: extensionapi.Failure, hasDeadline: true},
+	} {
+		t.Run(string(tc.reason), func(t *testing.T) {
+			ctx, cancel := shutdownContext(context.Background(), &extensionapi.NextEventResponse{
+				EventType:      extensionapi.Shutdown,
+				DeadlineMs:     deadline.UnixMilli(),
+				ShutdownReason: tc.reason,
+			})
+			defer cancel()
+
+			stopBy, ok := ctx.Deadline()
+			assert.Equal(t, tc.hasDeadline, ok)
+			if ok {
+				assert.WithinDuration(t, deadline.Add(-shutdownDeadlineMargin), stopBy, time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestProcessEvents(t *testing.T) {
+	client := &extensionapitest.Fake{
+		Events: []extensionapi.NextEventResponse{
+			{EventType: extensionapi.Invoke, RequestID: "1"},
+			{EventType: extensionapi.Invoke, RequestID: "2"},
+			{EventType: extensionapi.Shutdown, ShutdownReason: extensionapi.Spindown},
+		},
+	}
+	lm := &Manager{
+		collector:       startTestCollector(t),
+		extensionClient: client,
+	}
+
+	require.NoError(t, lm.processEvents(context.Background()))
+
+	assert.Equal(t, []string{"NextEvent", "NextEvent", "NextEvent"}, client.Calls())
+	assert.True(t, lm.collector.(*ServiceCollector).stopped)
+}
+
+func TestProcessEventsShutdownWithoutCollector(t *testing.T) {
+	client := &extensionapitest.Fake{
+		Events: []extensionapi.NextEventResponse{
+			{EventType: extensionapi.Shutdown, ShutdownReason: extensionapi.Spindown},
+		},
+	}
+	// The collector of a lazy start never ran
+	lm := &Manager{extensionClient: client}
+
+	require.NoError(t, lm.processEvents(context.Background()))
+	assert.Empty(t, client.ErrorTypes())
+}
+
+func TestProcessEventsInvocation(t *testing.T) {
+	t.Cleanup(invocation.Clear)
+
+	deadline := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	arn := "arn:aws:lambda:eu-west-1:123456789012:function:f"
+	client := &extensionapitest.Fake{
+		Events: []extensionapi.NextEventResponse{
+			{EventType: extensionapi.Invoke, RequestID: "1", DeadlineMs: deadline.UnixMilli(), InvokedFunctionArn: arn},
+			{EventType: extensionapi.Invoke, RequestID: "2", InvokedFunctionArn: arn},
+			{EventType: extensionapi.Shutdown, ShutdownReason: extensionapi.Spindown},
+		},
+	}
+
+	var invocations []invocation.Info
+	lm := &Manager{
+		collector:       startTestCollector(t),
+		extensionClient: client,
+		hooks: []Hooks{{
+			OnInvoke: func(ctx context.Context, _ *extensionapi.NextEventResponse) error {
+				info, ok := invocation.FromContext(ctx)
+				assert.True(t, ok)
+				invocations = append(invocations, info)
+				return nil
+			},
+		}},
+	}
+
+	require.NoError(t, lm.processEvents(context.Background()))
+
+	assert.Equal(t, []invocation.Info{
+		{RequestID: "1", Deadline: deadline, ColdStart: true, FunctionARN: arn},
+		{RequestID: "2", FunctionARN: arn},
+	}, invocations)
+
+	// Without the Telemetry API, the end of the last invocation isn't known
+	current, ok := invocation.Current()
+	assert.True(t, ok)
+	assert.Equal(t, "2", current.RequestID)
+}
+
+func TestProcessEventsPanic(t *testing.T) {
+	client := &extensionapitest.Fake{
+		Events: []extensionapi.NextEventResponse{
+			{EventType: extensionapi.Invoke, RequestID: "1"},
+			{EventType: extensionapi.Shutdown, ShutdownReason: extensionapi.Spindown},
+		},
+	}
+	lm := &Manager{
+		collector:       startTestCollector(t),
+		extensionClient: client,
+		hooks: []Hooks{{
+			OnInvoke: func(context.Context, *extensionapi.NextEventResponse) error {
+				panic("hook bug")
+			},
+		}},
+	}
+	defer lm.collector.Stop(context.Background())
+
+	var err error
+	assert.NotPanics(t, func() { err = lm.processEvents(context.Background()) })
+	assert.Equal(t, []string{"NextEvent", "ExitError"}, client.Calls())
+	assert.Equal(t, []string{extensionapi.ErrorPanic}, client.ErrorTypes())
+	assert.Equal(t, ExitCodePanic, ExitCode(err))
+	assert.EqualError(t, err, "Extension.Panic: panic: hook bug")
+}
+
+func TestProcessEventsNextEventError(t *testing.T) {
+	client := &extensionapitest.Fake{}
+	lm := &Manager{extensionClient: client}
+
+	start := time.Now()
+	err := lm.processEvents(context.Background())
+	assert.Equal(t, ExitCodeRuntimeAPI, ExitCode(err))
+
+	// NextEvent is retried until the retry window is over
+	calls := client.Calls()
+	assert.Greater(t, len(calls), 2)
+	assert.Equal(t, "ExitError", calls[len(calls)-1])
+	assert.Equal(t, []string{extensionapi.ErrorNextEventFailure}, client.ErrorTypes())
+	assert.Less(t, time.Since(start), nextEventRetryWindow+nextEventBackoff)
+}
+
+func TestProcessEventsNextEventRetries(t *testing.T) {
+	client := &extensionapitest.Fake{
+		NextEventFailures: 2,
+		Events: []extensionapi.NextEventResponse{
+			{EventType: extensionapi.Shutdown, ShutdownReason: extensionapi.Spindown},
+		},
+	}
+	lm := &Manager{
+		collector:       startTestCollector(t),
+		extensionClient: client,
+	}
+
+	require.NoError(t, lm.processEvents(context.Background()))
+
+	assert.Equal(t, []string{"NextEvent", "NextEvent", "NextEvent"}, client.Calls())
+	assert.Empty(t, client.ErrorTypes())
+	assert.True(t, lm.collector.(*ServiceCollector).stopped)
+}
+
+func TestProcessEventsRestartsCollector(t *testing.T) {
+	client := &extensionapitest.Fake{
+		Events: []extensionapi.NextEventResponse{
+			{EventType: extensionapi.Invoke, RequestID: "1"},
+			{EventType: extensionapi.Shutdown, ShutdownReason: extensionapi.Spindown},
+		},
+	}
+	exited := startTestCollector(t)
+	lm := &Manager{
+		collector:       exited,
+		extensionClient: client,
+	}
+
+	// The service stops on its own, as after a fatal component error
+	exited.svc.Shutdown()
+	<-exited.appDone
+	require.True(t, exited.Exited())
+
+	require.NoError(t, lm.processEvents(context.Background()))
+
+	assert.NotSame(t, exited, lm.collector)
+	assert.True(t, lm.collector.(*ServiceCollector).stopped)
+	assert.Empty(t, client.ErrorTypes())
+}
+
+func TestSuperviseCollectorFailure(t *testing.T) {
+	exited := startTestCollector(t)
+	lm := &Manager{
+		collector:       exited,
+		extensionClient: &extensionapitest.Fake{},
+	}
+
+	// Nothing to do while the collector runs
+	errorType, err := lm.superviseCollector(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, errorType)
+
+	exited.svc.Shutdown()
+	<-exited.appDone
+	require.NoError(t, os.WriteFile(os.Getenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE"), []byte("receivers: [invalid"), 0600))
+
+	start := time.Now()
+	errorType, err = lm.superviseCollector(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, extensionapi.ErrorConfigParseFailure, errorType)
+	assert.Same(t, exited, lm.collector)
+	// Every restart attempt was made, with backoff
+	assert.GreaterOrEqual(t, time.Since(start), 3*collectorRestartBackoff)
+}
+
+// hookedFake is an extensionapitest.Fake running a hook before the NextEvent
+// call of the same index, e.g. to change the environment between invocations.
+type hookedFake struct {
+	*extensionapitest.Fake
+	hooks map[int]func()
+	calls int
+}
+
+func (f *hookedFake) NextEvent(ctx context.Context) (*extensionapi.NextEventResponse, error) {
+	if hook, ok := f.hooks[f.calls]; ok {
+		hook()
+	}
+
+	f.calls++
+
+	return f.Fake.NextEvent(ctx)
+}
+
+// changeTestCollectorConfig rewrites the collector configuration file with a
+// newer modification time.
+func changeTestCollectorConfig(t *testing.T, config string) {
+	path := os.Getenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE")
+	require.NoError(t, os.WriteFile(path, []byte(config), 0600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+}
+
+func TestProcessEventsReloadsConfig(t *testing.T) {
+	running := startTestCollector(t)
+	t.Setenv("OTEL_LAMBDA_CONFIG_RELOAD_INTERVAL_MS", "1")
+
+	client := &hookedFake{
+		Fake: &extensionapitest.Fake{
+			Events: []extensionapi.NextEventResponse{
+				{EventType: extensionapi.Invoke, RequestID: "1"},
+				{EventType: extensionapi.Invoke, RequestID: "2"},
+				{EventType: extensionapi.Shutdown, ShutdownReason: extensionapi.Spindown},
+			},
+		},
+	}
+	lm := &Manager{
+		collector:       running,
+		extensionClient: client,
+		configReloader:  configReloaderFromEnv(context.Background()),
+	}
+	require.NotNil(t, lm.configReloader)
+
+	client.hooks = map[int]func(){
+		// The configuration changes before the second invocation
+		1: func() {
+			time.Sleep(2 * time.Millisecond)
+			changeTestCollectorConfig(t, testCollectorConfig+"      processors: [batch]\nprocessors:\n  batch:\n")
+		},
+		// and the collector is restarted at its end
+		2: func() {
+			assert.NotSame(t, running, lm.currentCollector())
+			assert.True(t, running.stopped)
+		},
+	}
+
+	require.NoError(t, lm.processEvents(context.Background()))
+
+	assert.Equal(t, 3, client.calls)
+	assert.NotSame(t, running, lm.collector)
+	assert.True(t, lm.collector.(*ServiceCollector).stopped)
+	assert.Empty(t, client.ErrorTypes())
+}
+
+func TestProcessEventsKeepsCollectorOnInvalidConfig(t *testing.T) {
+	running := startTestCollector(t)
+	t.Setenv("OTEL_LAMBDA_CONFIG_RELOAD_INTERVAL_MS", "1")
+
+	client := &hookedFake{
+		Fake: &extensionapitest.Fake{
+			Events: []extensionapi.NextEventResponse{
+				{EventType: extensionapi.Invoke, RequestID: "1"},
+				{EventType: extensionapi.Shutdown, ShutdownReason: extensionapi.Spindown},
+			},
+		},
+	}
+	lm := &Manager{
+		collector:       running,
+		extensionClient: client,
+		configReloader:  configReloaderFromEnv(context.Background()),
+	}
+
+	client.hooks = map[int]func(){
+		1: func() {
+			time.Sleep(2 * time.Millisecond)
+			// The pipeline uses an undefined exporter
+			changeTestCollectorConfig(t, strings.Replace(testCollectorConfig, "exporters: [logging]", "exporters: [otlp]", 1))
+		},
+	}
+
+	require.NoError(t, lm.processEvents(context.Background()))
+
+	assert.Same(t, running, lm.collector)
+	assert.True(t, running.stopped)
+	assert.Empty(t, client.ErrorTypes())
+}
+
+func TestProcessEventsResubscribesOnConfigReload(t *testing.T) {
+	t.Setenv("AWS_SAM_LOCAL", "true")
+	writeTestCollectorConfig(t)
+	t.Setenv("OTEL_LAMBDA_CONFIG_RELOAD_INTERVAL_MS", "1")
+
+	// The emulator serves the Telemetry API, the events come from the fake
+	emulator := lambdaemulator.New()
+	address, err := emulator.Start("127.0.0.1:0")
+	require.NoError(t, err)
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", address)
+
+	listener := telemetryapi.NewListener(telemetryapi.ListenerConfig{})
+	listenerURI, err := listener.Start()
+	require.NoError(t, err)
+
+	telemetryClient := telemetryapi.NewClient(telemetryapi.SchemaVersionLatest)
+	_, err = telemetryClient.Subscribe(context.Background(), extensionapitest.ExtensionID, listenerURI, telemetryapi.EventTypesFromEnv())
+	require.NoError(t, err)
+
+	client := &hookedFake{
+		Fake: &extensionapitest.Fake{
+			Events: []extensionapi.NextEventResponse{
+				{EventType: extensionapi.Invoke, RequestID: "1"},
+				{EventType: extensionapi.Invoke, RequestID: "2"},
+				{EventType: extensionapi.Invoke, RequestID: "3"},
+				{EventType: extensionapi.Shutdown, ShutdownReason: extensionapi.Spindown},
+			},
+		},
+	}
+	lm := &Manager{
+		extensionClient: client,
+		// Nothing sends platform.runtimeDone events to wait for
+		passive:         true,
+		listener:        listener,
+		telemetryClient: telemetryClient,
+		eventTypes:      telemetryapi.EventTypesFromEnv(),
+		configReloader:  configReloaderFromEnv(context.Background()),
+	}
+	_, err = lm.startCollector(context.Background())
+	require.NoError(t, err)
+
+	subscribedTypes := func() [][]telemetryapi.EventType {
+		var types [][]telemetryapi.EventType
+		for _, subscription := range emulator.Subscriptions() {
+			types = append(types, subscription.Request.EventTypes)
+		}
+		return types
+	}
+
+	withTypes := strings.Replace(testCollectorConfig, "exporters:\n", `  telemetryapi:
+    types: [platform, function]
+exporters:
+`, 1) + `    logs:
+      receivers: [telemetryapi]
+      exporters: [logging]
+`
+
+	client.hooks = map[int]func(){
+		// The configuration asks for function logs before the second invocation
+		1: func() {
+			time.Sleep(2 * time.Millisecond)
+			changeTestCollectorConfig(t, withTypes)
+		},
+		// and the subscription is updated at its end
+		2: func() {
+			assert.Equal(t, [][]telemetryapi.EventType{
+				{telemetryapi.Platform},
+				{telemetryapi.Platform, telemetryapi.Function},
+			}, subscribedTypes())
+
+			// Without types in the configuration, OTEL_LAMBDA_TELEMETRY_TYPES applies again
+			time.Sleep(2 * time.Millisecond)
+			changeTestCollectorConfig(t, testCollectorConfig)
+		},
+	}
+
+	require.NoError(t, lm.processEvents(context.Background()))
+	require.NoError(t, emulator.Close())
+
+	assert.Equal(t, [][]telemetryapi.EventType{
+		{telemetryapi.Platform},
+		{telemetryapi.Platform, telemetryapi.Function},
+		{telemetryapi.Platform},
+	}, subscribedTypes())
+	assert.Empty(t, client.ErrorTypes())
+}
+
+func TestInvocationContext(t *testing.T) {
+	deadline := time.Now().Add(3 * time.Second)
+
+	ctx, cancel := invocationContext(context.Background(), &extensionapi.NextEventResponse{EventType: extensionapi.Invoke, DeadlineMs: deadline.UnixMilli()})
+	defer cancel()
+
+	stopBy, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, deadline, stopBy, time.Millisecond)
+
+	ctx, cancel = invocationContext(context.Background(), &extensionapi.NextEventResponse{EventType: extensionapi.Invoke})
+	defer cancel()
+
+	_, ok = ctx.Deadline()
+	assert.False(t, ok)
+}
+
+func TestLifecycleInitError(t *testing.T) {
+	emulator := lambdaemulator.New()
+	address, err := emulator.Start("127.0.0.1:0")
+	require.NoError(t, err)
+
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", address)
+	t.Setenv("OTEL_LAMBDA_DISABLE_TELEMETRY_API", "true")
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+
+	_, lm, err := New(context.Background(), Settings{})
+	assert.Nil(t, lm)
+
+	var lifecycleErr *Error
+	require.ErrorAs(t, err, &lifecycleErr)
+	assert.Equal(t, extensionapi.ErrorConfigParseFailure, lifecycleErr.Type)
+	assert.Equal(t, ExitCodeConfig, ExitCode(err))
+
+	require.NoError(t, emulator.Close())
+	assert.Equal(t, []string{extensionapi.ErrorConfigParseFailure}, emulator.Errors())
+}
+
+func TestNewCollectorConfigFallback(t *testing.T) {
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_CONTENT", strings.Replace(testCollectorConfig, "logging:", "unknown:", 1))
+	view.Unregister(telemetryapi.MetricViews()...)
+	telemetryapi.RegisterMetricViews()
+
+	lm := &Manager{}
+	errorType, err := lm.newCollector(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, extensionapi.ErrorUnknownComponent, errorType)
+	assert.Nil(t, lm.currentCollector())
+
+	lm.configFallback = true
+	errorType, err = lm.newCollector(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, errorType)
+
+	collector := lm.currentCollector().(*ServiceCollector)
+	t.Cleanup(func() { require.NoError(t, collector.Stop(context.Background())) })
+	assert.Equal(t, "Running", collector.State())
+
+	rows, err := view.RetrieveData("telemetryapi_extension_config_fallbacks")
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, extensionapi.ErrorUnknownComponent, rows[0].Tags[0].Value)
+	assert.Equal(t, float64(1), rows[0].Data.(*view.SumData).Value)
+}
+
+func TestHealthHandler(t *testing.T) {
+	lm := &Manager{}
+
+	health := func() healthStatus {
+		recorder := httptest.NewRecorder()
+		lm.healthHandler(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var status healthStatus
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&status))
+
+		return status
+	}
+
+	assert.Equal(t, healthStatus{CollectorState: "NotStarted"}, health())
+
+	lm.collector = startTestCollector(t)
+	lm.listener = telemetryapi.NewListener(telemetryapi.ListenerConfig{})
+	lm.lastEvent.set(&extensionapi.NextEventResponse{EventType: extensionapi.Invoke, RequestID: "1"})
+
+	assert.Equal(t, healthStatus{
+		LastEventType:  extensionapi.Invoke,
+		LastRequestID:  "1",
+		CollectorState: "Running",
+	}, health())
+}
+
+func TestTimedAPI(t *testing.T) {
+	// Start from empty views, other tests record into the same global views
+	view.Unregister(telemetryapi.MetricViews()...)
+	telemetryapi.RegisterMetricViews()
+
+	client := &extensionapitest.Fake{Events: []extensionapi.NextEventResponse{{EventType: extensionapi.Shutdown}}}
+	api := timedAPI{client}
+
+	_, err := api.Register(context.Background(), "test")
+	require.NoError(t, err)
+	_, err = api.NextEvent(context.Background())
+	require.NoError(t, err)
+	_, err = api.ExitError(context.Background(), extensionapi.ErrorExportFailure)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Register", "NextEvent", "ExitError"}, client.Calls())
+
+	rows, err := view.RetrieveData("telemetryapi_extension_api_call_latency")
+	require.NoError(t, err)
+
+	var calls []string
+	for _, row := range rows {
+		calls = append(calls, row.Tags[0].Value)
+	}
+	assert.ElementsMatch(t, []string{"register", "event_next", "exit_error"}, calls)
+}
+
+func TestExtensionTimeoutsFromEnv(t *testing.T) {
+	assert.Equal(t, extensionapi.DefaultTimeouts(), extensionTimeoutsFromEnv())
+
+	t.Setenv("OTEL_LAMBDA_EXTENSION_API_TIMEOUT_MS", "250")
+	assert.Equal(t, extensionapi.Timeouts{Register: 250 * time.Millisecond, Error: 250 * time.Millisecond}, extensionTimeoutsFromEnv())
+}
+
+func TestExtensionEventTypesFromEnv(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		expected []extensionapi.EventType
+	}{
+		{value: "INVOKE,SHUTDOWN", expected: []extensionapi.EventType{extensionapi.Invoke, extensionapi.Shutdown}},
+		{value: "invoke", expected: []extensionapi.EventType{extensionapi.Invoke, extensionapi.Shutdown}},
+		{value: "SHUTDOWN", expected: []extensionapi.EventType{extensionapi.Shutdown}},
+		{value: "", expected: []extensionapi.EventType{extensionapi.Shutdown}},
+		{value: "SHUTDOWN,RESTORE", expected: []extensionapi.EventType{extensionapi.Shutdown}},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Setenv("OTEL_LAMBDA_EXTENSION_EVENTS", tc.value)
+
+			assert.Equal(t, tc.expected, extensionEventTypesFromEnv())
+		})
+	}
+}
+
+func TestPassiveFromEnv(t *testing.T) {
+	invokeAndShutdown := []extensionapi.EventType{extensionapi.Invoke, extensionapi.Shutdown}
+	shutdownOnly := []extensionapi.EventType{extensionapi.Shutdown}
+
+	for _, tc := range []struct {
+		value    string
+		events   []extensionapi.EventType
+		expected bool
+	}{
+		{value: "", events: invokeAndShutdown, expected: false},
+		{value: "active", events: invokeAndShutdown, expected: false},
+		{value: "Passive", events: invokeAndShutdown, expected: true},
+		{value: "unknown", events: invokeAndShutdown, expected: false},
+		{value: "active", events: shutdownOnly, expected: true},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Setenv("OTEL_LAMBDA_LIFECYCLE_MODE", tc.value)
+
+			assert.Equal(t, tc.expected, passiveFromEnv(tc.events))
+		})
+	}
+}
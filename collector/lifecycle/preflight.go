@@ -0,0 +1,199 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/service"
+)
+
+// endpointError is the failure to reach the endpoint of an exporter.
+type endpointError struct {
+	exporter component.ID
+	endpoint string
+	err      error
+}
+
+// preflightError is returned by the start of a collector whose exporter
+// endpoints can't be reached, see preflightFromEnv.
+type preflightError struct {
+	failures []endpointError
+}
+
+func (e *preflightError) Error() string {
+	failures := make([]string, 0, len(e.failures))
+	for _, failure := range e.failures {
+		failures = append(failures, fmt.Sprintf("cannot reach %s endpoint %s: %v", failure.exporter, failure.endpoint, failure.err))
+	}
+
+	return "exporter endpoints unreachable from the function, check its VPC, security groups and DNS resolution: " + strings.Join(failures, "; ")
+}
+
+// preflight checks the endpoints of the exporters of the configuration can be
+// reached. Invalid configurations are left to the start of the service to
+// report.
+func (c *ServiceCollector) preflight(ctx context.Context, timeout time.Duration) error {
+	cfg, err := c.configProvider.Get(ctx, c.factories)
+	if err != nil {
+		return nil
+	}
+
+	var (
+		mu          sync.Mutex
+		wg          sync.WaitGroup
+		unreachable = &preflightError{}
+	)
+	for id, endpoint := range exporterEndpoints(cfg) {
+		wg.Add(1)
+		go func(id component.ID, endpoint string) {
+			defer wg.Done()
+
+			if err := checkEndpoint(ctx, endpoint, timeout); err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				unreachable.failures = append(unreachable.failures, endpointError{exporter: id, endpoint: endpoint, err: err})
+			}
+		}(id, endpoint)
+	}
+	wg.Wait()
+
+	if len(unreachable.failures) == 0 {
+		return nil
+	}
+
+	sort.Slice(unreachable.failures, func(i, j int) bool {
+		return unreachable.failures[i].exporter.String() < unreachable.failures[j].exporter.String()
+	})
+
+	return unreachable
+}
+
+// exporterEndpoints returns the endpoints of the exporters of cfg which have
+// one, read from the Endpoint field of their settings, at any depth.
+func exporterEndpoints(cfg *service.Config) map[component.ID]string {
+	endpoints := make(map[component.ID]string)
+
+	for id, exporter := range cfg.Exporters {
+		if endpoint := findEndpoint(reflect.ValueOf(exporter)); endpoint != "" {
+			endpoints[id] = endpoint
+		}
+	}
+
+	return endpoints
+}
+
+// findEndpoint returns the first non-empty Endpoint string field of value,
+// searching embedded and nested structs depth first.
+func findEndpoint(value reflect.Value) string {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		return ""
+	}
+
+	if field := value.FieldByName("Endpoint"); field.IsValid() && field.Kind() == reflect.String && field.String() != "" {
+		return field.String()
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		if !value.Type().Field(i).IsExported() {
+			continue
+		}
+
+		if endpoint := findEndpoint(value.Field(i)); endpoint != "" {
+			return endpoint
+		}
+	}
+
+	return ""
+}
+
+// checkEndpoint resolves the host of endpoint and opens a connection to it,
+// completing a TLS handshake with https URLs and host:port endpoints on port
+// 443, within timeout.
+func checkEndpoint(ctx context.Context, endpoint string, timeout time.Duration) error {
+	address, useTLS, err := dialAddress(endpoint)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if !useTLS {
+		return nil
+	}
+
+	host, _, _ := net.SplitHostPort(address)
+	// Only the reachability is checked, the certificate is verified by the
+	// exporter with its own TLS settings, e.g. a private CA
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: host, InsecureSkipVerify: true})
+	return tlsConn.HandshakeContext(ctx)
+}
+
+// dialAddress returns the host:port to connect to for an exporter endpoint,
+// a URL or a host:port, and whether the connection uses TLS.
+func dialAddress(endpoint string) (string, bool, error) {
+	if !strings.Contains(endpoint, "://") {
+		host, port, err := net.SplitHostPort(endpoint)
+		if err != nil {
+			return "", false, err
+		}
+
+		return net.JoinHostPort(host, port), port == "443", nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", false, err
+	}
+
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "https":
+			port = "443"
+		case "http":
+			port = "80"
+		default:
+			return "", false, fmt.Errorf("no port in endpoint %q", endpoint)
+		}
+	}
+
+	return net.JoinHostPort(u.Hostname(), port), u.Scheme == "https", nil
+}
@@ -0,0 +1,117 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/lambdacomponents"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+)
+
+// closedAddress returns the address of a port nothing listens on.
+func closedAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, listener.Close())
+
+	return listener.Addr().String()
+}
+
+func TestDialAddress(t *testing.T) {
+	for _, tc := range []struct {
+		endpoint string
+		address  string
+		tls      bool
+	}{
+		{endpoint: "collector:4317", address: "collector:4317"},
+		{endpoint: "otlp.example.com:443", address: "otlp.example.com:443", tls: true},
+		{endpoint: "http://collector:4318/v1/traces", address: "collector:4318"},
+		{endpoint: "https://otlp.example.com", address: "otlp.example.com:443", tls: true},
+		{endpoint: "https://[::1]:8443/", address: "[::1]:8443", tls: true},
+	} {
+		t.Run(tc.endpoint, func(t *testing.T) {
+			address, useTLS, err := dialAddress(tc.endpoint)
+			require.NoError(t, err)
+			assert.Equal(t, tc.address, address)
+			assert.Equal(t, tc.tls, useTLS)
+		})
+	}
+
+	for _, endpoint := range []string{"collector", "unix:///var/run/otel.sock"} {
+		_, _, err := dialAddress(endpoint)
+		assert.Error(t, err, endpoint)
+	}
+}
+
+func TestCheckEndpoint(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	ctx := context.Background()
+	assert.NoError(t, checkEndpoint(ctx, server.URL, time.Second))
+	assert.NoError(t, checkEndpoint(ctx, strings.TrimPrefix(server.URL, "https://"), time.Second))
+	assert.Error(t, checkEndpoint(ctx, closedAddress(t), time.Second))
+
+	// The port is open, but doesn't speak TLS
+	plain := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer plain.Close()
+	assert.Error(t, checkEndpoint(ctx, strings.Replace(plain.URL, "http:", "https:", 1), time.Second))
+}
+
+func TestPreflight(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	unreachable := closedAddress(t)
+	config := strings.Replace(testCollectorConfig, "exporters: [logging]", "exporters: [logging, otlp, otlphttp]", 1)
+	config = strings.Replace(config, "exporters:\n", "exporters:\n  otlp:\n    endpoint: "+unreachable+"\n  otlphttp:\n    endpoint: "+server.URL+"\n", 1)
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", configFile)
+
+	factories, err := lambdacomponents.Components()
+	require.NoError(t, err)
+
+	collector, err := NewServiceCollector(factories)
+	require.NoError(t, err)
+
+	cfg, err := collector.configProvider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	assert.Equal(t, map[component.ID]string{
+		component.NewID("otlp"):     unreachable,
+		component.NewID("otlphttp"): server.URL,
+	}, exporterEndpoints(cfg))
+
+	t.Setenv("OTEL_LAMBDA_PREFLIGHT_CHECK", "true")
+	t.Setenv("OTEL_LAMBDA_PREFLIGHT_TIMEOUT_MS", "500")
+
+	err = collector.Start(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, extensionapi.ErrorExporterUnreachable, startErrorType(err))
+	assert.Contains(t, err.Error(), "cannot reach otlp endpoint "+unreachable)
+	assert.NotContains(t, err.Error(), "otlphttp")
+}
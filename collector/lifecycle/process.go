@@ -0,0 +1,275 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/utility"
+)
+
+const (
+	// processHealthInterval is the delay between the health checks of a starting collector process
+	processHealthInterval = 50 * time.Millisecond
+)
+
+// processCollector implements Collector by running an otelcol binary as a
+// child process of the extension, e.g. a custom collector build, with the
+// configuration of the extension. Its stdout and stderr are those of the
+// extension.
+type processCollector struct {
+	binary string
+	// configURIs are handed to the process with --config flags, and merged
+	// by the process in order
+	configURIs []string
+	// healthURL is polled until it answers 200 OK before Start returns, if set
+	healthURL    string
+	startTimeout time.Duration
+	httpClient   *http.Client
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	done    chan struct{}
+	waitErr error
+	stopped bool
+}
+
+// collectorBinaryFromEnv returns the otelcol binary run as a child process
+// instead of the collector of the extension, read from the
+// OTEL_LAMBDA_COLLECTOR_BINARY environment variable. Empty if unset.
+func collectorBinaryFromEnv() string {
+	return utility.GetEnvString("OTEL_LAMBDA_COLLECTOR_BINARY", "")
+}
+
+func newProcessCollector(binary string, configURIs ...string) *processCollector {
+	return &processCollector{
+		binary:       binary,
+		configURIs:   configURIs,
+		healthURL:    utility.GetEnvString("OTEL_LAMBDA_COLLECTOR_HEALTH_URL", ""),
+		startTimeout: collectorStartTimeoutFromEnv(),
+		httpClient:   &http.Client{Timeout: processHealthInterval * 4},
+	}
+}
+
+// Validate checks the binary of the collector can be run. The configuration
+// is only validated by the process once started.
+func (c *processCollector) Validate(_ context.Context) error {
+	info, err := os.Stat(c.binary)
+	if err != nil {
+		return fmt.Errorf("collector binary not found: %w", err)
+	}
+
+	if info.IsDir() || info.Mode()&0111 == 0 {
+		return fmt.Errorf("collector binary %s is not executable", c.binary)
+	}
+
+	return nil
+}
+
+// Start runs the collector process and waits for it to become healthy, up to
+// the start timeout. The process is killed if it doesn't.
+func (c *processCollector) Start(ctx context.Context) error {
+	if err := c.Validate(ctx); err != nil {
+		return err
+	}
+
+	args := make([]string, 0, len(c.configURIs))
+	for _, uri := range c.configURIs {
+		args = append(args, "--config="+uri)
+	}
+
+	cmd := exec.Command(c.binary, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start the collector process: %w", err)
+	}
+
+	done := make(chan struct{})
+
+	c.mu.Lock()
+	c.cmd = cmd
+	c.done = done
+	c.mu.Unlock()
+
+	go func() {
+		defer close(done)
+
+		err := cmd.Wait()
+
+		c.mu.Lock()
+		c.waitErr = err
+		c.mu.Unlock()
+	}()
+
+	if err := c.waitHealthy(ctx); err != nil {
+		_ = cmd.Process.Kill()
+		<-done
+
+		return err
+	}
+
+	return nil
+}
+
+// waitHealthy polls the health URL of the process until it answers 200 OK.
+// Without a health URL, it returns at once unless the process already exited.
+func (c *processCollector) waitHealthy(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.startTimeout)
+	defer cancel()
+
+	for {
+		select {
+		case <-c.done:
+			return fmt.Errorf("the collector process exited while starting: %w", c.exitErr())
+		default:
+		}
+
+		if c.healthURL == "" || c.healthy(ctx) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("the collector process is not healthy: %w", ctx.Err())
+
+		case <-c.done:
+		case <-time.After(processHealthInterval):
+		}
+	}
+}
+
+func (c *processCollector) healthy(ctx context.Context) bool {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.healthURL, nil)
+	if err != nil {
+		return false
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return false
+	}
+	response.Body.Close()
+
+	return response.StatusCode == http.StatusOK
+}
+
+// exitErr returns the error of an exited process, describing a clean exit too
+// as the process isn't expected to exit on its own.
+func (c *processCollector) exitErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.waitErr != nil {
+		return c.waitErr
+	}
+
+	return errors.New("exit status 0")
+}
+
+// Stop sends SIGTERM to the process, so it shuts its pipelines down, and
+// waits for it to exit. The process is killed once ctx is done.
+func (c *processCollector) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	cmd, done := c.cmd, c.done
+	alreadyStopped := c.stopped
+	c.stopped = true
+	c.mu.Unlock()
+
+	if cmd == nil {
+		return nil
+	}
+
+	if !alreadyStopped {
+		_ = cmd.Process.Signal(syscall.SIGTERM)
+	}
+
+	select {
+	case <-done:
+		return nil
+
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		<-done
+
+		return fmt.Errorf("collector process did not stop in time: %w", ctx.Err())
+	}
+}
+
+// Exited reports whether the process exited without being stopped.
+func (c *processCollector) Exited() bool {
+	c.mu.Lock()
+	done, stopped := c.done, c.stopped
+	c.mu.Unlock()
+
+	if done == nil || stopped {
+		return false
+	}
+
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Err returns the error the process exited with, if it exited.
+func (c *processCollector) Err() error {
+	c.mu.Lock()
+	done := c.done
+	c.mu.Unlock()
+
+	if done == nil {
+		return nil
+	}
+
+	select {
+	case <-done:
+		return c.exitErr()
+	default:
+		return nil
+	}
+}
+
+// State describes the state of the process: NotStarted, Running, Exited or Closed.
+func (c *processCollector) State() string {
+	c.mu.Lock()
+	done, stopped := c.done, c.stopped
+	c.mu.Unlock()
+
+	switch {
+	case done == nil:
+		return "NotStarted"
+	case stopped:
+		return "Closed"
+	}
+
+	select {
+	case <-done:
+		return "Exited"
+	default:
+		return "Running"
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/lambdaemulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCollectorScript writes a shell script standing in for a collector binary.
+func writeCollectorScript(t *testing.T, script string) string {
+	binary := filepath.Join(t.TempDir(), "otelcol")
+	require.NoError(t, os.WriteFile(binary, []byte("#!/bin/sh\n"+script+"\n"), 0700))
+
+	return binary
+}
+
+func TestProcessCollector(t *testing.T) {
+	argsFile := filepath.Join(t.TempDir(), "args")
+	binary := writeCollectorScript(t, `echo "$@" > `+argsFile+`; exec sleep 60`)
+
+	// The collector becomes healthy after a few checks
+	var checks int32
+	health := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&checks, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer health.Close()
+	t.Setenv("OTEL_LAMBDA_COLLECTOR_HEALTH_URL", health.URL)
+
+	collector := newProcessCollector(binary, "/opt/config.yaml", "yaml:exporters::otlp::endpoint: localhost:4317")
+	assert.Equal(t, "NotStarted", collector.State())
+	require.NoError(t, collector.Start(context.Background()))
+	assert.Equal(t, "Running", collector.State())
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&checks), int32(3))
+
+	args, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Equal(t, "--config=/opt/config.yaml --config=yaml:exporters::otlp::endpoint: localhost:4317\n", string(args))
+
+	require.NoError(t, collector.Stop(context.Background()))
+	assert.Equal(t, "Closed", collector.State())
+	assert.False(t, collector.Exited())
+}
+
+func TestProcessCollectorStartFailure(t *testing.T) {
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+
+	tests := []struct {
+		name     string
+		script   string
+		expected string
+	}{
+		{"exits", "exit 3", "the collector process exited while starting: exit status 3"},
+		{"unhealthy", "exec sleep 60", "the collector process is not healthy: context deadline exceeded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_LAMBDA_COLLECTOR_HEALTH_URL", unhealthy.URL)
+			t.Setenv("OTEL_LAMBDA_COLLECTOR_START_TIMEOUT_MS", "200")
+
+			collector := newProcessCollector(writeCollectorScript(t, tt.script), "/opt/config.yaml")
+			err := collector.Start(context.Background())
+			require.Error(t, err)
+			assert.Equal(t, tt.expected, err.Error())
+		})
+	}
+}
+
+func TestProcessCollectorExited(t *testing.T) {
+	collector := newProcessCollector(writeCollectorScript(t, "sleep 0.1; exit 1"), "/opt/config.yaml")
+	require.NoError(t, collector.Start(context.Background()))
+
+	assert.Eventually(t, collector.Exited, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "Exited", collector.State())
+	assert.EqualError(t, collector.Err(), "exit status 1")
+}
+
+func TestProcessCollectorValidate(t *testing.T) {
+	notExecutable := filepath.Join(t.TempDir(), "otelcol")
+	require.NoError(t, os.WriteFile(notExecutable, nil, 0600))
+
+	assert.Error(t, newProcessCollector(filepath.Join(t.TempDir(), "missing"), "").Validate(context.Background()))
+	assert.EqualError(t, newProcessCollector(notExecutable, "").Validate(context.Background()), "collector binary "+notExecutable+" is not executable")
+	assert.NoError(t, newProcessCollector(writeCollectorScript(t, ""), "").Validate(context.Background()))
+}
+
+func TestLifecycleCollectorProcess(t *testing.T) {
+	t.Setenv("OTEL_LAMBDA_DISABLE_TELEMETRY_API", "true")
+	t.Setenv("OTEL_LAMBDA_COLLECTOR_BINARY", writeCollectorScript(t, "exec sleep 60"))
+	writeTestCollectorConfig(t)
+
+	emulator := lambdaemulator.New(lambdaemulator.Invocation{RequestID: "1"})
+	address, err := emulator.Start("127.0.0.1:0")
+	require.NoError(t, err)
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", address)
+
+	ctx, lm, err := New(context.Background(), Settings{})
+	require.NoError(t, err)
+	assert.Equal(t, "Running", lm.collector.State())
+
+	require.NoError(t, lm.processEvents(ctx))
+	require.NoError(t, emulator.Close())
+
+	// The process is stopped on SHUTDOWN
+	assert.Empty(t, emulator.Errors())
+	assert.Equal(t, "Closed", lm.collector.State())
+}
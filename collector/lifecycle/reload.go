@@ -0,0 +1,221 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/confmap/provider/sigv4httpprovider"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/utility"
+)
+
+// configVersionTimeout bounds a single check of the configuration version
+const configVersionTimeout = 2 * time.Second
+
+// s3URIPattern matches the s3://[BUCKET].s3.[REGION].amazonaws.com/[KEY]
+// URIs of the s3 config provider.
+var s3URIPattern = regexp.MustCompile(`^s3://([^/]+)\.s3\.([^./]+)\.amazonaws\.com/(.+)$`)
+
+// configWatcher reports the version of a collector configuration source.
+type configWatcher interface {
+	// Version returns a version of the configuration which changes whenever
+	// the configuration does.
+	Version(ctx context.Context) (string, error)
+}
+
+// newConfigWatcher returns a watcher for the configuration at uri: a local
+// file, an s3 URI, an http(s) URL or a sigv4+https URL. Other sources, e.g.
+// env: or yaml:, can't be watched.
+func newConfigWatcher(ctx context.Context, uri string) (configWatcher, error) {
+	switch {
+	case strings.HasPrefix(uri, "file:"):
+		return &fileWatcher{path: strings.TrimPrefix(uri, "file:")}, nil
+
+	case strings.HasPrefix(uri, "s3:"):
+		matches := s3URIPattern.FindStringSubmatch(uri)
+		if matches == nil {
+			return nil, fmt.Errorf("invalid s3 config URI %q", uri)
+		}
+
+		cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(matches[2]))
+		if err != nil {
+			return nil, fmt.Errorf("unable to load AWS configuration: %w", err)
+		}
+
+		return &s3Watcher{client: s3.NewFromConfig(cfg), bucket: matches[1], key: matches[3]}, nil
+
+	case strings.HasPrefix(uri, "http:"), strings.HasPrefix(uri, "https:"):
+		return &httpWatcher{url: uri, httpClient: &http.Client{Timeout: configVersionTimeout}}, nil
+
+	case strings.HasPrefix(uri, "sigv4+https:"):
+		httpClient, err := sigv4httpprovider.NewClient(ctx, configVersionTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		return &httpWatcher{url: sigv4httpprovider.URL(uri), httpClient: httpClient}, nil
+
+	case strings.Contains(uri, ":"):
+		return nil, fmt.Errorf("config source %q can't be watched", uri)
+
+	default:
+		return &fileWatcher{path: uri}, nil
+	}
+}
+
+// fileWatcher versions a local file by its modification time and size.
+type fileWatcher struct {
+	path string
+}
+
+func (w *fileWatcher) Version(context.Context) (string, error) {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size()), nil
+}
+
+// s3HeadObjectAPI is the part of the S3 client used by s3Watcher.
+type s3HeadObjectAPI interface {
+	HeadObject(context.Context, *s3.HeadObjectInput, ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+}
+
+// s3Watcher versions an S3 object by its version ID, if the bucket is
+// versioned, and its ETag.
+type s3Watcher struct {
+	client s3HeadObjectAPI
+	bucket string
+	key    string
+}
+
+func (w *s3Watcher) Version(ctx context.Context) (string, error) {
+	output, err := w.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(w.bucket),
+		Key:    aws.String(w.key),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if versionID := aws.ToString(output.VersionId); versionID != "" {
+		return versionID + "-" + aws.ToString(output.ETag), nil
+	}
+
+	return aws.ToString(output.ETag), nil
+}
+
+// httpWatcher versions an HTTP resource by its ETag, or its Last-Modified
+// header if it has none.
+type httpWatcher struct {
+	url        string
+	httpClient *http.Client
+}
+
+func (w *httpWatcher) Version(ctx context.Context) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, w.url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	response, err := w.httpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return "", fmt.Errorf("request to %s failed: %s", w.url, response.Status)
+	}
+
+	if etag := response.Header.Get("ETag"); etag != "" {
+		return etag, nil
+	}
+
+	if lastModified := response.Header.Get("Last-Modified"); lastModified != "" {
+		return lastModified, nil
+	}
+
+	return "", fmt.Errorf("%s has neither an ETag nor a Last-Modified header", w.url)
+}
+
+// configReloader tracks the version of the collector configuration source,
+// checking it at most once per interval.
+type configReloader struct {
+	watcher  configWatcher
+	interval time.Duration
+	// version is the version of the configuration of the running collector
+	version string
+	checked time.Time
+}
+
+// configReloaderFromEnv returns a reloader checking the configuration set
+// by OPENTELEMETRY_COLLECTOR_CONFIG_FILE every
+// OTEL_LAMBDA_CONFIG_RELOAD_INTERVAL_MS milliseconds, or nil if reloading is
+// disabled (the default) or the configuration source can't be watched.
+func configReloaderFromEnv(ctx context.Context) *configReloader {
+	interval := time.Duration(utility.GetEnvInt("OTEL_LAMBDA_CONFIG_RELOAD_INTERVAL_MS", 0)) * time.Millisecond
+	if interval <= 0 {
+		return nil
+	}
+
+	uri, ok := os.LookupEnv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE")
+	if !ok {
+		utility.LogError(nil, "configReloaderFromEnv", "Only a configuration set by OPENTELEMETRY_COLLECTOR_CONFIG_FILE can change, config reload is disabled")
+		return nil
+	}
+
+	watcher, err := newConfigWatcher(ctx, uri)
+	if err != nil {
+		utility.LogError(err, "configReloaderFromEnv", "Cannot watch the collector configuration, config reload is disabled")
+		return nil
+	}
+
+	reloader := &configReloader{watcher: watcher, interval: interval}
+	// A change made while the collector starts is picked up at the first check
+	reloader.version, _ = reloader.watcher.Version(ctx)
+	reloader.checked = time.Now()
+
+	return reloader
+}
+
+// changed returns the new version of the configuration if it changed since
+// the running collector loaded it. It returns false until the interval since
+// the last check is over.
+func (r *configReloader) changed(ctx context.Context) (string, bool) {
+	if time.Since(r.checked) < r.interval {
+		return "", false
+	}
+
+	r.checked = time.Now()
+
+	version, err := r.watcher.Version(ctx)
+	if err != nil {
+		utility.LogError(err, "configReloader", "Cannot check the collector configuration version")
+		return "", false
+	}
+
+	return version, version != r.version
+}
@@ -0,0 +1,193 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewConfigWatcher(t *testing.T) {
+	for _, tc := range []struct {
+		uri      string
+		expected configWatcher
+	}{
+		{uri: "/opt/config.yaml", expected: &fileWatcher{path: "/opt/config.yaml"}},
+		{uri: "file:/opt/config.yaml", expected: &fileWatcher{path: "/opt/config.yaml"}},
+		{uri: "https://example.com/config.yaml", expected: &httpWatcher{url: "https://example.com/config.yaml", httpClient: &http.Client{Timeout: configVersionTimeout}}},
+	} {
+		t.Run(tc.uri, func(t *testing.T) {
+			watcher, err := newConfigWatcher(context.Background(), tc.uri)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, watcher)
+		})
+	}
+
+	for _, uri := range []string{"env:CONFIG", "yaml:receivers::otlp::protocols::grpc:", "s3://bucket/config.yaml"} {
+		t.Run(uri, func(t *testing.T) {
+			_, err := newConfigWatcher(context.Background(), uri)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestS3ConfigWatcher(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	watcher, err := newConfigWatcher(context.Background(), "s3://my-bucket.s3.eu-west-1.amazonaws.com/path/config.yaml")
+	require.NoError(t, err)
+
+	s3 := watcher.(*s3Watcher)
+	assert.Equal(t, "my-bucket", s3.bucket)
+	assert.Equal(t, "path/config.yaml", s3.key)
+}
+
+func TestSigV4ConfigWatcher(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	watcher, err := newConfigWatcher(context.Background(), "sigv4+https://abcdef1234.execute-api.eu-west-1.amazonaws.com/prod/collector")
+	require.NoError(t, err)
+
+	http := watcher.(*httpWatcher)
+	assert.Equal(t, "https://abcdef1234.execute-api.eu-west-1.amazonaws.com/prod/collector", http.url)
+	assert.NotNil(t, http.httpClient.Transport)
+}
+
+type fakeHeadObject struct {
+	output *s3.HeadObjectOutput
+	err    error
+}
+
+func (f *fakeHeadObject) HeadObject(_ context.Context, input *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	if aws.ToString(input.Bucket) != "my-bucket" || aws.ToString(input.Key) != "config.yaml" {
+		return nil, errors.New("NotFound")
+	}
+
+	return f.output, f.err
+}
+
+func TestS3WatcherVersion(t *testing.T) {
+	client := &fakeHeadObject{output: &s3.HeadObjectOutput{ETag: aws.String(`"e1"`)}}
+	watcher := &s3Watcher{client: client, bucket: "my-bucket", key: "config.yaml"}
+
+	version, err := watcher.Version(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, `"e1"`, version)
+
+	// An object uploaded again to a versioned bucket changes version
+	client.output = &s3.HeadObjectOutput{ETag: aws.String(`"e1"`), VersionId: aws.String("v2")}
+	version, err = watcher.Version(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, `v2-"e1"`, version)
+
+	client.err = errors.New("AccessDenied")
+	_, err = watcher.Version(context.Background())
+	assert.Error(t, err)
+}
+
+func TestFileWatcherVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("receivers:"), 0600))
+
+	watcher := &fileWatcher{path: path}
+	version, err := watcher.Version(context.Background())
+	require.NoError(t, err)
+
+	same, err := watcher.Version(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, version, same)
+
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	changed, err := watcher.Version(context.Background())
+	require.NoError(t, err)
+	assert.NotEqual(t, version, changed)
+
+	require.NoError(t, os.Remove(path))
+	_, err = watcher.Version(context.Background())
+	assert.Error(t, err)
+}
+
+func TestHTTPWatcherVersion(t *testing.T) {
+	headers := map[string]string{"ETag": `"v1"`}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		for k, v := range headers {
+			w.Header().Set(k, v)
+		}
+	}))
+	defer server.Close()
+
+	watcher := &httpWatcher{url: server.URL, httpClient: server.Client()}
+
+	version, err := watcher.Version(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, `"v1"`, version)
+
+	headers = map[string]string{"Last-Modified": "Wed, 21 Oct 2015 07:28:00 GMT"}
+	version, err = watcher.Version(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Wed, 21 Oct 2015 07:28:00 GMT", version)
+
+	headers = nil
+	_, err = watcher.Version(context.Background())
+	assert.Error(t, err)
+}
+
+func TestConfigReloaderInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("receivers:"), 0600))
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", path)
+	t.Setenv("OTEL_LAMBDA_CONFIG_RELOAD_INTERVAL_MS", "50")
+
+	reloader := configReloaderFromEnv(context.Background())
+	require.NotNil(t, reloader)
+
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+
+	// The change is only seen once the interval is over
+	_, changed := reloader.changed(context.Background())
+	assert.False(t, changed)
+
+	time.Sleep(60 * time.Millisecond)
+	version, changed := reloader.changed(context.Background())
+	assert.True(t, changed)
+	assert.NotEqual(t, reloader.version, version)
+}
+
+func TestConfigReloaderFromEnvDisabled(t *testing.T) {
+	assert.Nil(t, configReloaderFromEnv(context.Background()))
+
+	// The configuration bundled with the layer can't change
+	t.Setenv("OTEL_LAMBDA_CONFIG_RELOAD_INTERVAL_MS", "1000")
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", "")
+	os.Unsetenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE")
+	assert.Nil(t, configReloaderFromEnv(context.Background()))
+
+	t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", "env:CONFIG")
+	assert.Nil(t, configReloaderFromEnv(context.Background()))
+}
@@ -0,0 +1,229 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// startPollInterval is the delay between the checks of the state of a starting collector
+	startPollInterval = 10 * time.Millisecond
+	// maxStartStacksSize bounds the goroutine stacks reported for a stuck collector start
+	maxStartStacksSize = 32 * 1024
+)
+
+// startRecorder is a zap core recording, from the logs of a starting
+// collector service, the components whose start hasn't returned yet and the
+// errors logged, to tell why the collector doesn't run. It stops recording
+// once the start is over.
+type startRecorder struct {
+	fields []zapcore.Field
+	state  *startRecord
+}
+
+type startRecord struct {
+	mu      sync.Mutex
+	done    bool
+	pending map[string]bool
+	errors  []string
+}
+
+func newStartRecorder() *startRecorder {
+	return &startRecorder{state: &startRecord{pending: map[string]bool{}}}
+}
+
+func (r *startRecorder) Enabled(level zapcore.Level) bool {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+
+	return level >= zapcore.InfoLevel && !r.state.done
+}
+
+func (r *startRecorder) With(fields []zapcore.Field) zapcore.Core {
+	return &startRecorder{
+		fields: append(r.fields[:len(r.fields):len(r.fields)], fields...),
+		state:  r.state,
+	}
+}
+
+func (r *startRecorder) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if r.Enabled(entry.Level) {
+		return checked.AddCore(entry, r)
+	}
+
+	return checked
+}
+
+// Write tracks the "<Kind> is starting..." and "<Kind> started." logs of the
+// components, identified by their kind and name fields, and the errors.
+func (r *startRecorder) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	encoder := zapcore.NewMapObjectEncoder()
+	for _, field := range append(r.fields[:len(r.fields):len(r.fields)], fields...) {
+		field.AddTo(encoder)
+	}
+
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+
+	if r.state.done {
+		return nil
+	}
+
+	id := fmt.Sprintf("%v/%v", encoder.Fields["kind"], encoder.Fields["name"])
+
+	switch {
+	case strings.HasSuffix(entry.Message, " is starting..."):
+		r.state.pending[id] = true
+
+	case strings.HasSuffix(entry.Message, " started."):
+		delete(r.state.pending, id)
+
+	case entry.Level >= zapcore.ErrorLevel:
+		msg := entry.Message
+		if err, ok := encoder.Fields["error"]; ok {
+			msg += ": " + fmt.Sprint(err)
+		}
+
+		r.state.errors = append(r.state.errors, msg)
+	}
+
+	return nil
+}
+
+func (r *startRecorder) Sync() error {
+	return nil
+}
+
+// stop stops the recording, the start being over.
+func (r *startRecorder) stop() {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+
+	r.state.done = true
+}
+
+// pending returns the components still starting, sorted.
+func (r *startRecorder) pending() []string {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+
+	pending := make([]string, 0, len(r.state.pending))
+	for id := range r.state.pending {
+		pending = append(pending, id)
+	}
+
+	sort.Strings(pending)
+
+	return pending
+}
+
+// errors returns the errors logged so far.
+func (r *startRecorder) errors() []string {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+
+	return append([]string(nil), r.state.errors...)
+}
+
+// startTimeoutError is returned by ServiceCollector.Start when the collector
+// isn't running within the start timeout, with what could be found out about
+// the stuck start.
+type startTimeoutError struct {
+	timeout time.Duration
+	// pipelines summarizes the pipelines of the configuration, if it could be loaded
+	pipelines []string
+	// pending are the components whose start didn't return
+	pending []string
+	// errors are the errors logged during the start
+	errors []string
+	// stacks are the stacks of all the goroutines when the start timed out
+	stacks string
+}
+
+func (e *startTimeoutError) Error() string {
+	msg := fmt.Sprintf("collector not running after %s", e.timeout)
+
+	if len(e.pending) > 0 {
+		msg += fmt.Sprintf(", still starting: %s", strings.Join(e.pending, ", "))
+	}
+
+	if len(e.errors) > 0 {
+		msg += fmt.Sprintf(", errors: %s", strings.Join(e.errors, "; "))
+	}
+
+	if len(e.pipelines) > 0 {
+		msg += fmt.Sprintf(", pipelines: %s", strings.Join(e.pipelines, "; "))
+	}
+
+	return msg
+}
+
+// StackTrace returns the lines of the goroutine stacks.
+func (e *startTimeoutError) StackTrace() []string {
+	return strings.Split(strings.TrimSpace(e.stacks), "\n")
+}
+
+// newStartTimeoutError gathers the diagnostics of a collector not running
+// within timeout.
+func (c *ServiceCollector) newStartTimeoutError(timeout time.Duration, recorder *startRecorder) *startTimeoutError {
+	err := &startTimeoutError{
+		timeout: timeout,
+		pending: recorder.pending(),
+		errors:  recorder.errors(),
+		stacks:  goroutineStacks(),
+	}
+
+	// The configuration may be what the start is stuck on
+	ctx, cancel := context.WithTimeout(context.Background(), startPollInterval*10)
+	defer cancel()
+
+	if cfg, cfgErr := c.configProvider.Get(ctx, c.factories); cfgErr == nil {
+		for id, pipeline := range cfg.Service.Pipelines {
+			err.pipelines = append(err.pipelines, fmt.Sprintf("%s: %s -> %s -> %s", id, componentIDs(pipeline.Receivers), componentIDs(pipeline.Processors), componentIDs(pipeline.Exporters)))
+		}
+
+		sort.Strings(err.pipelines)
+	}
+
+	return err
+}
+
+func componentIDs(ids []component.ID) string {
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		names = append(names, id.String())
+	}
+
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
+// goroutineStacks returns the stacks of all the goroutines, truncated to
+// maxStartStacksSize.
+func goroutineStacks() string {
+	buf := make([]byte, maxStartStacksSize)
+	n := runtime.Stack(buf, true)
+
+	return string(buf[:n])
+}
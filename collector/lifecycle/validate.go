@@ -0,0 +1,82 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+)
+
+// ValidationReport is the outcome of Validate, printed as JSON by the
+// validation mode of the extension.
+type ValidationReport struct {
+	Valid bool `json:"valid"`
+	// Configs are the URIs of the collector configurations validated
+	Configs []string `json:"configs"`
+	// ErrorType is the error type the failure would be reported with at init
+	ErrorType string `json:"errorType,omitempty"`
+	Error     string `json:"error,omitempty"`
+	ExitCode  int    `json:"exitCode"`
+}
+
+// Validate resolves and validates the collector configurations the extension
+// would run, as set by the environment, without registering with the
+// Extensions API or starting anything, e.g. to check a configuration in CI
+// or locally before deploying it. A failure is returned as an *Error of the
+// type the extension would report it with. The collector configuration is
+// ignored in forwarding mode, there is nothing to validate then.
+func Validate(ctx context.Context, settings Settings) (ValidationReport, error) {
+	lm := &Manager{
+		components:       settings.Components,
+		collectorFactory: settings.NewCollector,
+		forwardEndpoint:  forwardEndpointFromEnv(),
+		collectorBinary:  collectorBinaryFromEnv(),
+		isolatedConfigs:  isolatedConfigsFromEnv(),
+	}
+
+	report := ValidationReport{Valid: true}
+	if lm.forwardEndpoint == "" {
+		report.Configs = append(configURIs(), lm.isolatedConfigs...)
+	}
+
+	err := lm.validate(ctx)
+	if err != nil {
+		lifecycleErr := err.(*Error)
+
+		report.Valid = false
+		report.ErrorType = lifecycleErr.Type
+		report.Error = lifecycleErr.Err.Error()
+		report.ExitCode = ExitCode(err)
+	}
+
+	return report, err
+}
+
+// validate builds the collector and validates its configuration.
+func (lm *Manager) validate(ctx context.Context) error {
+	if lm.forwardEndpoint != "" {
+		return nil
+	}
+
+	collector, errorType, err := lm.buildCollector()
+	if err != nil {
+		return &Error{Type: errorType, Err: err}
+	}
+
+	if err := collector.Validate(ctx); err != nil {
+		return &Error{Type: startErrorType(err), Err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	for name, test := range map[string]struct {
+		config    string
+		env       map[string]string
+		errorType string
+	}{
+		"valid": {
+			config: testCollectorConfig,
+		},
+		"parse failure": {
+			config:    "receivers: [",
+			errorType: extensionapi.ErrorConfigParseFailure,
+		},
+		"unknown component": {
+			config:    strings.Replace(testCollectorConfig, "logging", "unknown", -1),
+			errorType: extensionapi.ErrorUnknownComponent,
+		},
+		"invalid configuration": {
+			config:    strings.Replace(testCollectorConfig, "exporters: [logging]", "exporters: []", 1),
+			errorType: extensionapi.ErrorConfigInvalid,
+		},
+		"invalid isolated configuration": {
+			config:    testCollectorConfig,
+			env:       map[string]string{"OTEL_LAMBDA_ISOLATED_CONFIGS": "yaml:receivers: ["},
+			errorType: extensionapi.ErrorConfigParseFailure,
+		},
+		"forwarding mode": {
+			config: "receivers: [",
+			env:    map[string]string{"OTEL_LAMBDA_FORWARD_ENDPOINT": "http://localhost:4318"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(configFile, []byte(test.config), 0600))
+			t.Setenv("OPENTELEMETRY_COLLECTOR_CONFIG_FILE", configFile)
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+
+			report, err := Validate(context.Background(), Settings{})
+			assert.Equal(t, test.errorType == "", report.Valid)
+			assert.Equal(t, test.errorType, report.ErrorType)
+			assert.Equal(t, ExitCode(err), report.ExitCode)
+			if _, forwarding := test.env["OTEL_LAMBDA_FORWARD_ENDPOINT"]; forwarding {
+				assert.Empty(t, report.Configs)
+			} else {
+				assert.Equal(t, configFile, report.Configs[0])
+			}
+
+			if test.errorType == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var lifecycleErr *Error
+			require.ErrorAs(t, err, &lifecycleErr)
+			assert.Equal(t, test.errorType, lifecycleErr.Type)
+			assert.Equal(t, lifecycleErr.Err.Error(), report.Error)
+		})
+	}
+}
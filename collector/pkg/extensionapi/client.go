@@ -0,0 +1,394 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extensionapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RegisterResponse is the body of the response for /register
+type RegisterResponse struct {
+	FunctionName    string `json:"functionName"`
+	FunctionVersion string `json:"functionVersion"`
+	Handler         string `json:"handler"`
+	// AccountID is the AWS account of the function. It is only sent when the
+	// accountId feature is accepted, which the Client always requests.
+	AccountID   string `json:"accountId,omitempty"`
+	ExtensionID string
+}
+
+// NextEventResponse is the response for /event/next
+type NextEventResponse struct {
+	EventType          EventType `json:"eventType"`
+	DeadlineMs         int64     `json:"deadlineMs"`
+	RequestID          string    `json:"requestId"`
+	InvokedFunctionArn string    `json:"invokedFunctionArn"`
+	Tracing            Tracing   `json:"tracing"`
+	// ShutdownReason is only set for SHUTDOWN events
+	ShutdownReason ShutdownReason `json:"shutdownReason,omitempty"`
+}
+
+// Tracing is part of the response for /event/next
+type Tracing struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// StatusResponse is the body of the response for /init/error and /exit/error
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+// ShutdownReason is why the execution environment is shut down
+type ShutdownReason string
+
+const (
+	// Spindown is the regular shutdown of an idle execution environment
+	Spindown ShutdownReason = "spindown"
+	// Timeout is the shutdown following a function timeout
+	Timeout ShutdownReason = "timeout"
+	// Failure is the shutdown following a runtime or extension failure
+	Failure ShutdownReason = "failure"
+)
+
+// Error types reported by the extension to /init/error and /exit/error. The
+// details of the error are logged, so the error type stays a stable
+// identifier in the Extension.<Reason> format expected by Lambda.
+const (
+	// ErrorRegisterFailure is reported when the extension can't register
+	ErrorRegisterFailure = "Extension.RegisterFailure"
+	// ErrorListenerStartFailure is reported when the Telemetry API listener can't start
+	ErrorListenerStartFailure = "Extension.ListenerStartFailure"
+	// ErrorSubscribeFailure is reported when the Telemetry API subscription fails
+	ErrorSubscribeFailure = "Extension.SubscribeFailure"
+	// ErrorConfigInvalid is reported when the collector configuration can't be loaded or is invalid
+	ErrorConfigInvalid = "Extension.ConfigInvalid"
+	// ErrorConfigParseFailure is reported when the collector configuration can't be retrieved or parsed
+	ErrorConfigParseFailure = "Extension.ConfigParseFailure"
+	// ErrorUnknownComponent is reported when the collector configuration uses a component type that isn't built in
+	ErrorUnknownComponent = "Extension.UnknownComponent"
+	// ErrorPortBindFailure is reported when a receiver of the collector can't listen on its address
+	ErrorPortBindFailure = "Extension.PortBindFailure"
+	// ErrorAuthExtensionFailure is reported when an authentication extension of the collector fails
+	ErrorAuthExtensionFailure = "Extension.AuthExtensionFailure"
+	// ErrorCollectorStartFailure is reported when the collector can't start for another reason
+	ErrorCollectorStartFailure = "Extension.CollectorStartFailure"
+	// ErrorCollectorStartTimeout is reported when the collector isn't running within its start timeout
+	ErrorCollectorStartTimeout = "Extension.CollectorStartTimeout"
+	// ErrorExporterUnreachable is reported when the endpoint of an exporter can't be reached on start
+	ErrorExporterUnreachable = "Extension.ExporterUnreachable"
+	// ErrorNextEventFailure is reported when the next event can't be received
+	ErrorNextEventFailure = "Extension.NextEventFailure"
+	// ErrorExportFailure is reported when the exporters can't be flushed on shutdown
+	ErrorExportFailure = "Extension.ExportFailure"
+	// ErrorHookFailure is reported when a lifecycle hook of the extension fails its init
+	ErrorHookFailure = "Extension.HookFailure"
+	// ErrorPanic is reported when the extension or the collector panics and can't recover
+	ErrorPanic = "Extension.Panic"
+)
+
+// ErrorResponse is the body of the error responses of the Extensions API
+type ErrorResponse struct {
+	ErrorType    string `json:"errorType"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// ErrorRequest is the optional body of the /init/error and /exit/error
+// requests, detailing the reported error in the logs of the function.
+type ErrorRequest struct {
+	ErrorType    string   `json:"errorType"`
+	ErrorMessage string   `json:"errorMessage"`
+	StackTrace   []string `json:"stackTrace,omitempty"`
+}
+
+// EventType represents the type of events recieved from /event/next
+type EventType string
+
+const (
+	SchemaVersion20200101 = "2020-01-01"
+	SchemaVersionLatest   = SchemaVersion20200101
+
+	// FeatureAccountID adds the accountId field to the /register response
+	FeatureAccountID = "accountId"
+
+	// Invoke is a lambda INVOKE event
+	Invoke EventType = "INVOKE"
+
+	// Shutdown is a SHUTDOWN event for the environment
+	Shutdown EventType = "SHUTDOWN"
+
+	ExtensionNameHeader      = "Lambda-Extension-Name"
+	ExtensionIdentiferHeader = "Lambda-Extension-Identifier"
+	ExtensionErrorType       = "Lambda-Extension-Function-Error-Type"
+	ExtensionFeatureHeader   = "Lambda-Extension-Accept-Feature"
+
+	// maxErrorBodySize bounds how much of an error response is read
+	maxErrorBodySize = 4096
+	// defaultTimeout bounds the requests other than /event/next
+	defaultTimeout = 5 * time.Second
+)
+
+// API is the Lambda Extensions API, as used by an extension over its lifecycle.
+type API interface {
+	Register(ctx context.Context, extensionName string, events ...EventType) (*RegisterResponse, error)
+	NextEvent(ctx context.Context) (*NextEventResponse, error)
+	InitError(ctx context.Context, errorType string) (*StatusResponse, error)
+	ExitError(ctx context.Context, errorType string) (*StatusResponse, error)
+}
+
+// ErrorRequestAPI is implemented by the API clients able to report the
+// details of an error along with its type.
+type ErrorRequestAPI interface {
+	InitErrorRequest(ctx context.Context, errorRequest ErrorRequest) (*StatusResponse, error)
+	ExitErrorRequest(ctx context.Context, errorRequest ErrorRequest) (*StatusResponse, error)
+}
+
+var (
+	_ API             = (*Client)(nil)
+	_ ErrorRequestAPI = (*Client)(nil)
+)
+
+// Timeouts bound the requests of a Client by endpoint. Zero means no timeout.
+type Timeouts struct {
+	// Register bounds /register
+	Register time.Duration
+	// NextEvent bounds /event/next, which long polls until the next event
+	NextEvent time.Duration
+	// Error bounds /init/error and /exit/error
+	Error time.Duration
+}
+
+// DefaultTimeouts returns short timeouts for every endpoint but the long
+// polling /event/next.
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		Register: defaultTimeout,
+		Error:    defaultTimeout,
+	}
+}
+
+// registerFeatures are the optional /register response fields requested by
+// the Client. The Extensions API leaves out the ones it doesn't support.
+var registerFeatures = []string{FeatureAccountID}
+
+// Client is a simple client for the Lambda Extensions API.
+type Client struct {
+	baseURL     string
+	extensionID string
+	timeouts    Timeouts
+	httpClient  *http.Client
+}
+
+// NewClient returns a Lambda Extensions API client for the given schema
+// version of the API, SchemaVersionLatest if empty.
+//  POST http://${AWS_RUNTIME_API}/2020-01-01/extension
+func NewClient(awsLambdaRuntimeAPI string, schemaVersion string, timeouts Timeouts) *Client {
+	if schemaVersion == "" {
+		schemaVersion = SchemaVersionLatest
+	}
+
+	baseURL := fmt.Sprintf("http://%s/%s/extension", awsLambdaRuntimeAPI, schemaVersion)
+
+	return &Client{
+		baseURL:    baseURL,
+		timeouts:   timeouts,
+		httpClient: &http.Client{},
+	}
+}
+
+// Register will register the extension with the Extensions API for the given
+// events, INVOKE and SHUTDOWN if none are given.
+// Each API call must include the Lambda-Extension-Name header.
+//  Reference: https://github.com/awsdocs/aws-lambda-developer-guide/blob/main/doc_source/telemetry-api.md#telemetry-api-registration
+func (e *Client) Register(ctx context.Context, extensionName string, events ...EventType) (*RegisterResponse, error) {
+	const action = "/register"
+	url := e.baseURL + action
+
+	ctx, cancel := withTimeout(ctx, e.timeouts.Register)
+	defer cancel()
+
+	if len(events) == 0 {
+		events = []EventType{Invoke, Shutdown}
+	}
+
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"events": events,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set(ExtensionNameHeader, extensionName)
+	request.Header.Set(ExtensionFeatureHeader, strings.Join(registerFeatures, ","))
+
+	var registerResponse RegisterResponse
+	response, err := e.doRequest(request, &registerResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	e.extensionID = response.Header.Get(ExtensionIdentiferHeader)
+	registerResponse.ExtensionID = e.extensionID
+
+	return &registerResponse, nil
+}
+
+// NextEvent blocks while long polling for the next lambda invoke or shutdown.
+func (e *Client) NextEvent(ctx context.Context) (*NextEventResponse, error) {
+	const action = "/event/next"
+	url := e.baseURL + action
+
+	ctx, cancel := withTimeout(ctx, e.timeouts.NextEvent)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set(ExtensionIdentiferHeader, e.extensionID)
+
+	var nextEventResponse NextEventResponse
+	_, err = e.doRequest(request, &nextEventResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	return &nextEventResponse, nil
+}
+
+// InitError reports an initialization error to the platform.
+// Call it when you registered but failed to initialize.
+func (e *Client) InitError(ctx context.Context, errorType string) (*StatusResponse, error) {
+	return e.reportError(ctx, "/init/error", errorType, nil)
+}
+
+// ExitError reports an error to the platform before exiting.
+// Call it when you encounter an unexpected failure.
+func (e *Client) ExitError(ctx context.Context, errorType string) (*StatusResponse, error) {
+	return e.reportError(ctx, "/exit/error", errorType, nil)
+}
+
+// InitErrorRequest reports an initialization error to the platform along
+// with its details.
+func (e *Client) InitErrorRequest(ctx context.Context, errorRequest ErrorRequest) (*StatusResponse, error) {
+	return e.reportError(ctx, "/init/error", errorRequest.ErrorType, &errorRequest)
+}
+
+// ExitErrorRequest reports an error to the platform before exiting, along
+// with its details.
+func (e *Client) ExitErrorRequest(ctx context.Context, errorRequest ErrorRequest) (*StatusResponse, error) {
+	return e.reportError(ctx, "/exit/error", errorRequest.ErrorType, &errorRequest)
+}
+
+// reportError posts an error of the given type to action, with errorRequest
+// as body if not nil.
+func (e *Client) reportError(ctx context.Context, action string, errorType string, errorRequest *ErrorRequest) (*StatusResponse, error) {
+	url := e.baseURL + action
+
+	ctx, cancel := withTimeout(ctx, e.timeouts.Error)
+	defer cancel()
+
+	var body io.Reader
+	if errorRequest != nil {
+		data, err := json.Marshal(errorRequest)
+		if err != nil {
+			return nil, err
+		}
+
+		body = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set(ExtensionErrorType, errorType)
+	request.Header.Set(ExtensionIdentiferHeader, e.extensionID)
+
+	var statusResponse StatusResponse
+	_, err = e.doRequest(request, &statusResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	return &statusResponse, nil
+}
+
+// doRequest sends an HTTP request and returns an HTTP response.
+func (e *Client) doRequest(request *http.Request, out interface{}) (*http.Response, error) {
+	response, err := e.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != 200 {
+		return nil, responseError(response)
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(body, out)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// responseError returns the error of a failed request, with the error type
+// and message of its body, or the body itself if it is not an ErrorResponse.
+func responseError(response *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBodySize))
+
+	var errorResponse ErrorResponse
+	if json.Unmarshal(body, &errorResponse) == nil && errorResponse.ErrorType != "" {
+		return fmt.Errorf("request failed with status %s: %s: %s", response.Status, errorResponse.ErrorType, errorResponse.ErrorMessage)
+	}
+
+	if body = bytes.TrimSpace(body); len(body) > 0 {
+		return fmt.Errorf("request failed with status %s: %s", response.Status, body)
+	}
+
+	return fmt.Errorf("request failed with status %s", response.Status)
+}
+
+// withTimeout returns a context bounded by timeout, if it is positive.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,195 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extensionapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterError(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "error response",
+			body:     `{"errorType":"Extension.InvalidName","errorMessage":"name is not unique"}`,
+			expected: "request failed with status 400 Bad Request: Extension.InvalidName: name is not unique",
+		},
+		{
+			name:     "text body",
+			body:     "bad request\n",
+			expected: "request failed with status 400 Bad Request: bad request",
+		},
+		{
+			name:     "empty body",
+			expected: "request failed with status 400 Bad Request",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			_, err := NewClient(strings.TrimPrefix(server.URL, "http://"), SchemaVersionLatest, DefaultTimeouts()).Register(context.Background(), "test")
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		_, _ = w.Write([]byte(`{"eventType":"SHUTDOWN"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(strings.TrimPrefix(server.URL, "http://"), SchemaVersionLatest, Timeouts{Register: 10 * time.Millisecond, Error: 10 * time.Millisecond})
+
+	_, err := client.Register(context.Background(), "test")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = client.ExitError(context.Background(), "test.Error")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// /event/next is not bounded
+	next := make(chan error)
+	go func() {
+		_, err := client.NextEvent(context.Background())
+		next <- err
+	}()
+
+	select {
+	case err := <-next:
+		t.Fatalf("NextEvent returned before the event: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	assert.NoError(t, <-next)
+}
+
+func TestRegisterEvents(t *testing.T) {
+	var registered []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		registered = append(registered, request["events"])
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(strings.TrimPrefix(server.URL, "http://"), SchemaVersionLatest, DefaultTimeouts())
+
+	_, err := client.Register(context.Background(), "test")
+	assert.NoError(t, err)
+	_, err = client.Register(context.Background(), "test", Shutdown)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []interface{}{[]interface{}{"INVOKE", "SHUTDOWN"}, []interface{}{"SHUTDOWN"}}, registered)
+}
+
+func TestRegisterAccountID(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		schemaVersion string
+		body          string
+		expected      RegisterResponse
+	}{
+		{
+			name: "accountId sent",
+			body: `{"functionName":"fn","functionVersion":"$LATEST","handler":"index.handler","accountId":"123456789012"}`,
+			expected: RegisterResponse{
+				FunctionName:    "fn",
+				FunctionVersion: "$LATEST",
+				Handler:         "index.handler",
+				AccountID:       "123456789012",
+				ExtensionID:     "ext-id",
+			},
+		},
+		{
+			name:          "accountId not supported",
+			schemaVersion: SchemaVersion20200101,
+			body:          `{"functionName":"fn","functionVersion":"$LATEST","handler":"index.handler"}`,
+			expected: RegisterResponse{
+				FunctionName:    "fn",
+				FunctionVersion: "$LATEST",
+				Handler:         "index.handler",
+				ExtensionID:     "ext-id",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/2020-01-01/extension/register", r.URL.Path)
+				assert.Equal(t, FeatureAccountID, r.Header.Get(ExtensionFeatureHeader))
+				w.Header().Set(ExtensionIdentiferHeader, "ext-id")
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			response, err := NewClient(strings.TrimPrefix(server.URL, "http://"), tc.schemaVersion, DefaultTimeouts()).Register(context.Background(), "test")
+			assert.NoError(t, err)
+			assert.Equal(t, &tc.expected, response)
+		})
+	}
+}
+
+func TestErrorRequest(t *testing.T) {
+	var (
+		paths, errorTypes []string
+		bodies            []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		paths = append(paths, r.URL.Path)
+		errorTypes = append(errorTypes, r.Header.Get(ExtensionErrorType))
+		bodies = append(bodies, string(body))
+		_, _ = w.Write([]byte(`{"status":"OK"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(strings.TrimPrefix(server.URL, "http://"), SchemaVersionLatest, DefaultTimeouts())
+
+	_, err := client.InitError(context.Background(), "test.Error")
+	assert.NoError(t, err)
+	_, err = client.InitErrorRequest(context.Background(), ErrorRequest{ErrorType: "test.Timeout", ErrorMessage: "stuck", StackTrace: []string{"goroutine 1"}})
+	assert.NoError(t, err)
+	_, err = client.ExitErrorRequest(context.Background(), ErrorRequest{ErrorType: "test.Failure", ErrorMessage: "failed"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"/2020-01-01/extension/init/error", "/2020-01-01/extension/init/error", "/2020-01-01/extension/exit/error"}, paths)
+	assert.Equal(t, []string{"test.Error", "test.Timeout", "test.Failure"}, errorTypes)
+	assert.Equal(t, []string{
+		"",
+		`{"errorType":"test.Timeout","errorMessage":"stuck","stackTrace":["goroutine 1"]}`,
+		`{"errorType":"test.Failure","errorMessage":"failed"}`,
+	}, bodies)
+}
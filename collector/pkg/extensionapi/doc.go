@@ -0,0 +1,26 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package extensionapi is a client for the Lambda Extensions API, for use by
+// any external Lambda extension written in Go. It is a standalone module
+// without dependencies, so it can be imported outside of the collector.
+//
+// An extension registers once at init, then long polls NextEvent until it
+// receives a SHUTDOWN event, reporting failures with InitError and ExitError.
+// Code depending on the API interface rather than on Client can be unit
+// tested against the in-memory implementation of the extensionapitest
+// package.
+//
+// See https://docs.aws.amazon.com/lambda/latest/dg/runtimes-extensions-api.html
+package extensionapi // import "github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi"
@@ -0,0 +1,75 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extensionapi_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi"
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi/extensionapitest"
+)
+
+// run is the event loop of an extension, written against the API interface.
+func run(ctx context.Context, api extensionapi.API) error {
+	// The name of an extension must match the name of its executable
+	_, err := api.Register(ctx, "my-extension", extensionapi.Invoke, extensionapi.Shutdown)
+	if err != nil {
+		return err
+	}
+
+	for {
+		event, err := api.NextEvent(ctx)
+		if err != nil {
+			_, _ = api.ExitError(ctx, "Extension.NextEventFailure")
+			return err
+		}
+
+		if event.EventType == extensionapi.Shutdown {
+			fmt.Println("shutdown:", event.ShutdownReason)
+			return nil
+		}
+
+		fmt.Println("invoke:", event.RequestID)
+	}
+}
+
+func ExampleClient() {
+	client := extensionapi.NewClient(os.Getenv("AWS_LAMBDA_RUNTIME_API"), extensionapi.SchemaVersionLatest, extensionapi.DefaultTimeouts())
+
+	if err := run(context.Background(), client); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func Example_fake() {
+	api := &extensionapitest.Fake{
+		Events: []extensionapi.NextEventResponse{
+			{EventType: extensionapi.Invoke, RequestID: "1"},
+			{EventType: extensionapi.Shutdown, ShutdownReason: extensionapi.Spindown},
+		},
+	}
+
+	if err := run(context.Background(), api); err != nil {
+		fmt.Println(err)
+	}
+
+	fmt.Println(api.Calls())
+	// Output:
+	// invoke: 1
+	// shutdown: spindown
+	// [Register NextEvent NextEvent]
+}
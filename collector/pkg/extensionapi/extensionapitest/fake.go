@@ -0,0 +1,167 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package extensionapitest provides an in-memory implementation of the
+// Extensions API for unit tests of code depending on extensionapi.API.
+package extensionapitest // import "github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi/extensionapitest"
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/pkg/extensionapi"
+)
+
+// ExtensionID is the extension identifier returned by Fake.Register.
+const ExtensionID = "fake-extension-id"
+
+var (
+	// ErrRegister is returned by the failing Register calls of a Fake.
+	ErrRegister = errors.New("register failed")
+	// ErrNextEvent is returned by the failing NextEvent calls of a Fake.
+	ErrNextEvent = errors.New("next event failed")
+	// ErrNoMoreEvents is returned by NextEvent once the scripted events are used.
+	ErrNoMoreEvents = errors.New("no more events")
+)
+
+// Fake is an extensionapi.API handing out scripted events and recording the
+// calls made to it. It is safe for concurrent use.
+type Fake struct {
+	// Events are handed out in order by NextEvent.
+	Events []extensionapi.NextEventResponse
+	// RegisterFailures is the number of Register calls failing with
+	// ErrRegister before one succeeds.
+	RegisterFailures int
+	// NextEventFailures is the number of NextEvent calls failing with
+	// ErrNextEvent before the scripted events are handed out.
+	NextEventFailures int
+
+	mu         sync.Mutex
+	calls      []string
+	registered []extensionapi.EventType
+	errorTypes []string
+	requests   []extensionapi.ErrorRequest
+}
+
+var (
+	_ extensionapi.API             = (*Fake)(nil)
+	_ extensionapi.ErrorRequestAPI = (*Fake)(nil)
+)
+
+// Register registers for events, or fails while RegisterFailures is not reached.
+func (f *Fake) Register(_ context.Context, _ string, events ...extensionapi.EventType) (*extensionapi.RegisterResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, "Register")
+	if f.RegisterFailures > 0 {
+		f.RegisterFailures--
+		return nil, ErrRegister
+	}
+
+	f.registered = events
+
+	return &extensionapi.RegisterResponse{FunctionName: "fake-function", ExtensionID: ExtensionID}, nil
+}
+
+// NextEvent returns the next scripted event, or ErrNoMoreEvents. It fails
+// while NextEventFailures is not reached.
+func (f *Fake) NextEvent(ctx context.Context) (*extensionapi.NextEventResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, "NextEvent")
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if f.NextEventFailures > 0 {
+		f.NextEventFailures--
+		return nil, ErrNextEvent
+	}
+
+	if len(f.Events) == 0 {
+		return nil, ErrNoMoreEvents
+	}
+
+	event := f.Events[0]
+	f.Events = f.Events[1:]
+
+	return &event, nil
+}
+
+// InitError records the reported error type.
+func (f *Fake) InitError(_ context.Context, errorType string) (*extensionapi.StatusResponse, error) {
+	return f.reportError("InitError", errorType)
+}
+
+// ExitError records the reported error type.
+func (f *Fake) ExitError(_ context.Context, errorType string) (*extensionapi.StatusResponse, error) {
+	return f.reportError("ExitError", errorType)
+}
+
+// InitErrorRequest records the reported error type and details.
+func (f *Fake) InitErrorRequest(_ context.Context, errorRequest extensionapi.ErrorRequest) (*extensionapi.StatusResponse, error) {
+	return f.reportError("InitError", errorRequest.ErrorType, errorRequest)
+}
+
+// ExitErrorRequest records the reported error type and details.
+func (f *Fake) ExitErrorRequest(_ context.Context, errorRequest extensionapi.ErrorRequest) (*extensionapi.StatusResponse, error) {
+	return f.reportError("ExitError", errorRequest.ErrorType, errorRequest)
+}
+
+func (f *Fake) reportError(call string, errorType string, errorRequests ...extensionapi.ErrorRequest) (*extensionapi.StatusResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, call)
+	f.errorTypes = append(f.errorTypes, errorType)
+	f.requests = append(f.requests, errorRequests...)
+
+	return &extensionapi.StatusResponse{Status: "OK"}, nil
+}
+
+// Calls returns the names of the methods called so far, in order.
+func (f *Fake) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.calls...)
+}
+
+// Registered returns the events of the last successful Register call.
+func (f *Fake) Registered() []extensionapi.EventType {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]extensionapi.EventType(nil), f.registered...)
+}
+
+// ErrorTypes returns the error types reported to InitError and ExitError.
+func (f *Fake) ErrorTypes() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.errorTypes...)
+}
+
+// ErrorRequests returns the details of the errors reported to
+// InitErrorRequest and ExitErrorRequest.
+func (f *Fake) ErrorRequests() []extensionapi.ErrorRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]extensionapi.ErrorRequest(nil), f.requests...)
+}
@@ -0,0 +1,93 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package invocation holds the metadata of the Lambda invocation in progress,
+// so that collector components, e.g. a processor adding attributes, can
+// enrich the telemetry flowing during an invocation with it. The extension
+// sets the current invocation on each INVOKE event:
+//
+//	if info, ok := invocation.FromContext(ctx); ok {
+//		attrs.PutStr("faas.invocation_id", info.RequestID)
+//	}
+//
+// Receivers don't pass the context of the extension on to the pipelines, so
+// FromContext falls back to the current invocation.
+package invocation
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Info describes an invocation.
+type Info struct {
+	RequestID string
+	// Deadline is when the invocation times out, zero if unknown
+	Deadline time.Time
+	// ColdStart is whether this is the first invocation of the execution environment
+	ColdStart bool
+	// FunctionARN is the ARN the function was invoked with, with its qualifier if any
+	FunctionARN string
+}
+
+type contextKey struct{}
+
+var (
+	mu      sync.RWMutex
+	current *Info
+)
+
+// Set makes info the current invocation.
+func Set(info Info) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	current = &info
+}
+
+// Clear forgets the current invocation, once it is over.
+func Clear() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	current = nil
+}
+
+// Current returns the current invocation, if any.
+func Current() (Info, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if current == nil {
+		return Info{}, false
+	}
+
+	return *current, true
+}
+
+// NewContext returns a copy of ctx carrying info.
+func NewContext(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, contextKey{}, info)
+}
+
+// FromContext returns the invocation carried by ctx, or else the current
+// invocation, if any.
+func FromContext(ctx context.Context) (Info, bool) {
+	if info, ok := ctx.Value(contextKey{}).(Info); ok {
+		return info, true
+	}
+
+	return Current()
+}
@@ -0,0 +1,58 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package invocation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurrent(t *testing.T) {
+	t.Cleanup(Clear)
+
+	_, ok := Current()
+	assert.False(t, ok)
+
+	info := Info{RequestID: "1", Deadline: time.Now(), ColdStart: true, FunctionARN: "arn:aws:lambda:eu-west-1:123456789012:function:f"}
+	Set(info)
+
+	current, ok := Current()
+	assert.True(t, ok)
+	assert.Equal(t, info, current)
+
+	Clear()
+	_, ok = Current()
+	assert.False(t, ok)
+}
+
+func TestFromContext(t *testing.T) {
+	t.Cleanup(Clear)
+
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	// The current invocation is the fallback
+	Set(Info{RequestID: "current"})
+	info, ok := FromContext(context.Background())
+	assert.True(t, ok)
+	assert.Equal(t, "current", info.RequestID)
+
+	info, ok = FromContext(NewContext(context.Background(), Info{RequestID: "ctx"}))
+	assert.True(t, ok)
+	assert.Equal(t, "ctx", info.RequestID)
+}
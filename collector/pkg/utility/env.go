@@ -0,0 +1,51 @@
+package utility
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// GetEnvString returns the value of the environment variable key,
+// or def if it is not set.
+func GetEnvString(key string, def string) string {
+	if val, ok := os.LookupEnv(key); ok {
+		return val
+	}
+
+	return def
+}
+
+// GetEnvInt returns the integer value of the environment variable key,
+// or def if it is not set or cannot be parsed.
+func GetEnvInt(key string, def int) int {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+
+	i, err := strconv.Atoi(val)
+	if err != nil {
+		LogError(err, "GetEnvInt", fmt.Sprintf("Invalid value for %s, using default", key), KeyValue{K: "value", V: val}, KeyValue{K: "default", V: def})
+		return def
+	}
+
+	return i
+}
+
+// GetEnvBool returns the boolean value of the environment variable key,
+// or def if it is not set or cannot be parsed.
+func GetEnvBool(key string, def bool) bool {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		LogError(err, "GetEnvBool", fmt.Sprintf("Invalid value for %s, using default", key), KeyValue{K: "value", V: val}, KeyValue{K: "default", V: def})
+		return def
+	}
+
+	return b
+}
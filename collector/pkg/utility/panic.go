@@ -0,0 +1,34 @@
+package utility
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is the error of a goroutine which panicked, recovered by
+// RecoverPanic.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// RecoverPanic recovers from a panic of the calling goroutine, logs it with
+// its stack under code, and hands it to handle as a *PanicError. It must be
+// deferred directly, e.g. defer utility.RecoverPanic("Collector", handle).
+func RecoverPanic(code string, handle func(err *PanicError)) {
+	value := recover()
+	if value == nil {
+		return
+	}
+
+	err := &PanicError{Value: value, Stack: debug.Stack()}
+	LogError(err, code, "Recovered from a panic", KeyValue{K: "stack", V: string(err.Stack)})
+
+	if handle != nil {
+		handle(err)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decoupleprocessor // import "github.com/open-telemetry/opentelemetry-lambda/collector/processor/decoupleprocessor"
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// Shutdown priorities, exported first to last
+const (
+	priorityTraces = iota
+	priorityErrorLogs
+	priorityMetrics
+	priorityLogs
+)
+
+// spillSeq keeps the names of the files spilled within a nanosecond apart
+var spillSeq uint64
+
+// batch is data waiting to be exported by a decouple processor. Only the
+// field of its data type is set.
+type batch struct {
+	dataType component.DataType
+	traces   ptrace.Traces
+	metrics  pmetric.Metrics
+	logs     plog.Logs
+}
+
+// priority returns the rank of the batch in the shutdown export order.
+func (b batch) priority() int {
+	switch b.dataType {
+	case component.DataTypeTraces:
+		return priorityTraces
+	case component.DataTypeMetrics:
+		return priorityMetrics
+	}
+
+	if hasErrors(b.logs) {
+		return priorityErrorLogs
+	}
+
+	return priorityLogs
+}
+
+// hasErrors reports whether logs has a record with a severity of ERROR or above.
+func hasErrors(logs plog.Logs) bool {
+	for i := 0; i < logs.ResourceLogs().Len(); i++ {
+		scopeLogs := logs.ResourceLogs().At(i).ScopeLogs()
+		for j := 0; j < scopeLogs.Len(); j++ {
+			records := scopeLogs.At(j).LogRecords()
+			for k := 0; k < records.Len(); k++ {
+				if records.At(k).SeverityNumber() >= plog.SeverityNumberError {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func (b batch) marshal() ([]byte, error) {
+	switch b.dataType {
+	case component.DataTypeTraces:
+		return (&ptrace.JSONMarshaler{}).MarshalTraces(b.traces)
+	case component.DataTypeMetrics:
+		return (&pmetric.JSONMarshaler{}).MarshalMetrics(b.metrics)
+	default:
+		return (&plog.JSONMarshaler{}).MarshalLogs(b.logs)
+	}
+}
+
+func unmarshalBatch(dataType component.DataType, data []byte) (batch, error) {
+	var err error
+	b := batch{dataType: dataType}
+
+	switch dataType {
+	case component.DataTypeTraces:
+		b.traces, err = (&ptrace.JSONUnmarshaler{}).UnmarshalTraces(data)
+	case component.DataTypeMetrics:
+		b.metrics, err = (&pmetric.JSONUnmarshaler{}).UnmarshalMetrics(data)
+	default:
+		b.logs, err = (&plog.JSONUnmarshaler{}).UnmarshalLogs(data)
+	}
+
+	return b, err
+}
+
+// spillPrefix returns the prefix of the files spilled by the processors of
+// id for dataType, e.g. decouple_2-traces-.
+func spillPrefix(id component.ID, dataType component.DataType) string {
+	return fmt.Sprintf("%s-%s-", strings.ReplaceAll(id.String(), "/", "_"), dataType)
+}
+
+// spill writes b as OTLP JSON to a new file of dir.
+func spill(dir string, id component.ID, b batch) error {
+	data, err := b.marshal()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s%020d-%06d.json", spillPrefix(id, b.dataType), time.Now().UnixNano(), atomic.AddUint64(&spillSeq, 1))
+
+	return os.WriteFile(filepath.Join(dir, name), data, 0600)
+}
+
+// unspill reads and removes the files spilled to dir by the processors of
+// id for dataType, oldest first.
+func unspill(dir string, id component.ID, dataType component.DataType) ([]batch, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, spillPrefix(id, dataType)+"*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(paths)
+
+	var batches []batch
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return batches, err
+		}
+
+		// Another processor of the same configuration may have claimed it
+		if err := os.Remove(path); err != nil {
+			continue
+		}
+
+		b, err := unmarshalBatch(dataType, data)
+		if err != nil {
+			return batches, fmt.Errorf("invalid spilled file %s: %w", path, err)
+		}
+
+		batches = append(batches, b)
+	}
+
+	return batches, nil
+}
@@ -0,0 +1,129 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decoupleprocessor // import "github.com/open-telemetry/opentelemetry-lambda/collector/processor/decoupleprocessor"
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// shutdownBudget drains the queues of the decouple processors of a
+// configuration, one per pipeline, on shutdown. Lambda only leaves
+// extensions about 2 seconds to shut down, so the queued batches are
+// exported by priority: traces first, then logs with errors, metrics and the
+// other logs, each export capped by ShutdownExportTimeout, until
+// ShutdownBudget is used up. The batches left are spilled to SpillDirectory,
+// if set, or dropped.
+//
+// The collector shuts down every processor before the exporters, so the
+// first processor shut down drains them all while the exporters still run.
+type shutdownBudget struct {
+	cfg    *Config
+	logger *zap.Logger
+	// remove forgets the budget in its factory once drained
+	remove func()
+
+	mu         sync.Mutex
+	processors []*decoupleProcessor
+
+	once sync.Once
+	done chan struct{}
+}
+
+func newShutdownBudget(cfg *Config, logger *zap.Logger, remove func()) *shutdownBudget {
+	return &shutdownBudget{
+		cfg:    cfg,
+		logger: logger,
+		remove: remove,
+		done:   make(chan struct{}),
+	}
+}
+
+func (s *shutdownBudget) add(p *decoupleProcessor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.processors = append(s.processors, p)
+}
+
+// drain exports the queued batches within the budget, or until ctx is done.
+// Later calls wait for the first one to finish.
+func (s *shutdownBudget) drain(ctx context.Context) {
+	s.once.Do(func() {
+		defer close(s.done)
+		defer s.remove()
+
+		ctx, cancel := context.WithTimeout(ctx, s.cfg.ShutdownBudget)
+		defer cancel()
+
+		s.mu.Lock()
+		processors := s.processors
+		s.mu.Unlock()
+
+		var all []queued
+		for _, p := range processors {
+			for _, b := range p.halt(ctx) {
+				all = append(all, queued{processor: p, batch: b})
+			}
+		}
+
+		// Batches of the same priority keep their order
+		sort.SliceStable(all, func(i, j int) bool {
+			return all[i].batch.priority() < all[j].batch.priority()
+		})
+
+		for i, q := range all {
+			if ctx.Err() != nil {
+				s.abandon(all[i:])
+				return
+			}
+
+			exportCtx, cancel := context.WithTimeout(ctx, s.cfg.ShutdownExportTimeout)
+			err := q.processor.export(exportCtx, q.batch)
+			cancel()
+
+			if err != nil {
+				s.logger.Error("Failed to export decoupled data on shutdown", zap.Error(err))
+			}
+		}
+	})
+
+	<-s.done
+}
+
+// abandon spills the batches left once the budget is used up, or drops them.
+func (s *shutdownBudget) abandon(left []queued) {
+	if s.cfg.SpillDirectory == "" {
+		s.logger.Warn("Shutdown budget used up, dropping the data left", zap.Int("batches", len(left)))
+		return
+	}
+
+	for _, q := range left {
+		if err := spill(s.cfg.SpillDirectory, s.cfg.ID(), q.batch); err != nil {
+			s.logger.Error("Failed to spill decoupled data, dropping it", zap.Error(err))
+		}
+	}
+
+	s.logger.Info("Shutdown budget used up, spilled the data left", zap.Int("batches", len(left)), zap.String("directory", s.cfg.SpillDirectory))
+}
+
+// queued is a batch waiting in the queue of a processor.
+type queued struct {
+	processor *decoupleProcessor
+	batch     batch
+}
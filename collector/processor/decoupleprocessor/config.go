@@ -0,0 +1,62 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decoupleprocessor // import "github.com/open-telemetry/opentelemetry-lambda/collector/processor/decoupleprocessor"
+
+import (
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+)
+
+// Config defines the configuration of the decouple processor.
+type Config struct {
+	config.ProcessorSettings `mapstructure:",squash"`
+
+	// MaxQueueSize is the number of batches held for export. Once the queue is
+	// full, data is exported synchronously again until it has room.
+	MaxQueueSize int `mapstructure:"max_queue_size"`
+	// ShutdownBudget bounds the time spent exporting the queued data on
+	// shutdown. Traces are exported first, then logs with errors, metrics
+	// and the other logs.
+	ShutdownBudget time.Duration `mapstructure:"shutdown_budget"`
+	// ShutdownExportTimeout caps each export on shutdown, so a slow backend
+	// can't use up the budget of the other batches.
+	ShutdownExportTimeout time.Duration `mapstructure:"shutdown_export_timeout"`
+	// SpillDirectory is where the data left once the shutdown budget is used
+	// up is written, e.g. /tmp/decouple, to be exported by the next collector
+	// started in the same environment. The data is dropped if empty.
+	SpillDirectory string `mapstructure:"spill_directory"`
+}
+
+var _ component.ProcessorConfig = (*Config)(nil)
+
+// Validate checks the processor configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.MaxQueueSize <= 0 {
+		return errors.New("max_queue_size must be positive")
+	}
+
+	if cfg.ShutdownBudget <= 0 {
+		return errors.New("shutdown_budget must be positive")
+	}
+
+	if cfg.ShutdownExportTimeout <= 0 {
+		return errors.New("shutdown_export_timeout must be positive")
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decoupleprocessor // import "github.com/open-telemetry/opentelemetry-lambda/collector/processor/decoupleprocessor"
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/consumer"
+)
+
+const (
+	typeStr   = "decouple"
+	stability = component.StabilityLevelAlpha
+
+	defaultMaxQueueSize = 200
+	// defaultShutdownBudget leaves the rest of the 2 seconds Lambda gives
+	// extensions on shutdown to the other components
+	defaultShutdownBudget        = 1500 * time.Millisecond
+	defaultShutdownExportTimeout = 500 * time.Millisecond
+)
+
+// factory creates decouple processors, sharing a shutdown budget between the
+// processors of a configuration.
+type factory struct {
+	mu      sync.Mutex
+	budgets map[*Config]*shutdownBudget
+}
+
+// NewFactory returns a factory for the decouple processor, which hands the
+// data it receives to the rest of the pipeline in the background.
+func NewFactory() component.ProcessorFactory {
+	f := &factory{budgets: make(map[*Config]*shutdownBudget)}
+
+	return component.NewProcessorFactory(
+		typeStr,
+		createDefaultConfig,
+		component.WithTracesProcessor(f.createTracesProcessor, stability),
+		component.WithMetricsProcessor(f.createMetricsProcessor, stability),
+		component.WithLogsProcessor(f.createLogsProcessor, stability))
+}
+
+func createDefaultConfig() component.ProcessorConfig {
+	return &Config{
+		ProcessorSettings:     config.NewProcessorSettings(component.NewID(typeStr)),
+		MaxQueueSize:          defaultMaxQueueSize,
+		ShutdownBudget:        defaultShutdownBudget,
+		ShutdownExportTimeout: defaultShutdownExportTimeout,
+	}
+}
+
+func (f *factory) createTracesProcessor(_ context.Context, set component.ProcessorCreateSettings, cfg component.ProcessorConfig, next consumer.Traces) (component.TracesProcessor, error) {
+	p := f.processor(set, cfg.(*Config), component.DataTypeTraces)
+	p.nextTraces = next
+	return p, nil
+}
+
+func (f *factory) createMetricsProcessor(_ context.Context, set component.ProcessorCreateSettings, cfg component.ProcessorConfig, next consumer.Metrics) (component.MetricsProcessor, error) {
+	p := f.processor(set, cfg.(*Config), component.DataTypeMetrics)
+	p.nextMetrics = next
+	return p, nil
+}
+
+func (f *factory) createLogsProcessor(_ context.Context, set component.ProcessorCreateSettings, cfg component.ProcessorConfig, next consumer.Logs) (component.LogsProcessor, error) {
+	p := f.processor(set, cfg.(*Config), component.DataTypeLogs)
+	p.nextLogs = next
+	return p, nil
+}
+
+// processor returns a new processor of cfg, sharing the shutdown budget of
+// the other processors of cfg.
+func (f *factory) processor(set component.ProcessorCreateSettings, cfg *Config, dataType component.DataType) *decoupleProcessor {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	budget, ok := f.budgets[cfg]
+	if !ok {
+		budget = newShutdownBudget(cfg, set.Logger, func() { f.remove(cfg) })
+		f.budgets[cfg] = budget
+	}
+
+	return newDecoupleProcessor(cfg, set, dataType, budget)
+}
+
+func (f *factory) remove(cfg *Config) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.budgets, cfg)
+}
@@ -0,0 +1,73 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decoupleprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+)
+
+func TestCreateDefaultConfig(t *testing.T) {
+	cfg := NewFactory().CreateDefaultConfig().(*Config)
+
+	assert.Equal(t, defaultMaxQueueSize, cfg.MaxQueueSize)
+	assert.Equal(t, defaultShutdownBudget, cfg.ShutdownBudget)
+	assert.Equal(t, defaultShutdownExportTimeout, cfg.ShutdownExportTimeout)
+	assert.Empty(t, cfg.SpillDirectory)
+	assert.NoError(t, componenttest.CheckConfigStruct(cfg))
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestConfigValidate(t *testing.T) {
+	for name, invalidate := range map[string]func(*Config){
+		"max_queue_size":          func(cfg *Config) { cfg.MaxQueueSize = 0 },
+		"shutdown_budget":         func(cfg *Config) { cfg.ShutdownBudget = 0 },
+		"shutdown_export_timeout": func(cfg *Config) { cfg.ShutdownExportTimeout = -1 },
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := NewFactory().CreateDefaultConfig().(*Config)
+			invalidate(cfg)
+
+			assert.Error(t, cfg.Validate())
+		})
+	}
+}
+
+func TestCreateProcessors(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+	set := componenttest.NewNopProcessorCreateSettings()
+
+	traces, err := factory.CreateTracesProcessor(context.Background(), set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	metrics, err := factory.CreateMetricsProcessor(context.Background(), set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	logs, err := factory.CreateLogsProcessor(context.Background(), set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+
+	for _, p := range []interface {
+		Start(context.Context, component.Host) error
+		Shutdown(context.Context) error
+	}{traces, metrics, logs} {
+		require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+		assert.NoError(t, p.Shutdown(context.Background()))
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decoupleprocessor // import "github.com/open-telemetry/opentelemetry-lambda/collector/processor/decoupleprocessor"
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+// decoupleProcessor acknowledges the data it receives at once and exports it
+// from a background goroutine. Lambda freezes the sandbox between
+// invocations, so the exports run while the function handles later
+// invocations, and the queue is drained on shutdown, instead of adding the
+// latency of the backends to each invocation.
+type decoupleProcessor struct {
+	cfg      *Config
+	logger   *zap.Logger
+	dataType component.DataType
+	// budget drains the queues of the processors of cfg on shutdown
+	budget *shutdownBudget
+
+	nextTraces  consumer.Traces
+	nextMetrics consumer.Metrics
+	nextLogs    consumer.Logs
+
+	// mu guards queue against sends once the processor is stopped
+	mu      sync.RWMutex
+	queue   chan batch
+	started bool
+	stopped bool
+	stop    chan struct{}
+	done    chan struct{}
+	// cancel interrupts the background export in progress
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newDecoupleProcessor(cfg *Config, set component.ProcessorCreateSettings, dataType component.DataType, budget *shutdownBudget) *decoupleProcessor {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &decoupleProcessor{
+		cfg:      cfg,
+		logger:   set.Logger,
+		dataType: dataType,
+		budget:   budget,
+		queue:    make(chan batch, cfg.MaxQueueSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+
+	budget.add(p)
+
+	return p
+}
+
+// Start queues the data spilled by a previous collector, if any, and starts
+// exporting the queue.
+func (p *decoupleProcessor) Start(ctx context.Context, _ component.Host) error {
+	if p.cfg.SpillDirectory != "" {
+		batches, err := unspill(p.cfg.SpillDirectory, p.cfg.ID(), p.dataType)
+		if err != nil {
+			p.logger.Error("Failed to read the spilled data", zap.Error(err))
+		}
+
+		for _, b := range batches {
+			if err := p.enqueue(ctx, b); err != nil {
+				p.logger.Error("Failed to export the spilled data", zap.Error(err))
+			}
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.started && !p.stopped {
+		p.started = true
+		go p.run()
+	}
+
+	return nil
+}
+
+// Shutdown exports the queued data of every processor of the configuration
+// within the shutdown budget, see shutdownBudget.
+func (p *decoupleProcessor) Shutdown(ctx context.Context) error {
+	p.budget.drain(ctx)
+	return nil
+}
+
+func (p *decoupleProcessor) Capabilities() consumer.Capabilities {
+	return consumer.Capabilities{MutatesData: false}
+}
+
+func (p *decoupleProcessor) ConsumeTraces(ctx context.Context, td ptrace.Traces) error {
+	return p.enqueue(ctx, batch{dataType: component.DataTypeTraces, traces: td})
+}
+
+func (p *decoupleProcessor) ConsumeMetrics(ctx context.Context, md pmetric.Metrics) error {
+	return p.enqueue(ctx, batch{dataType: component.DataTypeMetrics, metrics: md})
+}
+
+func (p *decoupleProcessor) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
+	return p.enqueue(ctx, batch{dataType: component.DataTypeLogs, logs: ld})
+}
+
+// enqueue queues the export of a batch. The batch is exported synchronously
+// when the queue is full, or once the processor is stopped, so no data is
+// dropped.
+func (p *decoupleProcessor) enqueue(ctx context.Context, b batch) error {
+	p.mu.RLock()
+	if !p.stopped {
+		select {
+		case p.queue <- b:
+			p.mu.RUnlock()
+			return nil
+		default:
+		}
+	}
+	// Stopping must not wait for the synchronous export
+	p.mu.RUnlock()
+
+	return p.export(ctx, b)
+}
+
+// export sends a batch to the next consumer of the pipeline.
+func (p *decoupleProcessor) export(ctx context.Context, b batch) error {
+	switch b.dataType {
+	case component.DataTypeTraces:
+		return p.nextTraces.ConsumeTraces(ctx, b.traces)
+	case component.DataTypeMetrics:
+		return p.nextMetrics.ConsumeMetrics(ctx, b.metrics)
+	default:
+		return p.nextLogs.ConsumeLogs(ctx, b.logs)
+	}
+}
+
+// run exports the queued batches until the processor is stopped.
+func (p *decoupleProcessor) run() {
+	defer close(p.done)
+
+	for {
+		// Stopping takes precedence over the batches left in the queue
+		select {
+		case <-p.stop:
+			return
+		default:
+		}
+
+		select {
+		case <-p.stop:
+			return
+
+		case b := <-p.queue:
+			// The context of the receiver is done once the data is acknowledged
+			if err := p.export(p.ctx, b); err != nil {
+				p.logger.Error("Failed to export decoupled data", zap.Error(err))
+			}
+		}
+	}
+}
+
+// halt stops the background exports and returns the batches left in the
+// queue. The export in progress is given until ctx is done. Data received
+// afterwards is exported synchronously.
+func (p *decoupleProcessor) halt(ctx context.Context) []batch {
+	p.mu.Lock()
+	started := p.started && !p.stopped
+	p.stopped = true
+	p.mu.Unlock()
+
+	if started {
+		close(p.stop)
+
+		select {
+		case <-p.done:
+		case <-ctx.Done():
+			p.cancel()
+			<-p.done
+		}
+	}
+
+	p.cancel()
+
+	var batches []batch
+	for {
+		select {
+		case b := <-p.queue:
+			batches = append(batches, b)
+		default:
+			return batches
+		}
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decoupleprocessor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+func newTestConfig(maxQueueSize int) *Config {
+	cfg := NewFactory().CreateDefaultConfig().(*Config)
+	cfg.MaxQueueSize = maxQueueSize
+
+	return cfg
+}
+
+// newTestProcessor returns a processor of cfg sharing budget, or a budget of
+// its own if nil.
+func newTestProcessor(cfg *Config, dataType component.DataType, budget *shutdownBudget) *decoupleProcessor {
+	if budget == nil {
+		budget = newShutdownBudget(cfg, zap.NewNop(), func() {})
+	}
+
+	return newDecoupleProcessor(cfg, componenttest.NewNopProcessorCreateSettings(), dataType, budget)
+}
+
+func TestConsumeDoesNotWaitForExport(t *testing.T) {
+	release := make(chan struct{})
+	sink := new(consumertest.TracesSink)
+
+	p := newTestProcessor(newTestConfig(10), component.DataTypeTraces, nil)
+	p.nextTraces, _ = consumer.NewTraces(func(ctx context.Context, td ptrace.Traces) error {
+		<-release
+		return sink.ConsumeTraces(ctx, td)
+	})
+	require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, p.ConsumeTraces(context.Background(), ptrace.NewTraces()))
+	}
+	assert.Empty(t, sink.AllTraces())
+
+	close(release)
+	require.NoError(t, p.Shutdown(context.Background()))
+	assert.Len(t, sink.AllTraces(), 3)
+}
+
+func TestConsumeWhenQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	exported := make(chan struct{}, 10)
+	sink := new(consumertest.LogsSink)
+
+	p := newTestProcessor(newTestConfig(1), component.DataTypeLogs, nil)
+	p.nextLogs, _ = consumer.NewLogs(func(ctx context.Context, ld plog.Logs) error {
+		exported <- struct{}{}
+		<-release
+		return sink.ConsumeLogs(ctx, ld)
+	})
+	require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+
+	// The first batch is being exported and the second one fills the queue
+	require.NoError(t, p.ConsumeLogs(context.Background(), plog.NewLogs()))
+	<-exported
+	require.NoError(t, p.ConsumeLogs(context.Background(), plog.NewLogs()))
+
+	consumed := make(chan error)
+	go func() { consumed <- p.ConsumeLogs(context.Background(), plog.NewLogs()) }()
+
+	select {
+	case <-consumed:
+		t.Fatal("expected the third batch to be exported synchronously")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	assert.NoError(t, <-consumed)
+	require.NoError(t, p.Shutdown(context.Background()))
+	assert.Len(t, sink.AllLogs(), 3)
+}
+
+func TestHaltDuringSynchronousExport(t *testing.T) {
+	release := make(chan struct{})
+	exporting := make(chan struct{})
+
+	p := newTestProcessor(newTestConfig(1), component.DataTypeLogs, nil)
+	p.nextLogs, _ = consumer.NewLogs(func(ctx context.Context, ld plog.Logs) error {
+		close(exporting)
+		<-release
+		return nil
+	})
+
+	// The second batch finds the queue full and is exported synchronously
+	require.NoError(t, p.ConsumeLogs(context.Background(), plog.NewLogs()))
+	consumed := make(chan error)
+	go func() { consumed <- p.ConsumeLogs(context.Background(), plog.NewLogs()) }()
+	<-exporting
+
+	halted := make(chan []batch)
+	go func() { halted <- p.halt(context.Background()) }()
+
+	select {
+	case batches := <-halted:
+		assert.Len(t, batches, 1)
+	case <-time.After(time.Second):
+		t.Fatal("expected halt not to wait for the synchronous export")
+	}
+
+	close(release)
+	assert.NoError(t, <-consumed)
+}
+
+func TestConsumeAfterShutdown(t *testing.T) {
+	errExport := errors.New("export failed")
+
+	p := newTestProcessor(newTestConfig(10), component.DataTypeTraces, nil)
+	p.nextTraces = consumertest.NewErr(errExport)
+	require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+	require.NoError(t, p.Shutdown(context.Background()))
+
+	// Once shut down, the data is exported synchronously and errors are returned
+	assert.ErrorIs(t, p.ConsumeTraces(context.Background(), ptrace.NewTraces()), errExport)
+}
+
+func TestShutdownWithoutStart(t *testing.T) {
+	sink := new(consumertest.MetricsSink)
+
+	p := newTestProcessor(newTestConfig(10), component.DataTypeMetrics, nil)
+	p.nextMetrics = sink
+	require.NoError(t, p.ConsumeMetrics(context.Background(), pmetric.NewMetrics()))
+
+	assert.NoError(t, p.Shutdown(context.Background()))
+	assert.Len(t, sink.AllMetrics(), 1)
+}
+
+func TestShutdownPriority(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		exported []string
+	)
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		exported = append(exported, name)
+	}
+
+	cfg := newTestConfig(10)
+	budget := newShutdownBudget(cfg, zap.NewNop(), func() {})
+
+	// The processors are not started, so everything stays queued until shutdown
+	logs := newTestProcessor(cfg, component.DataTypeLogs, budget)
+	logs.nextLogs, _ = consumer.NewLogs(func(_ context.Context, ld plog.Logs) error {
+		if hasErrors(ld) {
+			record("error logs")
+		} else {
+			record("logs")
+		}
+		return nil
+	})
+	metrics := newTestProcessor(cfg, component.DataTypeMetrics, budget)
+	metrics.nextMetrics, _ = consumer.NewMetrics(func(context.Context, pmetric.Metrics) error {
+		record("metrics")
+		return nil
+	})
+	traces := newTestProcessor(cfg, component.DataTypeTraces, budget)
+	traces.nextTraces, _ = consumer.NewTraces(func(context.Context, ptrace.Traces) error {
+		record("traces")
+		return nil
+	})
+
+	errorLogs := plog.NewLogs()
+	errorLogs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().SetSeverityNumber(plog.SeverityNumberError)
+
+	require.NoError(t, logs.ConsumeLogs(context.Background(), plog.NewLogs()))
+	require.NoError(t, metrics.ConsumeMetrics(context.Background(), pmetric.NewMetrics()))
+	require.NoError(t, logs.ConsumeLogs(context.Background(), errorLogs))
+	require.NoError(t, traces.ConsumeTraces(context.Background(), ptrace.NewTraces()))
+
+	// The first processor shut down drains them all
+	require.NoError(t, logs.Shutdown(context.Background()))
+	assert.Equal(t, []string{"traces", "error logs", "metrics", "logs"}, exported)
+
+	require.NoError(t, traces.Shutdown(context.Background()))
+	require.NoError(t, metrics.Shutdown(context.Background()))
+	assert.Len(t, exported, 4)
+}
+
+func TestShutdownExportTimeout(t *testing.T) {
+	cfg := newTestConfig(10)
+	cfg.ShutdownExportTimeout = 20 * time.Millisecond
+
+	// A backend never answering only holds each batch for the export timeout
+	var attempts int
+	p := newTestProcessor(cfg, component.DataTypeTraces, nil)
+	p.nextTraces, _ = consumer.NewTraces(func(ctx context.Context, _ ptrace.Traces) error {
+		attempts++
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, p.ConsumeTraces(context.Background(), ptrace.NewTraces()))
+	}
+
+	start := time.Now()
+	require.NoError(t, p.Shutdown(context.Background()))
+	assert.Equal(t, 3, attempts)
+	assert.Less(t, time.Since(start), cfg.ShutdownBudget)
+}
+
+func TestShutdownBudgetSpill(t *testing.T) {
+	cfg := newTestConfig(10)
+	cfg.ShutdownBudget = 50 * time.Millisecond
+	cfg.SpillDirectory = t.TempDir()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	p := newTestProcessor(cfg, component.DataTypeLogs, nil)
+	p.nextLogs, _ = consumer.NewLogs(func(ctx context.Context, _ plog.Logs) error {
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+
+	for _, body := range []string{"first", "second", "third"} {
+		logs := plog.NewLogs()
+		logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr(body)
+		require.NoError(t, p.ConsumeLogs(context.Background(), logs))
+	}
+
+	// The budget is used up by the first batch, the others are spilled
+	start := time.Now()
+	require.NoError(t, p.Shutdown(context.Background()))
+	assert.Less(t, time.Since(start), 4*cfg.ShutdownBudget)
+
+	// and exported by the next collector started in the environment
+	sink := new(consumertest.LogsSink)
+	next := newTestProcessor(cfg, component.DataTypeLogs, nil)
+	next.nextLogs = sink
+	require.NoError(t, next.Start(context.Background(), componenttest.NewNopHost()))
+	require.NoError(t, next.Shutdown(context.Background()))
+
+	var bodies []string
+	for _, logs := range sink.AllLogs() {
+		bodies = append(bodies, logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str())
+	}
+	assert.Equal(t, []string{"second", "third"}, bodies)
+
+	// The spilled files are exported once
+	spilled, err := unspill(cfg.SpillDirectory, cfg.ID(), component.DataTypeLogs)
+	require.NoError(t, err)
+	assert.Empty(t, spilled)
+}
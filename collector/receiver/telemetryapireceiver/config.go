@@ -0,0 +1,67 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapireceiver // import "github.com/open-telemetry/opentelemetry-lambda/collector/receiver/telemetryapireceiver"
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/telemetryapi"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+)
+
+// Config defines the configuration of the telemetryapi receiver.
+type Config struct {
+	config.ReceiverSettings `mapstructure:",squash"`
+
+	// ParseJSONLogs maps the level, message and timestamp fields of JSON
+	// function log lines to the severity, body and timestamp of the log
+	// records, and the other fields to attributes.
+	ParseJSONLogs bool `mapstructure:"parse_json_logs"`
+	// EMFMetrics converts CloudWatch Embedded Metric Format function log
+	// lines into metrics.
+	EMFMetrics bool `mapstructure:"emf_metrics"`
+	// InternalMetrics adds the internal metrics of the Telemetry API listener
+	// to the metrics converted from each platform.report event.
+	InternalMetrics bool `mapstructure:"internal_metrics"`
+	// FunctionLogSampleRate is the fraction of function log lines forwarded,
+	// from 0 to 1. Lines with a severity of ERROR or above are always kept.
+	FunctionLogSampleRate float64 `mapstructure:"function_log_sample_rate"`
+	// Types are the Telemetry API event types to subscribe to: platform,
+	// function and extension. They take precedence over
+	// OTEL_LAMBDA_TELEMETRY_TYPES when set, and are applied at the end of the
+	// invocation in which the configuration is reloaded.
+	Types []string `mapstructure:"types"`
+}
+
+var _ component.ReceiverConfig = (*Config)(nil)
+
+// Validate checks the receiver configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.FunctionLogSampleRate < 0 || cfg.FunctionLogSampleRate > 1 {
+		return errors.New("function_log_sample_rate must be between 0 and 1")
+	}
+
+	for _, eventType := range cfg.Types {
+		switch telemetryapi.EventType(eventType) {
+		case telemetryapi.Platform, telemetryapi.Function, telemetryapi.Extension:
+		default:
+			return fmt.Errorf("unknown Telemetry API event type %q in types", eventType)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapireceiver // import "github.com/open-telemetry/opentelemetry-lambda/collector/receiver/telemetryapireceiver"
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/telemetryapi"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/consumer"
+)
+
+const (
+	typeStr   = "telemetryapi"
+	stability = component.StabilityLevelAlpha
+)
+
+var errListenerDisabled = errors.New("the Telemetry API listener is disabled by OTEL_LAMBDA_DISABLE_TELEMETRY_API")
+
+// factory creates the receivers of a Listener. A single receiver is created
+// per configuration, whichever pipelines it is used in, as the events of an
+// invocation feed its traces, metrics and logs at once.
+type factory struct {
+	listener *telemetryapi.Listener
+
+	mu        sync.Mutex
+	receivers map[*Config]*telemetryAPIReceiver
+}
+
+// NewFactory returns a factory for the telemetryapi receiver, which converts
+// the events dispatched by listener into traces, metrics and logs. listener
+// may be nil when the Telemetry API is disabled, in which case the receiver
+// can't be created.
+func NewFactory(listener *telemetryapi.Listener) component.ReceiverFactory {
+	f := &factory{
+		listener:  listener,
+		receivers: make(map[*Config]*telemetryAPIReceiver),
+	}
+
+	return component.NewReceiverFactory(
+		typeStr,
+		createDefaultConfig,
+		component.WithTracesReceiver(f.createTracesReceiver, stability),
+		component.WithMetricsReceiver(f.createMetricsReceiver, stability),
+		component.WithLogsReceiver(f.createLogsReceiver, stability))
+}
+
+func createDefaultConfig() component.ReceiverConfig {
+	return &Config{
+		ReceiverSettings:      config.NewReceiverSettings(component.NewID(typeStr)),
+		ParseJSONLogs:         true,
+		EMFMetrics:            true,
+		FunctionLogSampleRate: 1,
+	}
+}
+
+func (f *factory) createTracesReceiver(_ context.Context, set component.ReceiverCreateSettings, cfg component.ReceiverConfig, next consumer.Traces) (component.TracesReceiver, error) {
+	r, err := f.receiver(set, cfg.(*Config))
+	if err != nil {
+		return nil, err
+	}
+
+	r.nextTraces = next
+	return r, nil
+}
+
+func (f *factory) createMetricsReceiver(_ context.Context, set component.ReceiverCreateSettings, cfg component.ReceiverConfig, next consumer.Metrics) (component.MetricsReceiver, error) {
+	r, err := f.receiver(set, cfg.(*Config))
+	if err != nil {
+		return nil, err
+	}
+
+	r.nextMetrics = next
+	return r, nil
+}
+
+func (f *factory) createLogsReceiver(_ context.Context, set component.ReceiverCreateSettings, cfg component.ReceiverConfig, next consumer.Logs) (component.LogsReceiver, error) {
+	r, err := f.receiver(set, cfg.(*Config))
+	if err != nil {
+		return nil, err
+	}
+
+	r.nextLogs = next
+	return r, nil
+}
+
+// receiver returns the receiver of cfg, creating it on first use.
+func (f *factory) receiver(set component.ReceiverCreateSettings, cfg *Config) (*telemetryAPIReceiver, error) {
+	if f.listener == nil {
+		return nil, errListenerDisabled
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	r, ok := f.receivers[cfg]
+	if !ok {
+		r = newTelemetryAPIReceiver(f.listener, cfg, set, func() { f.remove(cfg) })
+		f.receivers[cfg] = r
+	}
+
+	return r, nil
+}
+
+func (f *factory) remove(cfg *Config) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.receivers, cfg)
+}
@@ -0,0 +1,102 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapireceiver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/telemetryapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+)
+
+func TestCreateDefaultConfig(t *testing.T) {
+	cfg := NewFactory(nil).CreateDefaultConfig().(*Config)
+
+	assert.True(t, cfg.ParseJSONLogs)
+	assert.True(t, cfg.EMFMetrics)
+	assert.False(t, cfg.InternalMetrics)
+	assert.Equal(t, 1.0, cfg.FunctionLogSampleRate)
+	assert.NoError(t, componenttest.CheckConfigStruct(cfg))
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, rate := range []float64{-0.1, 1.5} {
+		cfg := NewFactory(nil).CreateDefaultConfig().(*Config)
+		cfg.FunctionLogSampleRate = rate
+
+		assert.Error(t, cfg.Validate())
+	}
+
+	cfg := NewFactory(nil).CreateDefaultConfig().(*Config)
+	cfg.Types = []string{"platform", "function", "extension"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Types = []string{"platform", "logs"}
+	assert.Error(t, cfg.Validate())
+}
+
+func TestReceiverEventTypes(t *testing.T) {
+	listener := telemetryapi.NewListener(telemetryapi.ListenerConfig{})
+	factory := NewFactory(listener)
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Types = []string{"platform", "function"}
+
+	logs, err := factory.CreateLogsReceiver(context.Background(), componenttest.NewNopReceiverCreateSettings(), cfg, consumertest.NewNop())
+	require.NoError(t, err)
+
+	// The types are needed while the receiver is started
+	assert.Nil(t, listener.ConsumerEventTypes())
+	require.NoError(t, logs.Start(context.Background(), componenttest.NewNopHost()))
+	assert.Equal(t, []telemetryapi.EventType{telemetryapi.Platform, telemetryapi.Function}, listener.ConsumerEventTypes())
+	require.NoError(t, logs.Shutdown(context.Background()))
+	assert.Nil(t, listener.ConsumerEventTypes())
+}
+
+func TestCreateReceiverWithoutListener(t *testing.T) {
+	factory := NewFactory(nil)
+	cfg := factory.CreateDefaultConfig()
+
+	_, err := factory.CreateLogsReceiver(context.Background(), componenttest.NewNopReceiverCreateSettings(), cfg, consumertest.NewNop())
+	assert.ErrorIs(t, err, errListenerDisabled)
+}
+
+func TestCreateSharedReceiver(t *testing.T) {
+	factory := NewFactory(telemetryapi.NewListener(telemetryapi.ListenerConfig{}))
+	cfg := factory.CreateDefaultConfig()
+	set := componenttest.NewNopReceiverCreateSettings()
+
+	traces, err := factory.CreateTracesReceiver(context.Background(), set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	metrics, err := factory.CreateMetricsReceiver(context.Background(), set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	logs, err := factory.CreateLogsReceiver(context.Background(), set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+
+	assert.Same(t, traces, metrics)
+	assert.Same(t, traces, logs)
+
+	// A receiver created after the shared one is shut down starts afresh
+	require.NoError(t, traces.Start(context.Background(), componenttest.NewNopHost()))
+	require.NoError(t, traces.Shutdown(context.Background()))
+
+	logs, err = factory.CreateLogsReceiver(context.Background(), set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	assert.NotSame(t, traces, logs)
+}
@@ -0,0 +1,135 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapireceiver // import "github.com/open-telemetry/opentelemetry-lambda/collector/receiver/telemetryapireceiver"
+
+import (
+	"context"
+	"sync"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/telemetryapi"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.uber.org/zap"
+)
+
+// telemetryAPIReceiver converts the events dispatched by the listener and
+// passes them on to the pipelines it is used in.
+type telemetryAPIReceiver struct {
+	listener *telemetryapi.Listener
+	logger   *zap.Logger
+	// converters holds a converter per signal, as converters track the
+	// current invocation across the batches they convert
+	converters map[component.DataType]*telemetryapi.Converter
+	// eventTypes are the event types set in the receiver configuration, if any
+	eventTypes []telemetryapi.EventType
+	// remove forgets the receiver in its factory once it is shut down
+	remove func()
+
+	nextTraces  consumer.Traces
+	nextMetrics consumer.Metrics
+	nextLogs    consumer.Logs
+
+	startOnce    sync.Once
+	shutdownOnce sync.Once
+}
+
+func newTelemetryAPIReceiver(listener *telemetryapi.Listener, cfg *Config, set component.ReceiverCreateSettings, remove func()) *telemetryAPIReceiver {
+	converterConfig := telemetryapi.ConverterConfig{
+		ParseJSONLogs:         cfg.ParseJSONLogs,
+		EMFMetrics:            cfg.EMFMetrics,
+		InternalMetrics:       cfg.InternalMetrics,
+		FunctionLogSampleRate: cfg.FunctionLogSampleRate,
+	}
+
+	var eventTypes []telemetryapi.EventType
+	for _, eventType := range cfg.Types {
+		eventTypes = append(eventTypes, telemetryapi.EventType(eventType))
+	}
+
+	return &telemetryAPIReceiver{
+		listener:   listener,
+		eventTypes: eventTypes,
+		logger:     set.Logger,
+		converters: map[component.DataType]*telemetryapi.Converter{
+			component.DataTypeTraces:  telemetryapi.NewConverter(converterConfig),
+			component.DataTypeMetrics: telemetryapi.NewConverter(converterConfig),
+			component.DataTypeLogs:    telemetryapi.NewConverter(converterConfig),
+		},
+		remove: remove,
+	}
+}
+
+// Start subscribes the receiver to the listener. The receiver is shared by
+// the pipelines it is used in, so only the first call has an effect.
+func (r *telemetryAPIReceiver) Start(_ context.Context, _ component.Host) error {
+	r.startOnce.Do(func() {
+		r.listener.AddConsumer(r)
+	})
+
+	return nil
+}
+
+// Shutdown unsubscribes the receiver from the listener.
+func (r *telemetryAPIReceiver) Shutdown(_ context.Context) error {
+	r.shutdownOnce.Do(func() {
+		r.listener.RemoveConsumer(r)
+		r.remove()
+	})
+
+	return nil
+}
+
+// EventTypes returns the event types set in the receiver configuration, so
+// the extension subscribes to them.
+func (r *telemetryAPIReceiver) EventTypes() []telemetryapi.EventType {
+	return r.eventTypes
+}
+
+// ConsumeEvents converts a batch of events for each pipeline the receiver is used in.
+func (r *telemetryAPIReceiver) ConsumeEvents(ctx context.Context, events []telemetryapi.Event) {
+	function := r.listener.FunctionARN()
+	traceContexts := r.listener.TraceContexts()
+	for _, converter := range r.converters {
+		converter.SetFunctionARN(function)
+		converter.SetTraceContexts(traceContexts)
+	}
+
+	if r.nextTraces != nil {
+		traces := r.converters[component.DataTypeTraces].ToTraces(events)
+		if traces.SpanCount() > 0 {
+			if err := r.nextTraces.ConsumeTraces(ctx, traces); err != nil {
+				r.logger.Error("Failed to consume Telemetry API traces", zap.Error(err))
+			}
+		}
+	}
+
+	if r.nextMetrics != nil {
+		metrics := r.converters[component.DataTypeMetrics].ToMetrics(events)
+		if metrics.DataPointCount() > 0 {
+			if err := r.nextMetrics.ConsumeMetrics(ctx, metrics); err != nil {
+				r.logger.Error("Failed to consume Telemetry API metrics", zap.Error(err))
+			}
+		}
+	}
+
+	if r.nextLogs != nil {
+		logs := r.converters[component.DataTypeLogs].ToLogs(events)
+		if logs.LogRecordCount() > 0 {
+			if err := r.nextLogs.ConsumeLogs(ctx, logs); err != nil {
+				r.logger.Error("Failed to consume Telemetry API logs", zap.Error(err))
+			}
+		}
+	}
+}
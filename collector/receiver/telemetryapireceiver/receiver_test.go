@@ -0,0 +1,93 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetryapireceiver
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/open-telemetry/opentelemetry-lambda/collector/internal/telemetryapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+)
+
+func invocationEvents() []telemetryapi.Event {
+	return []telemetryapi.Event{
+		{Time: "2022-10-12T00:00:01.000Z", Type: telemetryapi.PLATFORM_START, Record: json.RawMessage(`{"requestId":"req-1"}`)},
+		{Time: "2022-10-12T00:00:01.100Z", Type: string(telemetryapi.Function), Record: json.RawMessage(`"{\"level\":\"WARN\",\"message\":\"slow\"}"`)},
+		{Time: "2022-10-12T00:00:01.150Z", Type: string(telemetryapi.Function), Record: json.RawMessage(`"{\"_aws\":{\"Timestamp\":1665532801150,\"CloudWatchMetrics\":[{\"Namespace\":\"app\",\"Dimensions\":[[]],\"Metrics\":[{\"Name\":\"orders\",\"Unit\":\"Count\"}]}]},\"orders\":2}"`)},
+		{Time: "2022-10-12T00:00:01.200Z", Type: telemetryapi.PLATFORM_RUNTIME_DONE, Record: json.RawMessage(`{"requestId":"req-1","status":"success","spans":[{"name":"responseLatency","start":"2022-10-12T00:00:01.150Z","durationMs":23.5}]}`)},
+		{Time: "2022-10-12T00:00:01.300Z", Type: telemetryapi.PLATFORM_REPORT, Record: json.RawMessage(`{"requestId":"req-1","status":"success","metrics":{"durationMs":200.5,"billedDurationMs":201,"memorySizeMB":128,"maxMemoryUsedMB":64}}`)},
+	}
+}
+
+func TestConsumeEvents(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		parseJSONLogs   bool
+		emfMetrics      bool
+		severity        string
+		metricDataPoint int
+	}{
+		{name: "default", parseJSONLogs: true, emfMetrics: true, severity: "WARN", metricDataPoint: 4},
+		{name: "raw function logs", parseJSONLogs: false, emfMetrics: false, severity: "", metricDataPoint: 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			factory := NewFactory(telemetryapi.NewListener(telemetryapi.ListenerConfig{}))
+			cfg := factory.CreateDefaultConfig().(*Config)
+			cfg.ParseJSONLogs = tc.parseJSONLogs
+			cfg.EMFMetrics = tc.emfMetrics
+			set := componenttest.NewNopReceiverCreateSettings()
+
+			traces := new(consumertest.TracesSink)
+			metrics := new(consumertest.MetricsSink)
+			logs := new(consumertest.LogsSink)
+
+			_, err := factory.CreateTracesReceiver(context.Background(), set, cfg, traces)
+			require.NoError(t, err)
+			_, err = factory.CreateMetricsReceiver(context.Background(), set, cfg, metrics)
+			require.NoError(t, err)
+			r, err := factory.CreateLogsReceiver(context.Background(), set, cfg, logs)
+			require.NoError(t, err)
+
+			r.(*telemetryAPIReceiver).ConsumeEvents(context.Background(), invocationEvents())
+
+			assert.Equal(t, 1, traces.SpanCount())
+			assert.Equal(t, tc.metricDataPoint, metrics.DataPointCount())
+			require.Equal(t, 5, logs.LogRecordCount())
+
+			functionLog := logs.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(1)
+			assert.Equal(t, tc.severity, functionLog.SeverityText())
+			invocationID, ok := functionLog.Attributes().Get("faas.invocation_id")
+			assert.True(t, ok)
+			assert.Equal(t, "req-1", invocationID.Str())
+		})
+	}
+}
+
+func TestConsumeEventsWithoutSpans(t *testing.T) {
+	factory := NewFactory(telemetryapi.NewListener(telemetryapi.ListenerConfig{}))
+	traces := new(consumertest.TracesSink)
+
+	r, err := factory.CreateTracesReceiver(context.Background(), componenttest.NewNopReceiverCreateSettings(), factory.CreateDefaultConfig(), traces)
+	require.NoError(t, err)
+
+	r.(*telemetryAPIReceiver).ConsumeEvents(context.Background(), invocationEvents()[:1])
+
+	assert.Empty(t, traces.AllTraces())
+}